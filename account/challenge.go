@@ -0,0 +1,77 @@
+package account
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// ChallengeStore keeps the short-lived token handed out by the first login step
+// when the user has 2FA enabled
+type ChallengeStore interface {
+	// CreateChallenge returns a new challenge token for a user
+	CreateChallenge(ctx context.Context, userID int, ttl time.Duration) (string, error)
+	// ChallengeUser returns the user of a challenge and counts the attempt; after
+	// MaxCodeAttempts the challenge is dropped and ErrTokenNotFound returned
+	ChallengeUser(ctx context.Context, token string) (int, error)
+	// DeleteChallenge ends a challenge once the second factor was verified
+	DeleteChallenge(ctx context.Context, token string) error
+}
+
+type RedisChallengeStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisChallengeStore(rdb *redis.Client) *RedisChallengeStore {
+	return &RedisChallengeStore{rdb: rdb}
+}
+
+func challengeKey(token string) string {
+	return "login-challenge:" + HashToken(token)
+}
+
+func (s *RedisChallengeStore) CreateChallenge(ctx context.Context, userID int, ttl time.Duration) (string, error) {
+	token, err := NewToken()
+	if err != nil {
+		return "", err
+	}
+
+	key := challengeKey(token)
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, key, "user_id", userID, "attempts", 0)
+	pipe.Expire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+func (s *RedisChallengeStore) ChallengeUser(ctx context.Context, token string) (int, error) {
+	key := challengeKey(token)
+
+	v, err := s.rdb.HGet(ctx, key, "user_id").Result()
+	if err == redis.Nil {
+		return 0, ErrTokenNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	attempts, err := s.rdb.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return 0, err
+	}
+	if attempts > MaxCodeAttempts {
+		if err := s.rdb.Del(ctx, key).Err(); err != nil {
+			return 0, err
+		}
+		return 0, ErrTokenNotFound
+	}
+	return strconv.Atoi(v)
+}
+
+func (s *RedisChallengeStore) DeleteChallenge(ctx context.Context, token string) error {
+	return s.rdb.Del(ctx, challengeKey(token)).Err()
+}
@@ -0,0 +1,162 @@
+package account
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// MaxCodeAttempts is the number of wrong guesses after which a code is dropped
+const MaxCodeAttempts = 5
+
+var ErrTokenNotFound = errors.New("token_not_found")
+
+// ErrTooManyAttempts is returned by SaveCode while the previous code of the user
+// and channel used up its attempts; a new code can be issued once that one expires
+var ErrTooManyAttempts = errors.New("too_many_attempts")
+
+// ErrCooldown is returned by StartCooldown while a previous message is still cooling down
+var ErrCooldown = errors.New("send_cooldown")
+
+// CodeStore keeps short-lived verification codes and password reset tokens
+type CodeStore interface {
+	// SaveCode stores a verification code for a user and channel, replacing the previous
+	// one. Wrong guesses of the previous code still count against the new one.
+	SaveCode(ctx context.Context, userID int, channel, code string, ttl time.Duration) error
+	// CheckCode reports whether code matches and consumes it on success
+	CheckCode(ctx context.Context, userID int, channel, code string) (bool, error)
+	// SaveResetToken stores a password reset token for a user
+	SaveResetToken(ctx context.Context, token string, userID int, ttl time.Duration) error
+	// ConsumeResetToken returns the user of a reset token and deletes it, so it works once
+	ConsumeResetToken(ctx context.Context, token string) (int, error)
+	// StartCooldown returns ErrCooldown when a message of kind was sent to the user
+	// within d, and otherwise starts a new cooldown of d
+	StartCooldown(ctx context.Context, kind string, userID int, d time.Duration) error
+}
+
+// NewCode returns a random 6 digit code
+func NewCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1_000_000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
+
+// NewToken returns a random 32 byte token, hex encoded
+func NewToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// HashToken returns the SHA-256 of a token; only the hash is ever stored
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RedisCodeStore keeps codes in Redis so they expire by themselves
+type RedisCodeStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisCodeStore(rdb *redis.Client) *RedisCodeStore {
+	return &RedisCodeStore{rdb: rdb}
+}
+
+func codeKey(userID int, channel string) string {
+	return fmt.Sprintf("verify:%s:%d", channel, userID)
+}
+
+func resetKey(token string) string {
+	return "password-reset:" + HashToken(token)
+}
+
+func (s *RedisCodeStore) SaveCode(ctx context.Context, userID int, channel, code string, ttl time.Duration) error {
+	key := codeKey(userID, channel)
+
+	// attempts ikut dibawa, kalau tidak resend + 5 tebakan bisa diulang tanpa batas
+	attempts, err := s.rdb.HGet(ctx, key, "attempts").Int()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	if attempts >= MaxCodeAttempts {
+		return ErrTooManyAttempts
+	}
+
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, key, "hash", HashToken(code), "attempts", attempts)
+	pipe.Expire(ctx, key, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisCodeStore) CheckCode(ctx context.Context, userID int, channel, code string) (bool, error) {
+	key := codeKey(userID, channel)
+
+	stored, err := s.rdb.HGet(ctx, key, "hash").Result()
+	if err == redis.Nil {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	attempts, err := s.rdb.HIncrBy(ctx, key, "attempts", 1).Result()
+	if err != nil {
+		return false, err
+	}
+	if attempts > MaxCodeAttempts {
+		// hash dibuang tapi attempts tetap ada sampai key expire, supaya SaveCode menolak kode baru
+		return false, s.rdb.HDel(ctx, key, "hash").Err()
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored), []byte(HashToken(code))) != 1 {
+		return false, nil
+	}
+
+	// hanya satu request yang berhasil menghapus key, jadi code tidak bisa dipakai dua kali
+	deleted, err := s.rdb.Del(ctx, key).Result()
+	if err != nil {
+		return false, err
+	}
+	return deleted == 1, nil
+}
+
+func (s *RedisCodeStore) SaveResetToken(ctx context.Context, token string, userID int, ttl time.Duration) error {
+	return s.rdb.Set(ctx, resetKey(token), userID, ttl).Err()
+}
+
+func (s *RedisCodeStore) ConsumeResetToken(ctx context.Context, token string) (int, error) {
+	v, err := s.rdb.GetDel(ctx, resetKey(token)).Result()
+	if err == redis.Nil {
+		return 0, ErrTokenNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(v)
+}
+
+func (s *RedisCodeStore) StartCooldown(ctx context.Context, kind string, userID int, d time.Duration) error {
+	ok, err := s.rdb.SetNX(ctx, fmt.Sprintf("send-cooldown:%s:%d", kind, userID), 1, d).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrCooldown
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package account
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewCode_SixDigits(t *testing.T) {
+	re := regexp.MustCompile(`^\d{6}$`)
+	for i := 0; i < 50; i++ {
+		code, err := NewCode()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !re.MatchString(code) {
+			t.Fatalf("unexpected code %q", code)
+		}
+	}
+}
+
+func TestNewToken_Unique(t *testing.T) {
+	a, _ := NewToken()
+	b, _ := NewToken()
+	if len(a) != 64 || a == b {
+		t.Fatalf("expected two different 64 char tokens, got %q and %q", a, b)
+	}
+}
+
+func TestHashToken_Stable(t *testing.T) {
+	if HashToken("abc") != HashToken("abc") || HashToken("abc") == HashToken("abd") {
+		t.Fatalf("hash must be deterministic and distinguish inputs")
+	}
+	if HashToken("abc") == "abc" {
+		t.Fatalf("hash must not be the token itself")
+	}
+}
+
+func TestResetKey_DoesNotContainToken(t *testing.T) {
+	if key := resetKey("secret-token"); regexp.MustCompile(`secret-token`).MatchString(key) {
+		t.Fatalf("redis key leaks the token: %s", key)
+	}
+}
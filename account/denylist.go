@@ -0,0 +1,37 @@
+package account
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisDenylist keeps the jti of revoked access tokens until they would have expired anyway
+type RedisDenylist struct {
+	rdb *redis.Client
+}
+
+func NewRedisDenylist(rdb *redis.Client) *RedisDenylist {
+	return &RedisDenylist{rdb: rdb}
+}
+
+func denyKey(jti string) string {
+	return "jwt-denylist:" + jti
+}
+
+// Deny revokes a token for ttl; a token that is already expired needs no entry
+func (d *RedisDenylist) Deny(ctx context.Context, jti string, ttl time.Duration) error {
+	if ttl <= 0 {
+		return nil
+	}
+	return d.rdb.Set(ctx, denyKey(jti), 1, ttl).Err()
+}
+
+func (d *RedisDenylist) IsDenied(ctx context.Context, jti string) (bool, error) {
+	n, err := d.rdb.Exists(ctx, denyKey(jti)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
@@ -0,0 +1,119 @@
+package account
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginLimiter counts failed logins per account and per client IP and locks
+// either out for a growing period once it passes its threshold
+type LoginLimiter interface {
+	// Locked returns how long the account or IP is still locked, 0 when it is not
+	Locked(ctx context.Context, account, ip string) (time.Duration, error)
+	// Fail records a failed attempt and returns the lockout it caused, if any
+	Fail(ctx context.Context, account, ip string) (time.Duration, error)
+	// Succeed clears the failure counter of the account
+	Succeed(ctx context.Context, account string) error
+}
+
+// LockoutPolicy describes when and for how long a key gets locked
+type LockoutPolicy struct {
+	Threshold int           // failures allowed before the first lockout
+	Base      time.Duration // first lockout, doubled on every further failure
+	Max       time.Duration
+	Window    time.Duration // failures older than this are forgotten
+}
+
+var (
+	DefaultAccountPolicy = LockoutPolicy{Threshold: 5, Base: 30 * time.Second, Max: time.Hour, Window: 24 * time.Hour}
+	DefaultIPPolicy      = LockoutPolicy{Threshold: 20, Base: time.Minute, Max: time.Hour, Window: time.Hour}
+)
+
+// LockoutFor returns the lockout after the given number of failures:
+// nothing below the threshold, then Base, 2*Base, 4*Base, ... up to Max
+func (p LockoutPolicy) LockoutFor(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+	d := p.Base
+	for i := p.Threshold; i < failures && d < p.Max; i++ {
+		d *= 2
+	}
+	if d > p.Max {
+		d = p.Max
+	}
+	return d
+}
+
+type RedisLoginLimiter struct {
+	rdb     *redis.Client
+	account LockoutPolicy
+	ip      LockoutPolicy
+}
+
+func NewRedisLoginLimiter(rdb *redis.Client, account, ip LockoutPolicy) *RedisLoginLimiter {
+	return &RedisLoginLimiter{rdb: rdb, account: account, ip: ip}
+}
+
+// akun dinormalisasi supaya "Budi@Example.com" dan "budi@example.com" berbagi counter
+func accountKey(account string) string {
+	return "account:" + HashToken(strings.ToLower(strings.TrimSpace(account)))
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func (l *RedisLoginLimiter) Locked(ctx context.Context, account, ip string) (time.Duration, error) {
+	var longest time.Duration
+	for _, key := range []string{accountKey(account), ipKey(ip)} {
+		ttl, err := l.rdb.PTTL(ctx, "login-lock:"+key).Result()
+		if err != nil {
+			return 0, err
+		}
+		if ttl > longest {
+			longest = ttl
+		}
+	}
+	return longest, nil
+}
+
+func (l *RedisLoginLimiter) Fail(ctx context.Context, account, ip string) (time.Duration, error) {
+	a, err := l.fail(ctx, accountKey(account), l.account)
+	if err != nil {
+		return 0, err
+	}
+	b, err := l.fail(ctx, ipKey(ip), l.ip)
+	if err != nil {
+		return 0, err
+	}
+	if b > a {
+		return b, nil
+	}
+	return a, nil
+}
+
+func (l *RedisLoginLimiter) fail(ctx context.Context, key string, policy LockoutPolicy) (time.Duration, error) {
+	pipe := l.rdb.TxPipeline()
+	incr := pipe.Incr(ctx, "login-fail:"+key)
+	pipe.Expire(ctx, "login-fail:"+key, policy.Window)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, err
+	}
+
+	lockout := policy.LockoutFor(int(incr.Val()))
+	if lockout > 0 {
+		if err := l.rdb.Set(ctx, "login-lock:"+key, 1, lockout).Err(); err != nil {
+			return 0, err
+		}
+	}
+	return lockout, nil
+}
+
+func (l *RedisLoginLimiter) Succeed(ctx context.Context, account string) error {
+	key := accountKey(account)
+	return l.rdb.Del(ctx, "login-fail:"+key, "login-lock:"+key).Err()
+}
@@ -0,0 +1,32 @@
+package account
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLockoutPolicy_LockoutFor(t *testing.T) {
+	p := LockoutPolicy{Threshold: 3, Base: time.Second, Max: 10 * time.Second}
+
+	cases := map[int]time.Duration{
+		0:  0,
+		2:  0,
+		3:  time.Second,
+		4:  2 * time.Second,
+		5:  4 * time.Second,
+		6:  8 * time.Second,
+		7:  10 * time.Second,
+		50: 10 * time.Second,
+	}
+	for failures, want := range cases {
+		if got := p.LockoutFor(failures); got != want {
+			t.Errorf("LockoutFor(%d) = %s, want %s", failures, got, want)
+		}
+	}
+}
+
+func TestAccountKey_Normalized(t *testing.T) {
+	if accountKey(" Budi@Example.com ") != accountKey("budi@example.com") {
+		t.Fatalf("expected the same key regardless of case and spaces")
+	}
+}
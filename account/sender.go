@@ -0,0 +1,76 @@
+// Package account holds the pieces of self-service account management that are
+// not plain SQL: one-time verification codes, password reset tokens and the
+// channel used to deliver them.
+package account
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Delivery channels
+const (
+	ChannelEmail = "email"
+	ChannelPhone = "phone"
+)
+
+// Sender delivers a message to an email address or phone number
+type Sender interface {
+	Send(ctx context.Context, channel, to, message string) error
+}
+
+// LogSender only writes messages to the log. It is meant for development and tests.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, channel, to, message string) error {
+	log.Printf("[sender] %s to %s: %s", channel, to, message)
+	return nil
+}
+
+// WebhookSender POSTs every message as JSON to URL, for a mail/SMS gateway to deliver
+type WebhookSender struct {
+	URL    string
+	Client *http.Client
+}
+
+func NewWebhookSender(url string) *WebhookSender {
+	return &WebhookSender{
+		URL:    url,
+		Client: &http.Client{Timeout: 5 * time.Second},
+	}
+}
+
+type webhookMessage struct {
+	Channel string `json:"channel"`
+	To      string `json:"to"`
+	Message string `json:"message"`
+}
+
+func (s *WebhookSender) Send(ctx context.Context, channel, to, message string) error {
+	body, err := json.Marshal(webhookMessage{Channel: channel, To: to, Message: message})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sender webhook responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package account
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestWebhookSender_PostsMessage(t *testing.T) {
+	var got webhookMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	if err := NewWebhookSender(srv.URL).Send(context.Background(), ChannelEmail, "budi@example.com", "code 123456"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := webhookMessage{Channel: ChannelEmail, To: "budi@example.com", Message: "code 123456"}
+	if got != want {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+}
+
+func TestWebhookSender_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	if err := NewWebhookSender(srv.URL).Send(context.Background(), ChannelPhone, "08123450000", "x"); err == nil {
+		t.Fatalf("expected error on 502")
+	}
+}
@@ -0,0 +1,67 @@
+package account
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// RFC 6238 appendix B test vectors (SHA1), secret "12345678901234567890"
+func TestTOTPCode_RFC6238(t *testing.T) {
+	secret := b32.EncodeToString([]byte("12345678901234567890"))
+	cases := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1111111111: "050471",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+	for unix, want := range cases {
+		got, err := TOTPCode(secret, TOTPStep(time.Unix(unix, 0)))
+		if err != nil || got != want {
+			t.Errorf("t=%d: expected %s, got %s (%v)", unix, want, got, err)
+		}
+	}
+}
+
+func TestVerifyTOTP_Skew(t *testing.T) {
+	secret, _ := NewTOTPSecret()
+	now := time.Unix(1_700_000_000, 0)
+
+	prev, _ := TOTPCode(secret, TOTPStep(now)-1)
+	if step, ok := VerifyTOTP(secret, prev, now); !ok || step != TOTPStep(now)-1 {
+		t.Fatalf("expected previous step to be accepted")
+	}
+
+	old, _ := TOTPCode(secret, TOTPStep(now)-3)
+	if _, ok := VerifyTOTP(secret, old, now); ok {
+		t.Fatalf("expected code from 90s ago to be rejected")
+	}
+	if _, ok := VerifyTOTP(secret, "12345", now); ok {
+		t.Fatalf("expected short code to be rejected")
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("Order Service", "sari@corp.example", "ABC")
+	if !strings.HasPrefix(uri, "otpauth://totp/Order%20Service:sari@corp.example?") ||
+		!strings.Contains(uri, "secret=ABC") || !strings.Contains(uri, "issuer=Order+Service") {
+		t.Fatalf("unexpected uri %s", uri)
+	}
+}
+
+func TestNewRecoveryCodes(t *testing.T) {
+	codes, err := NewRecoveryCodes(RecoveryCodeCount)
+	if err != nil || len(codes) != RecoveryCodeCount {
+		t.Fatalf("expected %d codes, got %d (%v)", RecoveryCodeCount, len(codes), err)
+	}
+	re := regexp.MustCompile(`^[a-z2-7]{4}-[a-z2-7]{4}-[a-z2-7]{4}$`)
+	seen := map[string]bool{}
+	for _, c := range codes {
+		if !re.MatchString(c) || seen[c] || !IsRecoveryCode(c) {
+			t.Fatalf("unexpected or duplicate code %q", c)
+		}
+		seen[c] = true
+	}
+}
@@ -0,0 +1,293 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"order-service-sample/account"
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// findUser looks a user up by email when the identifier contains "@", by phone otherwise
+func findUser(emailOrPhone string) (repository.User, string, error) {
+	emailOrPhone = strings.TrimSpace(emailOrPhone)
+	if strings.Contains(emailOrPhone, "@") {
+		u, err := repository.GetUserByEmail(db, strings.ToLower(emailOrPhone))
+		return u, account.ChannelEmail, err
+	}
+	u, err := repository.GetUserByPhone(db, emailOrPhone)
+	return u, account.ChannelPhone, err
+}
+
+// sendCooldown is the minimum time between two codes or reset tokens sent to the same user
+func sendCooldown() time.Duration {
+	return helper.GetEnvDuration("CODE_RESEND_COOLDOWN", time.Minute)
+}
+
+// sendVerificationCode creates a new code for the channel and delivers it
+func sendVerificationCode(ctx context.Context, user repository.User, channel string) error {
+	if err := codeStore.StartCooldown(ctx, "verify:"+channel, user.ID, sendCooldown()); err != nil {
+		return err
+	}
+	code, err := account.NewCode()
+	if err != nil {
+		return err
+	}
+	ttl := helper.GetEnvDuration("VERIFICATION_CODE_TTL", 15*time.Minute)
+	if err := codeStore.SaveCode(ctx, user.ID, channel, code, ttl); err != nil {
+		return err
+	}
+
+	to := user.Email
+	if channel == account.ChannelPhone {
+		to = user.Phone
+	}
+	return codeSender.Send(ctx, channel, to, fmt.Sprintf("Your verification code is %s (valid for %s)", code, ttl))
+}
+
+func RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.RegisterReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	req, errs := helper.NormalizeRegistration(req)
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	hash, err := helper.HashPassword(req.Password)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to hash password")
+		return
+	}
+
+	// constraint UNIQUE di tabel users yang menentukan, jadi tidak ada race antara cek dan insert
+	userID, err := repository.CreateUser(db, req.Email, req.Phone, hash)
+	if err != nil {
+		switch err.Error() {
+		case "email_taken":
+			helper.WriteJSON(w, http.StatusConflict, map[string]any{
+				"error":  "account already exists",
+				"fields": []model.FieldError{{Field: "email", Message: "email is already registered"}},
+			})
+		case "phone_taken":
+			helper.WriteJSON(w, http.StatusConflict, map[string]any{
+				"error":  "account already exists",
+				"fields": []model.FieldError{{Field: "phone", Message: "phone is already registered"}},
+			})
+		default:
+			helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to create user")
+		}
+		return
+	}
+
+	user := repository.User{ID: userID, Email: req.Email, Phone: req.Phone}
+	for _, channel := range []string{account.ChannelEmail, account.ChannelPhone} {
+		if err := sendVerificationCode(ctx, user, channel); err != nil {
+			// akun tetap dibuat, code bisa diminta ulang lewat /verify/resend
+			log.Printf("register: failed to send %s verification for user %d: %v", channel, userID, err)
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusCreated, model.UserResp{ID: userID, Email: req.Email, Phone: req.Phone})
+}
+
+func VerifyHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.VerifyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if req.EmailOrPhone == "" || req.Code == "" {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "email_or_phone and code are required")
+		return
+	}
+
+	// user tidak ditemukan dijawab sama seperti code salah
+	user, channel, err := findUser(req.EmailOrPhone)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid or expired code")
+		return
+	}
+
+	ok, err := codeStore.CheckCode(ctx, user.ID, channel, strings.TrimSpace(req.Code))
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to check code")
+		return
+	}
+	if !ok {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid or expired code")
+		return
+	}
+
+	if err := repository.MarkUserVerified(db, user.ID, channel); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to verify user")
+		return
+	}
+
+	profile, err := repository.GetUserProfile(db, user.ID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	helper.WriteJSON(w, http.StatusOK, profile)
+}
+
+func ResendVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.ForgotPasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if user, channel, err := findUser(req.EmailOrPhone); err == nil {
+		if err := sendVerificationCode(r.Context(), user, channel); err != nil {
+			log.Printf("verify: failed to resend %s code for user %d: %v", channel, user.ID, err)
+		}
+	}
+
+	// jawaban selalu sama supaya tidak bisa dipakai untuk menebak akun
+	helper.WriteJSON(w, http.StatusAccepted, map[string]string{"message": "if the account exists, a new code has been sent"})
+}
+
+func ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.ForgotPasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if user, channel, err := findUser(req.EmailOrPhone); err == nil {
+		if err := sendResetToken(ctx, user, channel); err != nil {
+			log.Printf("password: failed to send reset token for user %d: %v", user.ID, err)
+		}
+	}
+
+	helper.WriteJSON(w, http.StatusAccepted, map[string]string{"message": "if the account exists, a reset token has been sent"})
+}
+
+func sendResetToken(ctx context.Context, user repository.User, channel string) error {
+	if err := codeStore.StartCooldown(ctx, "password-reset", user.ID, sendCooldown()); err != nil {
+		return err
+	}
+	token, err := account.NewToken()
+	if err != nil {
+		return err
+	}
+	ttl := helper.GetEnvDuration("PASSWORD_RESET_TTL", 30*time.Minute)
+	if err := codeStore.SaveResetToken(ctx, token, user.ID, ttl); err != nil {
+		return err
+	}
+
+	to := user.Email
+	if channel == account.ChannelPhone {
+		to = user.Phone
+	}
+	return codeSender.Send(ctx, channel, to, fmt.Sprintf("Your password reset token is %s (valid for %s)", token, ttl))
+}
+
+func ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.ResetPasswordReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	// validasi password dulu supaya token tidak terbuang untuk request yang salah
+	errs := helper.ValidatePassword("new_password", req.NewPassword)
+	if req.Token == "" {
+		errs = append(errs, model.FieldError{Field: "token", Message: "token is required"})
+	}
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	hash, err := helper.HashPassword(req.NewPassword)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to hash password")
+		return
+	}
+
+	userID, err := codeStore.ConsumeResetToken(ctx, req.Token)
+	if err == account.ErrTokenNotFound {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid or expired token")
+		return
+	}
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to check token")
+		return
+	}
+
+	if err := repository.UpdateUserPassword(db, userID, hash); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to update password")
+		return
+	}
+	// sesi lama ikut dicabut, access token yang masih hidup habis sendiri dalam hitungan menit
+	if err := repository.RevokeUserRefreshTokens(db, userID); err != nil {
+		log.Printf("password: failed to revoke refresh tokens of user %d: %v", userID, err)
+	}
+
+	helper.WriteJSON(w, http.StatusOK, map[string]string{"message": "password has been reset"})
+}
+
+// SetUserRoleHandler lets an admin change the role of a user; it applies from the
+// user's next login or token refresh
+func SetUserRoleHandler(w http.ResponseWriter, r *http.Request) {
+	userID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || userID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid user id")
+		return
+	}
+
+	var req model.SetUserRoleReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if !helper.IsValidRole(req.Role) {
+		helper.WriteValidationErrorJSON(w, []model.FieldError{{
+			Field:   "role",
+			Message: "role must be one of customer, warehouse_operator, admin",
+		}})
+		return
+	}
+
+	if err := repository.SetUserRole(db, userID, req.Role); err != nil {
+		if err.Error() == "user_not_found" {
+			helper.WriteErrorJSON(w, http.StatusNotFound, "user not found")
+			return
+		}
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to update role")
+		return
+	}
+	log.Printf("audit: %s set role of user %d to %s", helper.ActorFromContext(r.Context()), userID, req.Role)
+
+	profile, err := repository.GetUserProfile(db, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	helper.WriteJSON(w, http.StatusOK, profile)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/repository"
+
+	"github.com/gorilla/mux"
+)
+
+func ListAddressesHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helper.GetUserIDFromContext(r.Context())
+
+	addresses, err := repository.ListAddresses(db, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load addresses")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, addresses)
+}
+
+func CreateAddressHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := helper.GetUserIDFromContext(ctx)
+
+	var req model.CreateAddressReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	req, errs := helper.NormalizeAddress(req)
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	address, err := repository.CreateAddress(ctx, db, userID, req)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to create address")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusCreated, address)
+}
+
+func GetAddressHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helper.GetUserIDFromContext(r.Context())
+
+	addressID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || addressID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid address id")
+		return
+	}
+
+	address, err := repository.GetAddress(db, userID, addressID)
+	if err != nil {
+		writeAddressError(w, err, "failed to load address")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, address)
+}
+
+func UpdateAddressHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := helper.GetUserIDFromContext(ctx)
+
+	addressID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || addressID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid address id")
+		return
+	}
+
+	var req model.UpdateAddressReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	req, errs := helper.NormalizeAddressUpdate(req)
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	address, err := repository.UpdateAddress(ctx, db, userID, addressID, req)
+	if err != nil {
+		writeAddressError(w, err, "failed to update address")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, address)
+}
+
+func DeleteAddressHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helper.GetUserIDFromContext(r.Context())
+
+	addressID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || addressID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid address id")
+		return
+	}
+
+	if err := repository.DeleteAddress(db, userID, addressID); err != nil {
+		writeAddressError(w, err, "failed to delete address")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAddressError reports addresses of other users the same as missing ones
+func writeAddressError(w http.ResponseWriter, err error, fallback string) {
+	if err.Error() == "address_not_found" {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "address not found")
+		return
+	}
+	helper.WriteErrorJSON(w, http.StatusInternalServerError, fallback)
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"order-service-sample/helper"
+	"order-service-sample/repository"
+)
+
+func LowStockAlertsHandler(w http.ResponseWriter, r *http.Request) {
+	warehouseID := 0
+	if v := r.URL.Query().Get("warehouse_id"); v != "" {
+		id, err := strconv.Atoi(v)
+		if err != nil || id <= 0 {
+			helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid warehouse_id")
+			return
+		}
+		warehouseID = id
+	}
+
+	alerts, err := repository.GetLowStockBreaches(db, warehouseID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to get low stock alerts")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, alerts)
+}
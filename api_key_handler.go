@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"order-service-sample/account"
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/repository"
+
+	"github.com/gorilla/mux"
+)
+
+const apiKeyPrefix = "sk_"
+
+// apiKeyVerifier resolves X-API-Key headers for middleware.AuthMiddleware
+type apiKeyVerifier struct{}
+
+func (apiKeyVerifier) VerifyAPIKey(ctx context.Context, key string) (model.APIKey, error) {
+	k, err := repository.GetActiveAPIKey(db, account.HashToken(key))
+	if err != nil {
+		return k, err
+	}
+	// last-used cukup best effort, request tidak perlu gagal karena ini
+	if err := repository.TouchAPIKey(db, k.ID); err != nil {
+		log.Printf("api-key: failed to update last_used_at of key %d: %v", k.ID, err)
+	}
+	return k, nil
+}
+
+func CreateAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateAPIKeyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	var errs []model.FieldError
+	req.Service = strings.TrimSpace(req.Service)
+	if req.Service == "" || len(req.Service) > 100 {
+		errs = append(errs, model.FieldError{Field: "service", Message: "service is required (max 100 characters)"})
+	}
+	if len(req.Scopes) == 0 {
+		errs = append(errs, model.FieldError{Field: "scopes", Message: "at least one scope is required"})
+	}
+	for _, s := range req.Scopes {
+		if !helper.IsValidScope(s) {
+			errs = append(errs, model.FieldError{
+				Field:   "scopes",
+				Message: "unknown scope " + s + ", allowed: " + strings.Join(helper.APIKeyScopes, ", "),
+			})
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		errs = append(errs, model.FieldError{Field: "expires_at", Message: "expires_at must be in the future"})
+	}
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	secret, err := account.NewToken()
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to generate key")
+		return
+	}
+	plain := apiKeyPrefix + secret
+
+	key, err := repository.CreateAPIKey(db, model.APIKey{
+		Service:   req.Service,
+		Prefix:    plain[:len(apiKeyPrefix)+8],
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: helper.ActorFromContext(r.Context()),
+	}, account.HashToken(plain))
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to create key")
+		return
+	}
+	log.Printf("audit: %s created API key %d for service %s with scopes %s",
+		key.CreatedBy, key.ID, key.Service, strings.Join(key.Scopes, ","))
+
+	helper.WriteJSON(w, http.StatusCreated, model.CreateAPIKeyResp{APIKey: key, Key: plain})
+}
+
+func ListAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	keys, err := repository.ListAPIKeys(db)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to list keys")
+		return
+	}
+	helper.WriteJSON(w, http.StatusOK, keys)
+}
+
+func RevokeAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || id <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid key id")
+		return
+	}
+
+	if err := repository.RevokeAPIKey(db, id); err != nil {
+		if err.Error() == "api_key_not_found" {
+			helper.WriteErrorJSON(w, http.StatusNotFound, "API key not found")
+			return
+		}
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to revoke key")
+		return
+	}
+	log.Printf("audit: %s revoked API key %d", helper.ActorFromContext(r.Context()), id)
+
+	w.WriteHeader(http.StatusNoContent)
+}
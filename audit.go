@@ -0,0 +1,15 @@
+package main
+
+import (
+	"context"
+
+	"order-service-sample/model"
+	"order-service-sample/repository"
+)
+
+// dbAuditor stores forbidden attempts in the audit_log table
+type dbAuditor struct{}
+
+func (dbAuditor) RecordForbidden(ctx context.Context, a model.ForbiddenAttempt) error {
+	return repository.RecordForbiddenAttempt(db, a)
+}
@@ -0,0 +1,170 @@
+// Package bulk implements the CSV import and export run modes used to load
+// the product catalogue and warehouse stock without hand-written SQL.
+package bulk
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+const DefaultBatchSize = 500
+
+// Options controls how an import is applied
+type Options struct {
+	DryRun    bool
+	BatchSize int
+}
+
+// RowError describes why a single CSV line was not applied
+type RowError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+func (e RowError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// Result summarises an import run
+type Result struct {
+	Total   int        `json:"total"`
+	Applied int        `json:"applied"`
+	DryRun  bool       `json:"dry_run"`
+	Errors  []RowError `json:"errors"`
+}
+
+// line pairs a parsed value with the CSV line it came from
+type line[T any] struct {
+	Line int
+	Row  T
+}
+
+// readCSV reads every record and returns the column index of each header name.
+// All required columns must be present in the header row.
+func readCSV(r io.Reader, required []string) (map[string]int, [][]string, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	cr.FieldsPerRecord = -1
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil, errors.New("csv file is empty")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cols := make(map[string]int, len(header))
+	for i, h := range header {
+		cols[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	for _, name := range required {
+		if _, ok := cols[name]; !ok {
+			return nil, nil, fmt.Errorf("missing required column %q", name)
+		}
+	}
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, nil, err
+	}
+	return cols, records, nil
+}
+
+// field returns the trimmed value of a column, or "" if the record is too short
+func field(rec []string, cols map[string]int, name string) string {
+	i, ok := cols[name]
+	if !ok || i >= len(rec) {
+		return ""
+	}
+	return strings.TrimSpace(rec[i])
+}
+
+// runBatches applies rows in transactions of opts.BatchSize rows. Every row runs
+// inside its own savepoint so a failing row is reported without aborting the rest
+// of its batch. afterBatch (optional) runs inside the batch transaction once all
+// rows have been attempted. In dry-run mode each batch is rolled back instead of committed.
+func runBatches[T any](db *sql.DB, rows []line[T], opts Options, apply func(tx *sql.Tx, row T) error, afterBatch func(tx *sql.Tx, applied []T) error) (Result, error) {
+	size := opts.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+
+	result := Result{Total: len(rows), DryRun: opts.DryRun}
+
+	for start := 0; start < len(rows); start += size {
+		end := start + size
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		applied, rowErrs, err := runBatch(db, rows[start:end], opts.DryRun, apply, afterBatch)
+		if err != nil {
+			return result, fmt.Errorf("batch starting at line %d: %w", rows[start].Line, err)
+		}
+		result.Applied += applied
+		result.Errors = append(result.Errors, rowErrs...)
+	}
+
+	return result, nil
+}
+
+func runBatch[T any](db *sql.DB, rows []line[T], dryRun bool, apply func(tx *sql.Tx, row T) error, afterBatch func(tx *sql.Tx, applied []T) error) (int, []RowError, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return 0, nil, err
+	}
+	defer tx.Rollback()
+
+	var ok []T
+	var rowErrs []RowError
+
+	for _, r := range rows {
+		if _, err := tx.Exec(`SAVEPOINT bulk_row`); err != nil {
+			return 0, nil, err
+		}
+
+		if err := apply(tx, r.Row); err != nil {
+			if _, rbErr := tx.Exec(`ROLLBACK TO SAVEPOINT bulk_row`); rbErr != nil {
+				return 0, nil, rbErr
+			}
+			rowErrs = append(rowErrs, RowError{Line: r.Line, Message: err.Error()})
+			continue
+		}
+
+		if _, err := tx.Exec(`RELEASE SAVEPOINT bulk_row`); err != nil {
+			return 0, nil, err
+		}
+		ok = append(ok, r.Row)
+	}
+
+	if afterBatch != nil && len(ok) > 0 {
+		if err := afterBatch(tx, ok); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	if dryRun {
+		return len(ok), rowErrs, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, nil, err
+	}
+	return len(ok), rowErrs, nil
+}
+
+// withParseErrors merges validation errors found while parsing into the result, ordered by line
+func withParseErrors(result Result, parseErrs []RowError) Result {
+	result.Total += len(parseErrs)
+	result.Errors = append(parseErrs, result.Errors...)
+	sort.SliceStable(result.Errors, func(i, j int) bool {
+		return result.Errors[i].Line < result.Errors[j].Line
+	})
+	return result
+}
@@ -0,0 +1,218 @@
+package bulk
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestParseProducts_Validation(t *testing.T) {
+	csv := `sku,name,description,price
+SKU-1,Mouse,Wireless mouse,150000
+SKU-2,,no name,1000
+SKU-3,Keyboard,,abc
+SKU-1,Mouse again,,1
+,No SKU,,10
+SKU-4,Hub,,350000.50
+`
+	rows, rowErrs, err := ParseProducts(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 valid rows, got %d", len(rows))
+	}
+	if rows[1].Line != 7 || rows[1].Row.Price != "350000.50" {
+		t.Fatalf("unexpected second row: %+v", rows[1])
+	}
+
+	wantLines := []int{3, 4, 5, 6}
+	if len(rowErrs) != len(wantLines) {
+		t.Fatalf("expected %d row errors, got %v", len(wantLines), rowErrs)
+	}
+	for i, ln := range wantLines {
+		if rowErrs[i].Line != ln {
+			t.Fatalf("expected error on line %d, got %+v", ln, rowErrs[i])
+		}
+	}
+}
+
+func TestParseProducts_Sizes(t *testing.T) {
+	csv := `sku,name,price,weight_grams,length_mm,width_mm,height_mm
+SKU-1,Mouse,150000,150,120,70,40
+SKU-2,Hub,350000,,,,
+SKU-3,Keyboard,700000,-1,450,150,45
+`
+	rows, rowErrs, err := ParseProducts(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 2 || len(rowErrs) != 1 || rowErrs[0].Line != 4 {
+		t.Fatalf("unexpected result: %+v %+v", rows, rowErrs)
+	}
+	if p := rows[0].Row; p.WeightGrams == nil || *p.WeightGrams != 150 || *p.HeightMM != 40 {
+		t.Fatalf("expected sizes on first row, got %+v", p)
+	}
+	if rows[1].Row.WeightGrams != nil {
+		t.Fatalf("empty weight should stay nil, got %d", *rows[1].Row.WeightGrams)
+	}
+}
+
+func TestParseProducts_MissingColumn(t *testing.T) {
+	_, _, err := ParseProducts(strings.NewReader("sku,name\nA,B\n"))
+	if err == nil || !strings.Contains(err.Error(), "price") {
+		t.Fatalf("expected missing price column error, got %v", err)
+	}
+}
+
+func TestParseStock_Validation(t *testing.T) {
+	csv := `warehouse_id,product_id,quantity
+1,1,20
+x,1,5
+1,2,-1
+1,1,30
+2,1,0
+`
+	rows, rowErrs, err := ParseStock(strings.NewReader(csv))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 valid rows, got %d", len(rows))
+	}
+	if len(rowErrs) != 3 {
+		t.Fatalf("expected 3 row errors, got %v", rowErrs)
+	}
+}
+
+func TestImportStock_PerRowErrorsAndSync(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	csv := `warehouse_id,product_id,quantity
+1,1,20
+1,2,1
+bad,1,1
+`
+	mock.ExpectBegin()
+
+	mock.ExpectExec(`SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO warehouse_stock`).WithArgs(1, 1, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(15))
+	mock.ExpectExec(`INSERT INTO stock_movements`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`RELEASE SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(`SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO warehouse_stock`).WithArgs(1, 2, 1).WillReturnError(sql.ErrNoRows)
+	mock.ExpectExec(`ROLLBACK TO SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	mock.ExpectExec(`UPDATE products SET stock`).WithArgs(1).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	result, err := ImportStock(db, strings.NewReader(csv), Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Total != 3 || result.Applied != 1 || len(result.Errors) != 2 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if result.Errors[0].Line != 3 || result.Errors[0].Message != "quantity_below_reserved" {
+		t.Fatalf("expected quantity_below_reserved on line 3, got %+v", result.Errors[0])
+	}
+	if result.Errors[1].Line != 4 {
+		t.Fatalf("expected parse error on line 4, got %+v", result.Errors[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportProducts_DryRunRollsBack(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	csv := "sku,name,price\nSKU-1,Mouse,150000\n"
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO products`).
+		WithArgs("SKU-1", "Mouse", "", "150000", nil, nil, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectExec(`RELEASE SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	result, err := ImportProducts(db, strings.NewReader(csv), Options{DryRun: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !result.DryRun || result.Applied != 1 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportProducts_BatchSize(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	csv := "sku,name,price\nA,One,1\nB,Two,2\n"
+
+	for _, sku := range []string{"A", "B"} {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`INSERT INTO products`).
+			WithArgs(sku, sqlmock.AnyArg(), "", sqlmock.AnyArg(), nil, nil, nil, nil).
+			WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectExec(`RELEASE SAVEPOINT bulk_row`).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+	}
+
+	result, err := ImportProducts(db, strings.NewReader(csv), Options{BatchSize: 1})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Applied != 2 {
+		t.Fatalf("expected 2 applied, got %d", result.Applied)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportProducts_BeginError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin().WillReturnError(errors.New("db down"))
+
+	_, err := ImportProducts(db, strings.NewReader("sku,name,price\nA,One,1\n"), Options{})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestExportStock(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"warehouse_id", "name", "product_id", "name", "quantity", "reserved"}).
+		AddRow(1, "Central Warehouse", 1, "Wireless Mouse", 20, 2)
+	mock.ExpectQuery(`SELECT ws.warehouse_id`).WillReturnRows(rows)
+
+	var buf bytes.Buffer
+	if err := ExportStock(db, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "warehouse_id,warehouse_name,product_id,product_name,quantity,reserved,available\n" +
+		"1,Central Warehouse,1,Wireless Mouse,20,2,18\n"
+	if buf.String() != want {
+		t.Fatalf("unexpected csv:\n%s", buf.String())
+	}
+}
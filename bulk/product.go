@@ -0,0 +1,112 @@
+package bulk
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"order-service-sample/model"
+	"order-service-sample/repository"
+)
+
+var priceRe = regexp.MustCompile(`^\d{1,10}(\.\d{1,2})?$`)
+
+// ParseProducts reads a product CSV with the columns sku, name, price and the
+// optional description, weight_grams, length_mm, width_mm and height_mm. Invalid rows
+// are returned as RowErrors and skipped.
+func ParseProducts(r io.Reader) ([]line[model.ProductImportRow], []RowError, error) {
+	cols, records, err := readCSV(r, []string{"sku", "name", "price"})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []line[model.ProductImportRow]
+	var rowErrs []RowError
+	seen := map[string]int{}
+
+	for i, rec := range records {
+		ln := i + 2 // header is line 1
+
+		p := model.ProductImportRow{
+			SKU:         field(rec, cols, "sku"),
+			Name:        field(rec, cols, "name"),
+			Description: field(rec, cols, "description"),
+			Price:       field(rec, cols, "price"),
+		}
+
+		switch {
+		case p.SKU == "":
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: "sku is required"})
+			continue
+		case len(p.SKU) > 64:
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: "sku must be at most 64 characters"})
+			continue
+		case p.Name == "":
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: "name is required"})
+			continue
+		case len(p.Name) > 100:
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: "name must be at most 100 characters"})
+			continue
+		case !priceRe.MatchString(p.Price):
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: fmt.Sprintf("invalid price %q", p.Price)})
+			continue
+		}
+
+		if msg := parseSizes(rec, cols, &p); msg != "" {
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: msg})
+			continue
+		}
+
+		if first, dup := seen[p.SKU]; dup {
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: fmt.Sprintf("duplicate sku %q (first seen on line %d)", p.SKU, first)})
+			continue
+		}
+		seen[p.SKU] = ln
+
+		rows = append(rows, line[model.ProductImportRow]{Line: ln, Row: p})
+	}
+
+	return rows, rowErrs, nil
+}
+
+// parseSizes fills the optional weight and dimension columns; empty cells stay nil
+func parseSizes(rec []string, cols map[string]int, p *model.ProductImportRow) string {
+	targets := []struct {
+		name string
+		dst  **int
+	}{
+		{"weight_grams", &p.WeightGrams},
+		{"length_mm", &p.LengthMM},
+		{"width_mm", &p.WidthMM},
+		{"height_mm", &p.HeightMM},
+	}
+	for _, t := range targets {
+		v := field(rec, cols, t.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return fmt.Sprintf("%s must be an integer >= 0", t.name)
+		}
+		*t.dst = &n
+	}
+	return ""
+}
+
+// ImportProducts upserts products by sku in batched transactions
+func ImportProducts(db *sql.DB, r io.Reader, opts Options) (Result, error) {
+	rows, rowErrs, err := ParseProducts(r)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := runBatches(db, rows, opts, func(tx *sql.Tx, p model.ProductImportRow) error {
+		_, err := repository.UpsertProductBySKU(tx, p)
+		return err
+	}, nil)
+
+	return withParseErrors(result, rowErrs), err
+}
@@ -0,0 +1,111 @@
+package bulk
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+
+	"order-service-sample/model"
+	"order-service-sample/repository"
+)
+
+// ParseStock reads a stock CSV with the columns warehouse_id, product_id and quantity.
+// Invalid rows are returned as RowErrors and skipped.
+func ParseStock(r io.Reader) ([]line[model.StockImportRow], []RowError, error) {
+	cols, records, err := readCSV(r, []string{"warehouse_id", "product_id", "quantity"})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []line[model.StockImportRow]
+	var rowErrs []RowError
+	seen := map[[2]int]int{}
+
+	for i, rec := range records {
+		ln := i + 2 // header is line 1
+
+		warehouseID, err := strconv.Atoi(field(rec, cols, "warehouse_id"))
+		if err != nil || warehouseID <= 0 {
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: "invalid warehouse_id"})
+			continue
+		}
+		productID, err := strconv.Atoi(field(rec, cols, "product_id"))
+		if err != nil || productID <= 0 {
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: "invalid product_id"})
+			continue
+		}
+		qty, err := strconv.Atoi(field(rec, cols, "quantity"))
+		if err != nil || qty < 0 {
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: "quantity must be an integer >= 0"})
+			continue
+		}
+
+		key := [2]int{warehouseID, productID}
+		if first, dup := seen[key]; dup {
+			rowErrs = append(rowErrs, RowError{Line: ln, Message: fmt.Sprintf("duplicate warehouse/product pair (first seen on line %d)", first)})
+			continue
+		}
+		seen[key] = ln
+
+		rows = append(rows, line[model.StockImportRow]{Line: ln, Row: model.StockImportRow{
+			WarehouseID: warehouseID,
+			ProductID:   productID,
+			Quantity:    qty,
+		}})
+	}
+
+	return rows, rowErrs, nil
+}
+
+// ImportStock upserts warehouse_stock rows in batched transactions and keeps
+// products.stock in sync for every product touched by a batch.
+func ImportStock(db *sql.DB, r io.Reader, opts Options) (Result, error) {
+	rows, rowErrs, err := ParseStock(r)
+	if err != nil {
+		return Result{}, err
+	}
+
+	result, err := runBatches(db, rows, opts, func(tx *sql.Tx, s model.StockImportRow) error {
+		return repository.UpsertWarehouseStock(tx, s.WarehouseID, s.ProductID, s.Quantity, "system:import")
+	}, func(tx *sql.Tx, applied []model.StockImportRow) error {
+		synced := map[int]bool{}
+		for _, s := range applied {
+			if synced[s.ProductID] {
+				continue
+			}
+			if err := repository.SyncProductStock(tx, s.ProductID); err != nil {
+				return err
+			}
+			synced[s.ProductID] = true
+		}
+		return nil
+	})
+
+	return withParseErrors(result, rowErrs), err
+}
+
+// ExportStock writes the current stock of every warehouse as CSV
+func ExportStock(db *sql.DB, w io.Writer) error {
+	items, err := repository.GetStockPerWarehouse(db)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"warehouse_id", "warehouse_name", "product_id", "product_name", "quantity", "reserved", "available"})
+	for _, it := range items {
+		cw.Write([]string{
+			strconv.Itoa(it.WarehouseID),
+			it.WarehouseName,
+			strconv.Itoa(it.ProductID),
+			it.ProductName,
+			strconv.Itoa(it.Quantity),
+			strconv.Itoa(it.Reserved),
+			strconv.Itoa(it.Available),
+		})
+	}
+	cw.Flush()
+	return cw.Error()
+}
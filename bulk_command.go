@@ -0,0 +1,107 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"order-service-sample/bulk"
+)
+
+// runImportCommand handles the "import" and "import-stock" run modes.
+//
+//	order-service-sample import [--dry-run] [--batch-size=N] products.csv
+//	order-service-sample import-stock [--dry-run] [--batch-size=N] stock.csv
+//
+// It returns the process exit code: 0 when every row was applied, 1 otherwise.
+func runImportCommand(db *sql.DB, mode string, args []string) int {
+	fs := flag.NewFlagSet(mode, flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "validate and apply rows inside a transaction that is rolled back")
+	batchSize := fs.Int("batch-size", bulk.DefaultBatchSize, "number of rows per transaction")
+
+	files, err := parseArgs(fs, args)
+	if err != nil {
+		return 2
+	}
+	if len(files) != 1 {
+		fmt.Fprintf(os.Stderr, "usage: order-service-sample %s [--dry-run] [--batch-size=N] <file.csv>\n", mode)
+		return 2
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		log.Println("import: failed to open file:", err)
+		return 1
+	}
+	defer f.Close()
+
+	opts := bulk.Options{DryRun: *dryRun, BatchSize: *batchSize}
+
+	var result bulk.Result
+	if mode == "import-stock" {
+		result, err = bulk.ImportStock(db, f, opts)
+	} else {
+		result, err = bulk.ImportProducts(db, f, opts)
+	}
+
+	for _, rowErr := range result.Errors {
+		fmt.Fprintln(os.Stderr, rowErr.Error())
+	}
+
+	if err != nil {
+		log.Println("import: failed:", err)
+		return 1
+	}
+
+	prefix := ""
+	if result.DryRun {
+		prefix = "[dry-run] "
+	}
+	log.Printf("%simport: %d rows read, %d applied, %d failed", prefix, result.Total, result.Applied, len(result.Errors))
+
+	if len(result.Errors) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runExportCommand handles the "export" run mode and writes the current stock
+// per warehouse as CSV to the given file, or to stdout when no file is given.
+//
+//	order-service-sample export [stock.csv]
+func runExportCommand(db *sql.DB, args []string) int {
+	var out io.Writer = os.Stdout
+	if len(args) > 0 {
+		f, err := os.Create(args[0])
+		if err != nil {
+			log.Println("export: failed to create file:", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if err := bulk.ExportStock(db, out); err != nil {
+		log.Println("export: failed:", err)
+		return 1
+	}
+	return 0
+}
+
+// parseArgs parses flags that may appear before or after positional arguments
+func parseArgs(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
@@ -0,0 +1,33 @@
+package cart
+
+import (
+	"context"
+	"database/sql"
+
+	"order-service-sample/repository"
+)
+
+// PostgresStore is the durable cart store backed by the cart_items table
+type PostgresStore struct {
+	db *sql.DB
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{db: db}
+}
+
+func (s *PostgresStore) Items(ctx context.Context, userID int) (map[int]int, error) {
+	return repository.GetCartItems(s.db, userID)
+}
+
+func (s *PostgresStore) SetQty(ctx context.Context, userID, productID, qty int) error {
+	return repository.UpsertCartItem(s.db, userID, productID, qty)
+}
+
+func (s *PostgresStore) Remove(ctx context.Context, userID, productID int) error {
+	return repository.DeleteCartItem(s.db, userID, productID)
+}
+
+func (s *PostgresStore) Clear(ctx context.Context, userID int) error {
+	return repository.ClearCart(s.db, userID)
+}
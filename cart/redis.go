@@ -0,0 +1,62 @@
+package cart
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore keeps each cart as a hash "cart:{user_id}" of product_id -> qty
+type RedisStore struct {
+	rdb *redis.Client
+	ttl time.Duration
+}
+
+func NewRedisStore(rdb *redis.Client, ttl time.Duration) *RedisStore {
+	return &RedisStore{rdb: rdb, ttl: ttl}
+}
+
+func cartKey(userID int) string {
+	return fmt.Sprintf("cart:%d", userID)
+}
+
+func (s *RedisStore) Items(ctx context.Context, userID int) (map[int]int, error) {
+	raw, err := s.rdb.HGetAll(ctx, cartKey(userID)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make(map[int]int, len(raw))
+	for k, v := range raw {
+		productID, err := strconv.Atoi(k)
+		if err != nil {
+			continue
+		}
+		qty, err := strconv.Atoi(v)
+		if err != nil {
+			continue
+		}
+		items[productID] = qty
+	}
+	return items, nil
+}
+
+func (s *RedisStore) SetQty(ctx context.Context, userID, productID, qty int) error {
+	key := cartKey(userID)
+	pipe := s.rdb.TxPipeline()
+	pipe.HSet(ctx, key, strconv.Itoa(productID), qty)
+	pipe.Expire(ctx, key, s.ttl)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+func (s *RedisStore) Remove(ctx context.Context, userID, productID int) error {
+	return s.rdb.HDel(ctx, cartKey(userID), strconv.Itoa(productID)).Err()
+}
+
+func (s *RedisStore) Clear(ctx context.Context, userID int) error {
+	return s.rdb.Del(ctx, cartKey(userID)).Err()
+}
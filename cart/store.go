@@ -0,0 +1,88 @@
+// Package cart keeps each user's shopping cart on the server between requests.
+package cart
+
+import (
+	"context"
+	"log"
+)
+
+// Store persists cart lines as product_id -> qty per user
+type Store interface {
+	Items(ctx context.Context, userID int) (map[int]int, error)
+	SetQty(ctx context.Context, userID, productID, qty int) error
+	Remove(ctx context.Context, userID, productID int) error
+	Clear(ctx context.Context, userID int) error
+}
+
+// FallbackStore serves carts from a fast primary store (Redis) and keeps a
+// durable copy in a fallback store (Postgres). Writes go to the fallback first;
+// reads fall back to it when the primary fails or has lost the cart.
+type FallbackStore struct {
+	Primary  Store
+	Fallback Store
+}
+
+func NewFallbackStore(primary, fallback Store) *FallbackStore {
+	return &FallbackStore{Primary: primary, Fallback: fallback}
+}
+
+func (s *FallbackStore) Items(ctx context.Context, userID int) (map[int]int, error) {
+	items, err := s.Primary.Items(ctx, userID)
+	if err == nil && len(items) > 0 {
+		return items, nil
+	}
+	if err != nil {
+		log.Println("cart: primary store read failed, using fallback:", err)
+	}
+
+	items, err = s.Fallback.Items(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// warm the primary store again so the next read is served from it
+	for productID, qty := range items {
+		if err := s.Primary.SetQty(ctx, userID, productID, qty); err != nil {
+			// jangan tinggalkan cart setengah jadi di primary
+			s.dropPrimary(ctx, userID, err)
+			break
+		}
+	}
+	return items, nil
+}
+
+func (s *FallbackStore) SetQty(ctx context.Context, userID, productID, qty int) error {
+	if err := s.Fallback.SetQty(ctx, userID, productID, qty); err != nil {
+		return err
+	}
+	if err := s.Primary.SetQty(ctx, userID, productID, qty); err != nil {
+		s.dropPrimary(ctx, userID, err)
+	}
+	return nil
+}
+
+func (s *FallbackStore) Remove(ctx context.Context, userID, productID int) error {
+	if err := s.Fallback.Remove(ctx, userID, productID); err != nil {
+		return err
+	}
+	if err := s.Primary.Remove(ctx, userID, productID); err != nil {
+		s.dropPrimary(ctx, userID, err)
+	}
+	return nil
+}
+
+func (s *FallbackStore) Clear(ctx context.Context, userID int) error {
+	if err := s.Fallback.Clear(ctx, userID); err != nil {
+		return err
+	}
+	// primary yang gagal dikosongkan akan terus menyajikan cart lama, jadi ini error
+	return s.Primary.Clear(ctx, userID)
+}
+
+// dropPrimary discards the primary copy after a failed write so it is never served stale
+func (s *FallbackStore) dropPrimary(ctx context.Context, userID int, cause error) {
+	log.Println("cart: primary store write failed:", cause)
+	if err := s.Primary.Clear(ctx, userID); err != nil {
+		log.Println("cart: failed to drop stale primary cart:", err)
+	}
+}
@@ -0,0 +1,159 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// memStore is an in-memory Store used to exercise FallbackStore
+type memStore struct {
+	carts map[int]map[int]int
+	err   error
+}
+
+func newMemStore() *memStore {
+	return &memStore{carts: map[int]map[int]int{}}
+}
+
+func (m *memStore) Items(ctx context.Context, userID int) (map[int]int, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	items := map[int]int{}
+	for k, v := range m.carts[userID] {
+		items[k] = v
+	}
+	return items, nil
+}
+
+func (m *memStore) SetQty(ctx context.Context, userID, productID, qty int) error {
+	if m.err != nil {
+		return m.err
+	}
+	if m.carts[userID] == nil {
+		m.carts[userID] = map[int]int{}
+	}
+	m.carts[userID][productID] = qty
+	return nil
+}
+
+func (m *memStore) Remove(ctx context.Context, userID, productID int) error {
+	if m.err != nil {
+		return m.err
+	}
+	delete(m.carts[userID], productID)
+	return nil
+}
+
+func (m *memStore) Clear(ctx context.Context, userID int) error {
+	if m.err != nil {
+		return m.err
+	}
+	delete(m.carts, userID)
+	return nil
+}
+
+func TestFallbackStore_WritesBoth(t *testing.T) {
+	primary, fallback := newMemStore(), newMemStore()
+	s := NewFallbackStore(primary, fallback)
+	ctx := context.Background()
+
+	if err := s.SetQty(ctx, 1, 10, 2); err != nil {
+		t.Fatalf("SetQty err: %v", err)
+	}
+	if primary.carts[1][10] != 2 || fallback.carts[1][10] != 2 {
+		t.Fatalf("expected both stores to hold qty 2: primary=%v fallback=%v", primary.carts, fallback.carts)
+	}
+
+	if err := s.Clear(ctx, 1); err != nil {
+		t.Fatalf("Clear err: %v", err)
+	}
+	if len(primary.carts[1]) != 0 || len(fallback.carts[1]) != 0 {
+		t.Fatalf("expected both stores to be cleared")
+	}
+}
+
+func TestFallbackStore_ReadsFallbackWhenPrimaryFails(t *testing.T) {
+	primary, fallback := newMemStore(), newMemStore()
+	fallback.carts[1] = map[int]int{10: 3}
+	primary.err = errors.New("redis down")
+
+	items, err := NewFallbackStore(primary, fallback).Items(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Items err: %v", err)
+	}
+	if items[10] != 3 {
+		t.Fatalf("expected qty 3 from fallback, got %v", items)
+	}
+}
+
+func TestFallbackStore_WarmsPrimaryOnMiss(t *testing.T) {
+	primary, fallback := newMemStore(), newMemStore()
+	fallback.carts[1] = map[int]int{10: 3, 11: 1}
+
+	items, err := NewFallbackStore(primary, fallback).Items(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Items err: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items, got %v", items)
+	}
+	if primary.carts[1][10] != 3 || primary.carts[1][11] != 1 {
+		t.Fatalf("expected primary to be warmed, got %v", primary.carts[1])
+	}
+}
+
+// flakyStore fails every SetQty after the first okWrites calls
+type flakyStore struct {
+	*memStore
+	okWrites int
+}
+
+func (f *flakyStore) SetQty(ctx context.Context, userID, productID, qty int) error {
+	if f.okWrites == 0 {
+		return errors.New("redis down")
+	}
+	f.okWrites--
+	return f.memStore.SetQty(ctx, userID, productID, qty)
+}
+
+func TestFallbackStore_FailedWarmDropsPartialCart(t *testing.T) {
+	primary, fallback := &flakyStore{memStore: newMemStore(), okWrites: 1}, newMemStore()
+	fallback.carts[1] = map[int]int{10: 3, 11: 1}
+
+	items, err := NewFallbackStore(primary, fallback).Items(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("Items err: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 items from fallback, got %v", items)
+	}
+	if len(primary.carts[1]) != 0 {
+		t.Fatalf("expected partial primary cart to be dropped, got %v", primary.carts[1])
+	}
+}
+
+func TestFallbackStore_PrimaryWriteFailureStillSucceeds(t *testing.T) {
+	primary, fallback := newMemStore(), newMemStore()
+	primary.err = errors.New("redis down")
+
+	if err := NewFallbackStore(primary, fallback).SetQty(context.Background(), 1, 10, 1); err != nil {
+		t.Fatalf("expected durable write to succeed, got %v", err)
+	}
+	if fallback.carts[1][10] != 1 {
+		t.Fatalf("expected fallback to hold the line")
+	}
+}
+
+func TestFallbackStore_FallbackWriteFailureIsReturned(t *testing.T) {
+	primary, fallback := newMemStore(), newMemStore()
+	fallback.err = errors.New("db down")
+
+	if err := NewFallbackStore(primary, fallback).SetQty(context.Background(), 1, 10, 1); err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(primary.carts[1]) != 0 {
+		t.Fatalf("primary must not be written when the durable write fails")
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/repository"
+
+	"github.com/gorilla/mux"
+)
+
+func GetCartHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := helper.GetUserIDFromContext(ctx)
+
+	cart, err := loadCart(ctx, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load cart")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, cart)
+}
+
+func AddCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := helper.GetUserIDFromContext(ctx)
+
+	var req model.CartItemReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.ProductID <= 0 || req.Qty <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "product_id and qty must be > 0")
+		return
+	}
+
+	exists, err := repository.ProductExists(db, req.ProductID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !exists {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "product not found")
+		return
+	}
+
+	// tambahkan ke qty yang sudah ada di cart
+	items, err := cartStore.Items(ctx, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load cart")
+		return
+	}
+
+	if err := cartStore.SetQty(ctx, userID, req.ProductID, items[req.ProductID]+req.Qty); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to update cart")
+		return
+	}
+
+	writeCart(ctx, w, userID)
+}
+
+func UpdateCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := helper.GetUserIDFromContext(ctx)
+
+	productID, err := strconv.Atoi(mux.Vars(r)["product_id"])
+	if err != nil || productID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid product id")
+		return
+	}
+
+	var req model.UpdateCartItemReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.Qty < 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "qty must be >= 0")
+		return
+	}
+
+	items, err := cartStore.Items(ctx, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load cart")
+		return
+	}
+	if _, ok := items[productID]; !ok {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "product not in cart")
+		return
+	}
+
+	// qty 0 berarti hapus item dari cart
+	if req.Qty == 0 {
+		err = cartStore.Remove(ctx, userID, productID)
+	} else {
+		err = cartStore.SetQty(ctx, userID, productID, req.Qty)
+	}
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to update cart")
+		return
+	}
+
+	writeCart(ctx, w, userID)
+}
+
+func DeleteCartItemHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := helper.GetUserIDFromContext(ctx)
+
+	productID, err := strconv.Atoi(mux.Vars(r)["product_id"])
+	if err != nil || productID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid product id")
+		return
+	}
+
+	if err := cartStore.Remove(ctx, userID, productID); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to update cart")
+		return
+	}
+
+	writeCart(ctx, w, userID)
+}
+
+func ClearCartHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := helper.GetUserIDFromContext(ctx)
+
+	if err := cartStore.Clear(ctx, userID); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to clear cart")
+		return
+	}
+
+	writeCart(ctx, w, userID)
+}
+
+func CartCheckoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := helper.GetUserIDFromContext(ctx)
+
+	// body boleh kosong, alamat default yang dipakai
+	var req model.CartCheckoutReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	cart, err := loadCart(ctx, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load cart")
+		return
+	}
+	if len(cart.Items) == 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "cart is empty")
+		return
+	}
+
+	items := make([]model.CheckoutItem, 0, len(cart.Items))
+	for _, line := range cart.Items {
+		items = append(items, model.CheckoutItem{ProductID: line.ProductID, Qty: line.Qty})
+	}
+
+	// kosongkan cart dulu: cart lama yang masih terbaca setelah order dibuat bisa di-checkout dua kali
+	if err := cartStore.Clear(ctx, userID); err != nil {
+		restoreCart(ctx, userID, items)
+		helper.WriteErrorJSON(w, http.StatusServiceUnavailable, "failed to clear cart, try again")
+		return
+	}
+
+	resp, err := placeOrder(ctx, userID, items, req.ShippingAddressID, req.ShippingMethod)
+	if err != nil {
+		restoreCart(ctx, userID, items)
+		writeCheckoutError(w, err)
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusCreated, resp)
+}
+
+// restoreCart puts the lines of a checkout that did not go through back into the cart
+func restoreCart(ctx context.Context, userID int, items []model.CheckoutItem) {
+	for _, item := range items {
+		if err := cartStore.SetQty(ctx, userID, item.ProductID, item.Qty); err != nil {
+			log.Printf("cart checkout: failed to restore product %d in cart of user %d: %v", item.ProductID, userID, err)
+		}
+	}
+}
+
+// loadCart reads the stored cart and prices every line with the current product price.
+// Lines whose product no longer exists are dropped from the cart.
+func loadCart(ctx context.Context, userID int) (model.CartResp, error) {
+	items, err := cartStore.Items(ctx, userID)
+	if err != nil {
+		return model.CartResp{}, err
+	}
+
+	productIDs := make([]int, 0, len(items))
+	for productID := range items {
+		productIDs = append(productIDs, productID)
+	}
+	sort.Ints(productIDs)
+
+	resp := model.CartResp{Items: []model.CartLine{}}
+	for _, productID := range productIDs {
+		price, err := repository.GetProductPrice(db, productID)
+		if err != nil {
+			if err.Error() == "product_not_found" {
+				cartStore.Remove(ctx, userID, productID)
+				continue
+			}
+			return model.CartResp{}, err
+		}
+
+		line := model.CartLine{
+			ProductID: productID,
+			Qty:       items[productID],
+			UnitPrice: price,
+			Subtotal:  price * int64(items[productID]),
+		}
+		resp.Items = append(resp.Items, line)
+		resp.TotalAmount += line.Subtotal
+	}
+
+	return resp, nil
+}
+
+func writeCart(ctx context.Context, w http.ResponseWriter, userID int) {
+	cart, err := loadCart(ctx, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load cart")
+		return
+	}
+	helper.WriteJSON(w, http.StatusOK, cart)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+
+	"order-service-sample/helper"
+	"order-service-sample/repository"
+
+	"github.com/gorilla/mux"
+)
+
+func ListCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+	categories, err := repository.GetAllCategories(db)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load categories")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, repository.BuildCategoryTree(categories))
+}
+
+func CategoryProductsHandler(w http.ResponseWriter, r *http.Request) {
+	categoryID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || categoryID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid category id")
+		return
+	}
+
+	exists, err := repository.CategoryExists(db, categoryID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !exists {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "category not found")
+		return
+	}
+
+	products, err := repository.GetProductsByCategory(db, categoryID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load products")
+		return
+	}
+
+	if err := attachProductImages(products); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load product images")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, products)
+}
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -12,49 +14,109 @@ import (
 	"order-service-sample/helper"
 	"order-service-sample/model"
 	"order-service-sample/repository"
+	"order-service-sample/shipping"
 
 	"github.com/gorilla/mux"
 )
 
+// LoginHandler answers every failed login with the same message and runs a bcrypt
+// comparison even when the user does not exist, so neither the response nor its
+// timing tells which accounts exist. Failures are counted per account and per IP.
 func LoginHandler(w http.ResponseWriter, r *http.Request) {
-	var req model.LoginReq
+	ctx := r.Context()
 
+	var req model.LoginReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
 		return
 	}
 
-	var user repository.User
-	var err error
-	if strings.Contains(req.EmailOrPhone, "@") {
-		user, err = repository.GetUserByEmail(db, req.EmailOrPhone)
-	} else {
-		user, err = repository.GetUserByPhone(db, req.EmailOrPhone)
+	ip := clientIP(r)
+	locked, err := loginLimiter.Locked(ctx, req.EmailOrPhone, ip)
+	if err != nil {
+		// Redis bermasalah: login tetap jalan, bcrypt sendiri sudah memperlambat tebakan
+		log.Println("login: failed to check lockout:", err)
+	}
+	if locked > 0 {
+		writeLoginLocked(w, locked)
+		return
 	}
 
+	user, _, err := findUser(req.EmailOrPhone)
+	hash := user.PasswordHash
 	if err != nil {
-		helper.WriteErrorJSON(w, http.StatusUnauthorized, "user not found")
-		return
+		hash = helper.DummyPasswordHash()
 	}
 
-	if !helper.CheckPasswordHash(req.Password, user.PasswordHash) {
+	if !helper.CheckPasswordHash(req.Password, hash) || err != nil {
+		lockout, ferr := loginLimiter.Fail(ctx, req.EmailOrPhone, ip)
+		if ferr != nil {
+			log.Println("login: failed to record failed attempt:", ferr)
+		}
+		if lockout > 0 {
+			writeLoginLocked(w, lockout)
+			return
+		}
 		helper.WriteErrorJSON(w, http.StatusUnauthorized, "invalid credentials")
 		return
 	}
 
-	token, err := helper.GenerateJWT(user.ID)
+	if err := loginLimiter.Succeed(ctx, req.EmailOrPhone); err != nil {
+		log.Println("login: failed to reset failed attempts:", err)
+	}
+
+	// dengan 2FA aktif, password saja hanya menghasilkan challenge untuk /login/2fa
+	totp, err := repository.GetTOTPState(db, user.ID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	if totp.Enabled {
+		writeTwoFactorChallenge(ctx, w, user.ID)
+		return
+	}
+
+	tokens, err := issueTokens(user.ID, user.Role, false)
 	if err != nil {
 		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to generate token")
 		return
 	}
 
-	var data = struct {
-		Token string `json:"token"`
-	}{
-		Token: token,
+	helper.WriteJSON(w, http.StatusOK, tokens)
+}
+
+// writeTwoFactorChallenge answers a first login step with a challenge for /login/2fa
+func writeTwoFactorChallenge(ctx context.Context, w http.ResponseWriter, userID int) {
+	challenge, err := loginChallenges.CreateChallenge(ctx, userID, loginChallengeTTL)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to start two-factor login")
+		return
 	}
+	helper.WriteJSON(w, http.StatusOK, model.TwoFactorChallengeResp{
+		TwoFactorRequired: true,
+		ChallengeToken:    challenge,
+		ExpiresIn:         int(loginChallengeTTL.Seconds()),
+	})
+}
 
-	helper.WriteJSON(w, http.StatusOK, data)
+func writeLoginLocked(w http.ResponseWriter, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(retryAfter.Round(time.Second).Seconds())))
+	helper.WriteErrorJSON(w, http.StatusTooManyRequests, "too many failed login attempts, try again later")
+}
+
+// clientIP returns the IP of the direct peer; X-Forwarded-For is only trusted
+// when TRUST_PROXY_HEADERS=true (the service runs behind our own proxy)
+func clientIP(r *http.Request) string {
+	if helper.GetEnv("TRUST_PROXY_HEADERS", "false") == "true" {
+		if fwd := r.Header.Get("X-Forwarded-For"); fwd != "" {
+			return strings.TrimSpace(strings.Split(fwd, ",")[0])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
 
 func ListProductsHandler(w http.ResponseWriter, r *http.Request) {
@@ -64,6 +126,11 @@ func ListProductsHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := attachProductImages(products); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load product images")
+		return
+	}
+
 	helper.WriteJSON(w, http.StatusOK, products)
 }
 
@@ -79,56 +146,262 @@ func CheckoutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if len(req.Items) == 0 {
-		helper.WriteErrorJSON(w, http.StatusBadRequest, "items cannot be empty")
+	resp, err := placeOrder(ctx, userID, req.Items, req.ShippingAddressID, req.ShippingMethod)
+	if err != nil {
+		writeCheckoutError(w, err)
+		return
+	}
+
+	// Return JSON
+	helper.WriteJSON(w, http.StatusCreated, resp)
+}
+
+// checkoutError carries the HTTP status a failed checkout should be reported with.
+// Validation failures also carry the offending fields.
+type checkoutError struct {
+	status  int
+	message string
+	fields  []model.FieldError
+}
+
+func (e *checkoutError) Error() string {
+	return e.message
+}
+
+func writeCheckoutError(w http.ResponseWriter, err error) {
+	if ce, ok := err.(*checkoutError); ok {
+		if len(ce.fields) > 0 {
+			helper.WriteValidationErrorJSON(w, ce.fields)
+			return
+		}
+		helper.WriteErrorJSON(w, ce.status, ce.message)
 		return
 	}
+	helper.WriteErrorJSON(w, http.StatusInternalServerError, err.Error())
+}
+
+// placeOrder creates a pending order for the items, snapshots the shipping address,
+// reserves their stock, starts the reservation TTL and prices the shipping. It is
+// shared by /checkout and /cart/checkout.
+func placeOrder(ctx context.Context, userID int, requested []model.CheckoutItem, shippingAddressID *int, shippingMethod string) (model.CheckoutResponse, error) {
+	var resp model.CheckoutResponse
+
+	// 1. Validasi & gabungkan product_id yang duplikat
+	items, fieldErrs := helper.NormalizeCheckoutItems(requested, helper.CheckoutLimitsFromEnv())
+	if shippingMethod != "" && !shipping.IsValidMethod(shippingMethod) {
+		fieldErrs = append(fieldErrs, model.FieldError{Field: "shipping_method", Message: "shipping_method must be regular or express"})
+	}
+	if len(fieldErrs) > 0 {
+		return resp, &checkoutError{status: http.StatusBadRequest, message: "validation failed", fields: fieldErrs}
+	}
+
+	address, err := resolveShippingAddress(userID, shippingAddressID)
+	if err != nil {
+		return resp, err
+	}
+	if address == nil && shippingMethod != "" {
+		return resp, &checkoutError{
+			status:  http.StatusBadRequest,
+			message: "validation failed",
+			fields:  []model.FieldError{{Field: "shipping_address_id", Message: "a shipping address is required for shipping"}},
+		}
+	}
+	if shippingMethod == "" {
+		shippingMethod = shipping.MethodRegular
+	}
+
+	// 2. Pastikan semua product ada sebelum menulis apapun
+	productIDs := make([]int, 0, len(items))
+	for _, item := range items {
+		productIDs = append(productIDs, item.ProductID)
+	}
+	prices, err := repository.GetProductPrices(db, productIDs)
+	if err != nil {
+		return resp, &checkoutError{status: http.StatusInternalServerError, message: "failed to load product prices"}
+	}
+
+	for i, item := range requested {
+		if _, ok := prices[item.ProductID]; !ok {
+			fieldErrs = append(fieldErrs, model.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "product not found"})
+		}
+	}
+	if len(fieldErrs) > 0 {
+		return resp, &checkoutError{status: http.StatusBadRequest, message: "validation failed", fields: fieldErrs}
+	}
 
 	// 3. Hitung total harga
 	var totalAmount int64
-	for _, item := range req.Items {
-		price, err := repository.GetProductPrice(db, item.ProductID)
+	for _, item := range items {
+		totalAmount += prices[item.ProductID] * int64(item.Qty)
+	}
+
+	// 4. Hitung ongkir sebelum menulis apapun, supaya metode yang tidak tersedia
+	// tidak meninggalkan order pending beserta reservasinya
+	var region string
+	var option model.ShippingOption
+	if address != nil {
+		region = address.Region
+		option, resp.ShippingOptions, err = chooseShipping(items, region, shippingMethod)
 		if err != nil {
-			helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid product_id")
-			return
+			return resp, err
 		}
-		totalAmount += price * int64(item.Qty)
 	}
 
-	// 4. Buat order
+	// 5. Buat order
 	orderID, err := repository.CreateOrder(db, userID, totalAmount)
 	if err != nil {
-		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to create order")
-		return
+		return resp, &checkoutError{status: http.StatusInternalServerError, message: "failed to create order"}
 	}
 
-	// 5. Insert order_items
-	for _, item := range req.Items {
-		price, _ := repository.GetProductPrice(db, item.ProductID)
+	// Simpan snapshot alamat supaya edit alamat berikutnya tidak mengubah order
+	if address != nil {
+		err := repository.SetOrderShippingAddress(db, orderID, address.ID, address.ShippingAddress)
+		if err != nil {
+			return resp, &checkoutError{status: http.StatusInternalServerError, message: "failed to save shipping address"}
+		}
+		err = repository.SetOrderShipping(db, orderID, option.Method, option.Cost)
+		if err != nil {
+			return resp, &checkoutError{status: http.StatusInternalServerError, message: "failed to save shipping method"}
+		}
+		resp.ShippingAddress = &address.ShippingAddress
+		resp.ShippingMethod = option.Method
+		resp.ShippingCost = option.Cost
+	}
 
-		err := repository.InsertOrderItem(db, orderID, item, price)
+	// 6. Insert order_items
+	for _, item := range items {
+		err := repository.InsertOrderItem(db, orderID, item, prices[item.ProductID])
 		if err != nil {
-			helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to save order items")
-			return
+			return resp, &checkoutError{status: http.StatusInternalServerError, message: "failed to save order items"}
 		}
 	}
 
-	// 6. Reserve stock (versi terbaru yang sudah kamu pakai)
-	err = repository.ReserveStockForOrder(ctx, db, orderID, req.Items)
+	// 7. Reserve stock, utamakan gudang di region tujuan
+	err = repository.ReserveStockForOrder(ctx, db, orderID, items, region)
 	if err != nil {
-		helper.WriteErrorJSON(w, http.StatusBadRequest, err.Error())
-		return
+		return resp, &checkoutError{status: http.StatusBadRequest, message: err.Error()}
 	}
 
 	ttlMinute := helper.ReservationTTLMinutesDefault
-	// 7. Set Redis TTL 5 menit
+	// 8. Set Redis TTL 5 menit
 	err = rdb.SetEx(ctx, "reservation:"+fmt.Sprintf("%d", orderID), orderID, time.Duration(ttlMinute)*time.Minute).Err()
 	log.Println("error set redis", err)
 
-	// 8. Return JSON
-	helper.WriteJSON(w, http.StatusCreated, model.CheckoutResponse{
-		OrderID: orderID,
-	})
+	// 9. Quote di langkah 4 dibaca tanpa lock; checkout lain bisa membuat reservasi
+	// jatuh ke gudang lain, jadi ongkir dihitung ulang dari gudang yang direservasi
+	if address != nil {
+		actual, err := repriceShipping(orderID, region, option)
+		if err != nil {
+			abandonOrder(ctx, orderID)
+			return model.CheckoutResponse{}, err
+		}
+		resp.ShippingCost = actual.Cost
+	}
+
+	resp.OrderID = orderID
+	resp.TotalAmount = totalAmount + resp.ShippingCost
+
+	return resp, nil
+}
+
+// repriceShipping prices the quoted method again for the warehouses the order was
+// actually reserved in and stores the new cost when it changed
+func repriceShipping(orderID int, destination string, quoted model.ShippingOption) (model.ShippingOption, error) {
+	lines, err := repository.GetOrderShipmentLines(db, orderID)
+	if err != nil {
+		return model.ShippingOption{}, &checkoutError{status: http.StatusInternalServerError, message: "failed to load shipment"}
+	}
+	options, err := shipping.Options(shippingRates, destination, lines)
+	if err != nil {
+		return model.ShippingOption{}, &checkoutError{status: http.StatusInternalServerError, message: "failed to calculate shipping"}
+	}
+
+	for _, option := range options {
+		if option.Method != quoted.Method {
+			continue
+		}
+		if option.Cost != quoted.Cost {
+			if err := repository.SetOrderShipping(db, orderID, option.Method, option.Cost); err != nil {
+				return model.ShippingOption{}, &checkoutError{status: http.StatusInternalServerError, message: "failed to save shipping method"}
+			}
+		}
+		return option, nil
+	}
+
+	return model.ShippingOption{}, &checkoutError{
+		status:  http.StatusConflict,
+		message: quoted.Method + " shipping is no longer available for the reserved stock, please try again",
+	}
+}
+
+// abandonOrder releases the reservation of an order that failed after stock was
+// reserved and cancels it, so a retry does not leave a pending duplicate behind
+func abandonOrder(ctx context.Context, orderID int) {
+	if err := repository.ReleaseReservationByOrderID(db, orderID); err != nil {
+		log.Printf("checkout: failed to release reservation of order %d: %v", orderID, err)
+	}
+	if err := repository.CancelOrder(db, orderID); err != nil {
+		log.Printf("checkout: failed to cancel order %d: %v", orderID, err)
+	}
+	if err := rdb.Del(ctx, fmt.Sprintf("reservation:%d", orderID)).Err(); err != nil {
+		log.Printf("checkout: failed to drop reservation ttl of order %d: %v", orderID, err)
+	}
+}
+
+// chooseShipping prices every shipping method for the warehouses the items would be
+// reserved in and picks the requested one. It writes nothing, so it runs before the order exists.
+func chooseShipping(items []model.CheckoutItem, destination, method string) (model.ShippingOption, []model.ShippingOption, error) {
+	lines, err := repository.PreviewShipmentLines(db, items, destination)
+	if err != nil {
+		if strings.HasPrefix(err.Error(), "no active warehouse") {
+			return model.ShippingOption{}, nil, &checkoutError{status: http.StatusBadRequest, message: err.Error()}
+		}
+		return model.ShippingOption{}, nil, &checkoutError{status: http.StatusInternalServerError, message: "failed to load shipment"}
+	}
+
+	options, err := shipping.Options(shippingRates, destination, lines)
+	if err != nil {
+		return model.ShippingOption{}, nil, &checkoutError{status: http.StatusInternalServerError, message: "failed to calculate shipping"}
+	}
+
+	for _, option := range options {
+		if option.Method == method {
+			return option, options, nil
+		}
+	}
+
+	return model.ShippingOption{}, options, &checkoutError{
+		status:  http.StatusBadRequest,
+		message: "validation failed",
+		fields:  []model.FieldError{{Field: "shipping_method", Message: method + " shipping is not available for this address"}},
+	}
+}
+
+// resolveShippingAddress loads the requested address of the user, or the default
+// address when none is requested. Without a default the order has no shipping address.
+func resolveShippingAddress(userID int, addressID *int) (*model.Address, error) {
+	var address model.Address
+	var err error
+	if addressID != nil {
+		address, err = repository.GetAddress(db, userID, *addressID)
+	} else {
+		address, err = repository.GetDefaultAddress(db, userID)
+	}
+
+	if err != nil && err.Error() == "address_not_found" {
+		if addressID == nil {
+			return nil, nil
+		}
+		return nil, &checkoutError{
+			status:  http.StatusBadRequest,
+			message: "validation failed",
+			fields:  []model.FieldError{{Field: "shipping_address_id", Message: "address not found"}},
+		}
+	}
+	if err != nil {
+		return nil, &checkoutError{status: http.StatusInternalServerError, message: "failed to load shipping address"}
+	}
+	return &address, nil
 }
 
 func PayHandler(w http.ResponseWriter, r *http.Request) {
@@ -178,7 +451,7 @@ func PayHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	defer tx.Rollback()
 
-	if err := repository.ApplyStockPayment(tx, items); err != nil {
+	if err := repository.ApplyStockPayment(tx, items, helper.ActorFromContext(ctx)); err != nil {
 		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to update stock")
 		return
 	}
@@ -204,73 +477,6 @@ func PayHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func TransferHandler(w http.ResponseWriter, r *http.Request) {
-	ctx := r.Context()
-
-	var req model.TransferReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
-		return
-	}
-
-	if req.ProductID == 0 || req.FromWarehouse == 0 || req.ToWarehouse == 0 || req.Quantity <= 0 {
-		helper.WriteErrorJSON(w, http.StatusBadRequest, "missing or invalid fields")
-		return
-	}
-
-	// validate warehouses active
-	ok, err := repository.CheckWarehouseActive(db, req.FromWarehouse)
-	if err != nil {
-		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to validate source warehouse")
-		return
-	}
-	if !ok {
-		helper.WriteErrorJSON(w, http.StatusBadRequest, "source warehouse not active or not found")
-		return
-	}
-
-	ok, err = repository.CheckWarehouseActive(db, req.ToWarehouse)
-	if err != nil {
-		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to validate destination warehouse")
-		return
-	}
-	if !ok {
-		helper.WriteErrorJSON(w, http.StatusBadRequest, "destination warehouse not active or not found")
-		return
-	}
-
-	// check available stock quickly (not strictly required because tx will check again)
-	avail, err := repository.GetAvailableStock(db, req.FromWarehouse, req.ProductID)
-	if err != nil {
-		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to check stock")
-		return
-	}
-	if avail < req.Quantity {
-		helper.WriteErrorJSON(w, http.StatusBadRequest, "not enough available stock in source warehouse")
-		return
-	}
-
-	// do transfer in transaction
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to begin transaction")
-		return
-	}
-	defer tx.Rollback()
-
-	if err := repository.TransferStock(tx, req.FromWarehouse, req.ToWarehouse, req.ProductID, req.Quantity); err != nil {
-		helper.WriteErrorJSON(w, http.StatusBadRequest, err.Error())
-		return
-	}
-
-	if err := tx.Commit(); err != nil {
-		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to commit transfer")
-		return
-	}
-
-	helper.WriteJSON(w, http.StatusOK, req)
-}
-
 func WarehouseUpdateStatusHandler(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	idStr := vars["id"]
@@ -305,6 +511,44 @@ func WarehouseUpdateStatusHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Deactivate: reservasi yang masih ada di warehouse ini ditangani sesuai policy
+	if req.Status == "deactive" {
+		if req.Policy == "" {
+			req.Policy = repository.DeactivateBlock
+		}
+
+		affected, err := repository.DeactivateWarehouse(r.Context(), db, warehouseID, req.Policy)
+		if err != nil {
+			switch err.Error() {
+			case "invalid_policy":
+				helper.WriteErrorJSON(w, http.StatusBadRequest, "policy must be 'block', 'complete' or 'reassign'")
+			case "warehouse_not_found":
+				helper.WriteErrorJSON(w, http.StatusNotFound, "warehouse not found")
+			case "warehouse_has_reservations":
+				helper.WriteJSON(w, http.StatusConflict, map[string]any{
+					"error":           "warehouse still holds reservations",
+					"affected_orders": affected,
+				})
+			case "cannot_reassign_reservation":
+				helper.WriteJSON(w, http.StatusConflict, map[string]any{
+					"error":           "no other active warehouse has enough stock to take over a reservation",
+					"affected_orders": affected,
+				})
+			default:
+				helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to update warehouse status")
+			}
+			return
+		}
+
+		helper.WriteJSON(w, http.StatusOK, map[string]any{
+			"id":              warehouseID,
+			"status":          req.Status,
+			"policy":          req.Policy,
+			"affected_orders": affected,
+		})
+		return
+	}
+
 	// Update status
 	err = repository.UpdateWarehouseStatus(db, warehouseID, req.Status)
 	if err != nil {
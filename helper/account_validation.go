@@ -0,0 +1,51 @@
+package helper
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"order-service-sample/model"
+)
+
+// bcrypt hanya memakai 72 byte pertama
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+var phonePattern = regexp.MustCompile(`^\+?[0-9]{8,15}$`)
+
+// ValidatePassword checks the password policy; field is used in the returned error
+func ValidatePassword(field, password string) []model.FieldError {
+	if len(password) < MinPasswordLength {
+		return []model.FieldError{{Field: field, Message: "password must be at least 8 characters"}}
+	}
+	if len(password) > MaxPasswordLength {
+		return []model.FieldError{{Field: field, Message: "password must be at most 72 bytes"}}
+	}
+	return nil
+}
+
+// NormalizeRegistration trims and lower-cases the email, trims the phone and
+// validates all fields of a registration
+func NormalizeRegistration(req model.RegisterReq) (model.RegisterReq, []model.FieldError) {
+	req.Email = strings.ToLower(strings.TrimSpace(req.Email))
+	req.Phone = strings.TrimSpace(req.Phone)
+
+	var errs []model.FieldError
+	if req.Email == "" {
+		errs = append(errs, model.FieldError{Field: "email", Message: "email is required"})
+	} else if addr, err := mail.ParseAddress(req.Email); err != nil || addr.Address != req.Email || len(req.Email) > 100 {
+		errs = append(errs, model.FieldError{Field: "email", Message: "email is not valid"})
+	}
+
+	if req.Phone == "" {
+		errs = append(errs, model.FieldError{Field: "phone", Message: "phone is required"})
+	} else if !phonePattern.MatchString(req.Phone) {
+		errs = append(errs, model.FieldError{Field: "phone", Message: "phone must be 8-15 digits, optionally starting with +"})
+	}
+
+	errs = append(errs, ValidatePassword("password", req.Password)...)
+	return req, errs
+}
@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"order-service-sample/model"
+)
+
+func TestNormalizeRegistration_Valid(t *testing.T) {
+	req, errs := NormalizeRegistration(model.RegisterReq{
+		Email:    "  Budi@Example.com ",
+		Phone:    "+628123456789",
+		Password: "rahasia123",
+	})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+	if req.Email != "budi@example.com" {
+		t.Fatalf("expected normalized email, got %q", req.Email)
+	}
+}
+
+func TestNormalizeRegistration_Invalid(t *testing.T) {
+	_, errs := NormalizeRegistration(model.RegisterReq{
+		Email:    "Budi <budi@example.com>",
+		Phone:    "0812-abc",
+		Password: "short",
+	})
+
+	fields := map[string]bool{}
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, f := range []string{"email", "phone", "password"} {
+		if !fields[f] {
+			t.Errorf("expected error for %s, got %+v", f, errs)
+		}
+	}
+}
+
+func TestValidatePassword_TooLong(t *testing.T) {
+	if errs := ValidatePassword("new_password", strings.Repeat("a", 73)); len(errs) != 1 || errs[0].Field != "new_password" {
+		t.Fatalf("expected new_password error, got %+v", errs)
+	}
+}
@@ -0,0 +1,82 @@
+package helper
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"order-service-sample/model"
+)
+
+var postalCodePattern = regexp.MustCompile(`^[0-9A-Za-z -]{3,10}$`)
+
+// NormalizeAddress trims all fields of a new address, lower-cases the region and
+// validates them
+func NormalizeAddress(req model.CreateAddressReq) (model.CreateAddressReq, []model.FieldError) {
+	fields := model.UpdateAddressReq{
+		Label:         &req.Label,
+		RecipientName: &req.RecipientName,
+		Phone:         &req.Phone,
+		Street:        &req.Street,
+		City:          &req.City,
+		Region:        &req.Region,
+		PostalCode:    &req.PostalCode,
+	}
+	return req, normalizeAddressFields(fields)
+}
+
+// NormalizeAddressUpdate does the same as NormalizeAddress for the fields that are set
+func NormalizeAddressUpdate(req model.UpdateAddressReq) (model.UpdateAddressReq, []model.FieldError) {
+	return req, normalizeAddressFields(req)
+}
+
+// normalizeAddressFields rewrites the fields through their pointers; nil fields are skipped
+func normalizeAddressFields(req model.UpdateAddressReq) []model.FieldError {
+	fields := []struct {
+		name     string
+		value    *string
+		max      int
+		required bool
+	}{
+		{"label", req.Label, 50, false},
+		{"recipient_name", req.RecipientName, 100, true},
+		{"phone", req.Phone, 20, true},
+		{"street", req.Street, 255, true},
+		{"city", req.City, 100, true},
+		{"region", req.Region, 50, true},
+		{"postal_code", req.PostalCode, 10, true},
+	}
+
+	var errs []model.FieldError
+	for _, f := range fields {
+		if f.value == nil {
+			continue
+		}
+		*f.value = strings.TrimSpace(*f.value)
+		if *f.value == "" {
+			if f.required {
+				errs = append(errs, model.FieldError{Field: f.name, Message: f.name + " is required"})
+			}
+			continue
+		}
+		if len(*f.value) > f.max {
+			errs = append(errs, model.FieldError{Field: f.name, Message: fmt.Sprintf("%s must be at most %d characters", f.name, f.max)})
+			continue
+		}
+
+		switch f.name {
+		case "region":
+			// disamakan dengan warehouses.region supaya bisa dipakai untuk pilih gudang
+			*f.value = strings.ToLower(*f.value)
+		case "phone":
+			if !phonePattern.MatchString(*f.value) {
+				errs = append(errs, model.FieldError{Field: "phone", Message: "phone must be 8-15 digits, optionally starting with +"})
+			}
+		case "postal_code":
+			if !postalCodePattern.MatchString(*f.value) {
+				errs = append(errs, model.FieldError{Field: "postal_code", Message: "postal_code is not valid"})
+			}
+		}
+	}
+	return errs
+}
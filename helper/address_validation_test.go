@@ -0,0 +1,60 @@
+package helper
+
+import (
+	"testing"
+
+	"order-service-sample/model"
+)
+
+func TestNormalizeAddress_Valid(t *testing.T) {
+	req, errs := NormalizeAddress(model.CreateAddressReq{
+		Label:         " Rumah ",
+		RecipientName: "Budi",
+		Phone:         "+628123456789",
+		Street:        "Jl. Sudirman No. 1",
+		City:          "Jakarta Selatan",
+		Region:        " Jakarta",
+		PostalCode:    "12190",
+	})
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+	if req.Label != "Rumah" || req.Region != "jakarta" {
+		t.Fatalf("expected trimmed label and lower-cased region, got %q / %q", req.Label, req.Region)
+	}
+}
+
+func TestNormalizeAddress_Invalid(t *testing.T) {
+	_, errs := NormalizeAddress(model.CreateAddressReq{
+		RecipientName: "  ",
+		Phone:         "0812-abc",
+		Street:        "Jl. Sudirman No. 1",
+		City:          "Jakarta Selatan",
+		PostalCode:    "12#90",
+	})
+
+	fields := map[string]bool{}
+	for _, e := range errs {
+		fields[e.Field] = true
+	}
+	for _, f := range []string{"recipient_name", "phone", "region", "postal_code"} {
+		if !fields[f] {
+			t.Errorf("expected error for %s, got %+v", f, errs)
+		}
+	}
+	if fields["label"] {
+		t.Errorf("label is optional, got %+v", errs)
+	}
+}
+
+func TestNormalizeAddressUpdate_SkipsUnsetFields(t *testing.T) {
+	empty := " "
+	region := "SURABAYA"
+	req, errs := NormalizeAddressUpdate(model.UpdateAddressReq{City: &empty, Region: &region})
+	if len(errs) != 1 || errs[0].Field != "city" {
+		t.Fatalf("expected only city error, got %+v", errs)
+	}
+	if *req.Region != "surabaya" {
+		t.Fatalf("expected lower-cased region, got %q", *req.Region)
+	}
+}
@@ -2,6 +2,9 @@ package helper
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"os"
 	"time"
 
@@ -12,6 +15,62 @@ type ContextKey string
 
 const UserIDKey ContextKey = "user_id"
 
+// RoleKey holds the role of the authenticated user
+const RoleKey ContextKey = "role"
+
+// Roles stored on users and embedded in access tokens
+const (
+	RoleCustomer          = "customer"
+	RoleWarehouseOperator = "warehouse_operator"
+	RoleAdmin             = "admin"
+)
+
+// RoleService is the role of requests authenticated with an API key instead of a
+// user token; what they may do is decided by the key's scopes
+const RoleService = "service"
+
+// ServiceKey holds the service principal name and ScopesKey the scopes of an API key
+const (
+	ServiceKey ContextKey = "service"
+	ScopesKey  ContextKey = "scopes"
+)
+
+// Scopes that can be granted to API keys
+const (
+	ScopeTransfersRead   = "transfers:read"
+	ScopeTransfersWrite  = "transfers:write"
+	ScopeWarehousesRead  = "warehouses:read"
+	ScopeWarehousesWrite = "warehouses:write"
+)
+
+var APIKeyScopes = []string{ScopeTransfersRead, ScopeTransfersWrite, ScopeWarehousesRead, ScopeWarehousesWrite}
+
+// IsValidScope reports whether scope can be granted to an API key
+func IsValidScope(scope string) bool {
+	for _, s := range APIKeyScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidRole reports whether role is one of the known roles
+func IsValidRole(role string) bool {
+	switch role {
+	case RoleCustomer, RoleWarehouseOperator, RoleAdmin:
+		return true
+	}
+	return false
+}
+
+// TokenClaimsKey holds the jwt.MapClaims of the access token used for the request
+const TokenClaimsKey ContextKey = "token_claims"
+
+// AccessTokenTTLDefault is used when ACCESS_TOKEN_TTL is not set; refresh tokens
+// take care of keeping the user logged in
+const AccessTokenTTLDefault = 15 * time.Minute
+
 var ReservationTTLMinutesDefault = 5
 
 func GetEnv(k, def string) string {
@@ -22,6 +81,15 @@ func GetEnv(k, def string) string {
 	return v
 }
 
+// GetEnvDuration reads a duration like "15m"; invalid or non-positive values fall back to def
+func GetEnvDuration(k string, def time.Duration) time.Duration {
+	d, err := time.ParseDuration(os.Getenv(k))
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
 // secret key disimpan di environment variable, misalnya JWT_SECRET
 // contoh di docker-compose.yml:
 // environment:
@@ -29,31 +97,53 @@ func GetEnv(k, def string) string {
 func jwtSecret() []byte {
 	secret := os.Getenv("JWT_SECRET")
 	if secret == "" {
-		secret = "defaultsecret" // fallback, jangan dipakai di production
+		secret = defaultJWTSecret // fallback, hanya boleh di dev mode (lihat CheckJWTConfig)
 	}
 	return []byte(secret)
 }
 
-// GenerateJWT membuat token JWT baru untuk user tertentu
-func GenerateJWT(userID int) (string, error) {
+// AccessTokenTTL is the lifetime of access tokens from GenerateJWT
+func AccessTokenTTL() time.Duration {
+	return GetEnvDuration("ACCESS_TOKEN_TTL", AccessTokenTTLDefault)
+}
+
+// GenerateJWT membuat access token JWT baru untuk user tertentu.
+// Setiap token punya jti unik supaya bisa di-revoke lewat denylist.
+// Ditandatangani dengan RS256/EdDSA (header kid) kalau key sudah di-load, HS256 kalau belum.
+func GenerateJWT(userID int, role string) (string, error) {
+	return GenerateJWTWithMFA(userID, role, false)
+}
+
+// GenerateJWTWithMFA is GenerateJWT with the mfa claim, set when the user has
+// two-factor authentication enabled (see RequiresMFA)
+func GenerateJWTWithMFA(userID int, role string, mfa bool) (string, error) {
+	jti := make([]byte, 16)
+	if _, err := rand.Read(jti); err != nil {
+		return "", err
+	}
+
 	claims := jwt.MapClaims{
 		"user_id": userID,
-		"exp":     time.Now().Add(24 * time.Hour).Unix(), // expired 1 hari
+		"role":    role,
+		"mfa":     mfa,
+		"jti":     hex.EncodeToString(jti),
+		"exp":     time.Now().Add(AccessTokenTTL()).Unix(),
 		"iat":     time.Now().Unix(),
 	}
 
+	if jwtKeys != nil {
+		token := jwt.NewWithClaims(jwtKeys.method, claims)
+		token.Header["kid"] = jwtKeys.kid
+		return token.SignedString(jwtKeys.signer)
+	}
+
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(jwtSecret())
 }
 
 // ValidateJWT memverifikasi token JWT dan mengembalikan claims-nya
 func ValidateJWT(tokenStr string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, jwt.ErrTokenSignatureInvalid
-		}
-		return jwtSecret(), nil
-	})
+	token, err := jwt.Parse(tokenStr, verificationKey)
 
 	if err != nil {
 		return nil, err
@@ -66,6 +156,33 @@ func ValidateJWT(tokenStr string) (jwt.MapClaims, error) {
 	return nil, jwt.ErrTokenInvalidClaims
 }
 
+// GetTokenClaimsFromContext returns the claims of the access token of the request
+func GetTokenClaimsFromContext(ctx context.Context) jwt.MapClaims {
+	if v, ok := ctx.Value(TokenClaimsKey).(jwt.MapClaims); ok {
+		return v
+	}
+	return nil
+}
+
+// RequiresMFA tells whether role may only use its privileged routes with two-factor authentication
+func RequiresMFA(role string) bool {
+	return role == RoleAdmin || role == RoleWarehouseOperator
+}
+
+// HasMFAFromContext tells whether the access token of the request carries the mfa claim
+func HasMFAFromContext(ctx context.Context) bool {
+	mfa, _ := GetTokenClaimsFromContext(ctx)["mfa"].(bool)
+	return mfa
+}
+
+// GetRoleFromContext returns the role of the authenticated user
+func GetRoleFromContext(ctx context.Context) string {
+	if v, ok := ctx.Value(RoleKey).(string); ok {
+		return v
+	}
+	return ""
+}
+
 // helper function untuk mengambil user id dari context di handler
 func GetUserIDFromContext(ctx context.Context) int {
 	if v, ok := ctx.Value(UserIDKey).(int); ok {
@@ -73,3 +190,23 @@ func GetUserIDFromContext(ctx context.Context) int {
 	}
 	return 0
 }
+
+// GetScopesFromContext returns the scopes of the API key of the request
+func GetScopesFromContext(ctx context.Context) []string {
+	if v, ok := ctx.Value(ScopesKey).([]string); ok {
+		return v
+	}
+	return nil
+}
+
+// ActorFromContext returns who is performing an action, for audit records:
+// "user:<id>" for user tokens, "service:<name>" for API keys, "system" otherwise
+func ActorFromContext(ctx context.Context) string {
+	if id := GetUserIDFromContext(ctx); id != 0 {
+		return fmt.Sprintf("user:%d", id)
+	}
+	if name, ok := ctx.Value(ServiceKey).(string); ok && name != "" {
+		return "service:" + name
+	}
+	return "system"
+}
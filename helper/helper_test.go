@@ -27,7 +27,7 @@ func TestGetEnv(t *testing.T) {
 func TestGenerateAndValidateJWT(t *testing.T) {
 	os.Setenv("JWT_SECRET", "unittestsecret") // set secret untuk test
 
-	token, err := GenerateJWT(42)
+	token, err := GenerateJWT(42, RoleCustomer)
 	if err != nil {
 		t.Fatalf("GenerateJWT returned error: %v", err)
 	}
@@ -47,6 +47,9 @@ func TestGenerateAndValidateJWT(t *testing.T) {
 	if int(userID) != 42 {
 		t.Fatalf("expected user_id=42, got %d", int(userID))
 	}
+	if claims["role"] != RoleCustomer {
+		t.Fatalf("expected role claim %q, got %v", RoleCustomer, claims["role"])
+	}
 }
 
 func TestValidateJWT_InvalidToken(t *testing.T) {
@@ -61,7 +64,7 @@ func TestValidateJWT_InvalidToken(t *testing.T) {
 func TestValidateJWT_WrongSignature(t *testing.T) {
 	// set secret 1 untuk sign
 	os.Setenv("JWT_SECRET", "secretA")
-	token, _ := GenerateJWT(99)
+	token, _ := GenerateJWT(99, RoleCustomer)
 
 	// ubah secret → signature mismatch
 	os.Setenv("JWT_SECRET", "secretB")
@@ -107,3 +110,26 @@ func TestGetUserIDFromContext(t *testing.T) {
 		t.Fatalf("expected default 0 when not set, got %d", uid)
 	}
 }
+
+func TestActorFromContext(t *testing.T) {
+	if a := ActorFromContext(context.Background()); a != "system" {
+		t.Fatalf("expected system, got %s", a)
+	}
+
+	ctx := context.WithValue(context.Background(), UserIDKey, 7)
+	if a := ActorFromContext(ctx); a != "user:7" {
+		t.Fatalf("expected user:7, got %s", a)
+	}
+}
+
+func TestGetEnvDuration(t *testing.T) {
+	t.Setenv("TEST_ENV_DURATION", "90s")
+	if d := GetEnvDuration("TEST_ENV_DURATION", time.Minute); d != 90*time.Second {
+		t.Fatalf("expected 90s, got %s", d)
+	}
+
+	t.Setenv("TEST_ENV_DURATION", "banana")
+	if d := GetEnvDuration("TEST_ENV_DURATION", time.Minute); d != time.Minute {
+		t.Fatalf("expected fallback 1m, got %s", d)
+	}
+}
@@ -0,0 +1,257 @@
+package helper
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const defaultJWTSecret = "defaultsecret"
+
+// jwtKeySet holds the asymmetric signing key and every public key tokens may be
+// verified with. During a key rotation the previous public key stays in verify until
+// the tokens signed with it have expired.
+type jwtKeySet struct {
+	kid    string
+	method jwt.SigningMethod
+	signer crypto.Signer
+	verify map[string]crypto.PublicKey
+}
+
+// jwtKeys is nil while tokens are signed with the HS256 JWT_SECRET
+var jwtKeys *jwtKeySet
+
+// JWK is one public key in the JSON Web Key Set format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadJWTKeysFromEnv switches signing to RS256/EdDSA when JWT_PRIVATE_KEY_FILE is set.
+// JWT_PUBLIC_KEY_FILES is a comma separated list of extra (old) public keys that are
+// still accepted for verification.
+func LoadJWTKeysFromEnv() error {
+	privatePath := os.Getenv("JWT_PRIVATE_KEY_FILE")
+	if privatePath == "" {
+		return nil
+	}
+
+	var publicPaths []string
+	for _, p := range strings.Split(os.Getenv("JWT_PUBLIC_KEY_FILES"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			publicPaths = append(publicPaths, p)
+		}
+	}
+	return LoadJWTKeys(privatePath, publicPaths)
+}
+
+// LoadJWTKeys loads a PEM private key (RSA or Ed25519) for signing, plus extra PEM
+// public keys for verification
+func LoadJWTKeys(privatePath string, publicPaths []string) error {
+	data, err := os.ReadFile(privatePath)
+	if err != nil {
+		return fmt.Errorf("read private key: %w", err)
+	}
+	signer, err := parsePrivateKeyPEM(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", privatePath, err)
+	}
+
+	method, err := signingMethodFor(signer.Public())
+	if err != nil {
+		return fmt.Errorf("%s: %w", privatePath, err)
+	}
+	kid, err := keyID(signer.Public())
+	if err != nil {
+		return err
+	}
+
+	keys := &jwtKeySet{
+		kid:    kid,
+		method: method,
+		signer: signer,
+		verify: map[string]crypto.PublicKey{kid: signer.Public()},
+	}
+
+	for _, path := range publicPaths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("read public key: %w", err)
+		}
+		pub, err := parsePublicKeyPEM(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if _, err := signingMethodFor(pub); err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		kid, err := keyID(pub)
+		if err != nil {
+			return err
+		}
+		keys.verify[kid] = pub
+	}
+
+	jwtKeys = keys
+	return nil
+}
+
+// CheckJWTConfig refuses the built-in HS256 secret outside dev mode
+// (APP_ENV=development)
+func CheckJWTConfig() error {
+	if jwtKeys != nil || IsDevMode() {
+		return nil
+	}
+	if secret := os.Getenv("JWT_SECRET"); secret == "" || secret == defaultJWTSecret {
+		return errors.New("JWT_SECRET is not set: configure JWT_PRIVATE_KEY_FILE or JWT_SECRET, or set APP_ENV=development")
+	}
+	return nil
+}
+
+// IsDevMode reports whether APP_ENV is development
+func IsDevMode() bool {
+	switch strings.ToLower(os.Getenv("APP_ENV")) {
+	case "dev", "development":
+		return true
+	}
+	return false
+}
+
+// PublicJWKS returns the verification keys for /.well-known/jwks.json; it is empty
+// while HS256 is used since that secret must never be published
+func PublicJWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	if jwtKeys == nil {
+		return set
+	}
+
+	for kid, pub := range jwtKeys.verify {
+		switch k := pub.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "RSA",
+				Use: "sig",
+				Alg: jwt.SigningMethodRS256.Alg(),
+				Kid: kid,
+				N:   base64.RawURLEncoding.EncodeToString(k.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			set.Keys = append(set.Keys, JWK{
+				Kty: "OKP",
+				Use: "sig",
+				Alg: jwt.SigningMethodEdDSA.Alg(),
+				Kid: kid,
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(k),
+			})
+		}
+	}
+	return set
+}
+
+// verificationKey picks the key for a token: the kid header when asymmetric keys are
+// configured, the HS256 secret otherwise. Mixing the two is refused so a token can not
+// choose a weaker algorithm than the server uses.
+func verificationKey(token *jwt.Token) (interface{}, error) {
+	if jwtKeys == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return jwtSecret(), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	pub, ok := jwtKeys.verify[kid]
+	if !ok {
+		return nil, jwt.ErrTokenUnverifiable
+	}
+	method, err := signingMethodFor(pub)
+	if err != nil || method.Alg() != token.Method.Alg() {
+		return nil, jwt.ErrTokenSignatureInvalid
+	}
+	return pub, nil
+}
+
+func signingMethodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch pub.(type) {
+	case *rsa.PublicKey:
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	}
+	return nil, errors.New("unsupported key type, use RSA or Ed25519")
+}
+
+// keyID is derived from the public key so every instance computes the same kid
+func keyID(pub crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(der)
+	return hex.EncodeToString(sum[:8]), nil
+}
+
+func parsePrivateKeyPEM(data []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, errors.New("unsupported private key")
+	}
+	// format lama "RSA PRIVATE KEY" dari openssl genrsa
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return nil, errors.New("unsupported private key, use PKCS#8 or PKCS#1 PEM")
+}
+
+func parsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		return cert.PublicKey, nil
+	}
+	if pub, err := x509.ParsePKIXPublicKey(block.Bytes); err == nil {
+		return pub, nil
+	}
+	if pub, err := x509.ParsePKCS1PublicKey(block.Bytes); err == nil {
+		return pub, nil
+	}
+	return nil, errors.New("unsupported public key")
+}
@@ -0,0 +1,163 @@
+package helper
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// writeKeyPair writes a PKCS#8 private key and its PKIX public key as PEM files
+func writeKeyPair(t *testing.T, name string, key crypto.Signer) (string, string) {
+	t.Helper()
+	dir := t.TempDir()
+
+	priv, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	privPath := filepath.Join(dir, name+".pem")
+	pubPath := filepath.Join(dir, name+".pub.pem")
+	if err := os.WriteFile(privPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: priv}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return privPath, pubPath
+}
+
+func resetJWTKeys(t *testing.T) {
+	t.Cleanup(func() { jwtKeys = nil })
+}
+
+func TestLoadJWTKeys_SignAndVerify(t *testing.T) {
+	resetJWTKeys(t)
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	cases := map[string]crypto.Signer{"RS256": rsaKey, "EdDSA": edKey}
+	for alg, key := range cases {
+		privPath, _ := writeKeyPair(t, alg, key)
+		if err := LoadJWTKeys(privPath, nil); err != nil {
+			t.Fatalf("%s: load failed: %v", alg, err)
+		}
+
+		tokenStr, err := GenerateJWT(7, RoleAdmin)
+		if err != nil {
+			t.Fatalf("%s: generate failed: %v", alg, err)
+		}
+
+		parsed, _, _ := jwt.NewParser().ParseUnverified(tokenStr, jwt.MapClaims{})
+		if parsed.Method.Alg() != alg || parsed.Header["kid"] != jwtKeys.kid {
+			t.Fatalf("%s: unexpected header %v", alg, parsed.Header)
+		}
+
+		claims, err := ValidateJWT(tokenStr)
+		if err != nil || claims["role"] != RoleAdmin {
+			t.Fatalf("%s: validate failed: %v", alg, err)
+		}
+	}
+}
+
+func TestLoadJWTKeys_RotationKeepsOldKey(t *testing.T) {
+	resetJWTKeys(t)
+
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	oldPriv, oldPub := writeKeyPair(t, "old", oldKey)
+	if err := LoadJWTKeys(oldPriv, nil); err != nil {
+		t.Fatal(err)
+	}
+	oldToken, _ := GenerateJWT(1, RoleCustomer)
+
+	_, newKey, _ := ed25519.GenerateKey(rand.Reader)
+	newPriv, _ := writeKeyPair(t, "new", newKey)
+
+	// tanpa public key lama, token lama ditolak
+	if err := LoadJWTKeys(newPriv, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateJWT(oldToken); err == nil {
+		t.Fatalf("expected token of unknown kid to be rejected")
+	}
+
+	if err := LoadJWTKeys(newPriv, []string{oldPub}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ValidateJWT(oldToken); err != nil {
+		t.Fatalf("expected old token to verify during rotation: %v", err)
+	}
+	if n := len(PublicJWKS().Keys); n != 2 {
+		t.Fatalf("expected 2 keys in JWKS, got %d", n)
+	}
+}
+
+func TestValidateJWT_RejectsHS256WhenKeysLoaded(t *testing.T) {
+	resetJWTKeys(t)
+	os.Setenv("JWT_SECRET", "unittestsecret")
+
+	hsToken, _ := GenerateJWT(1, RoleAdmin)
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	privPath, _ := writeKeyPair(t, "ed", edKey)
+	if err := LoadJWTKeys(privPath, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := ValidateJWT(hsToken); err == nil {
+		t.Fatalf("expected HS256 token to be rejected once asymmetric keys are used")
+	}
+}
+
+func TestPublicJWKS(t *testing.T) {
+	resetJWTKeys(t)
+
+	if n := len(PublicJWKS().Keys); n != 0 {
+		t.Fatalf("HS256 secret must not be published, got %d keys", n)
+	}
+
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	privPath, _ := writeKeyPair(t, "ed", edKey)
+	if err := LoadJWTKeys(privPath, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := PublicJWKS().Keys
+	if len(keys) != 1 || keys[0].Kty != "OKP" || keys[0].Crv != "Ed25519" || keys[0].Kid != jwtKeys.kid || keys[0].X == "" {
+		t.Fatalf("unexpected JWKS: %+v", keys)
+	}
+}
+
+func TestCheckJWTConfig(t *testing.T) {
+	resetJWTKeys(t)
+
+	t.Setenv("APP_ENV", "production")
+	t.Setenv("JWT_SECRET", "")
+	if err := CheckJWTConfig(); err == nil {
+		t.Fatalf("expected default secret to be refused outside dev mode")
+	}
+
+	t.Setenv("JWT_SECRET", "something-long-and-random")
+	if err := CheckJWTConfig(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Setenv("JWT_SECRET", "")
+	t.Setenv("APP_ENV", "development")
+	if err := CheckJWTConfig(); err != nil {
+		t.Fatalf("default secret should be allowed in dev mode: %v", err)
+	}
+}
@@ -1,11 +1,15 @@
 package helper
 
 import (
+	"sync"
+
 	"golang.org/x/crypto/bcrypt"
 )
 
+const bcryptCost = 10
+
 func HashPassword(password string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), 10)
+	bytes, err := bcrypt.GenerateFromPassword([]byte(password), bcryptCost)
 	return string(bytes), err
 }
 
@@ -13,3 +17,19 @@ func CheckPasswordHash(password, hash string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
 	return err == nil
 }
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// DummyPasswordHash returns a valid bcrypt hash (same cost as real ones) that no
+// password is known for. Comparing against it when a user does not exist makes the
+// login take as long as for an existing user.
+func DummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		h, _ := bcrypt.GenerateFromPassword([]byte("dummy-password-for-missing-users"), bcryptCost)
+		dummyHash = string(h)
+	})
+	return dummyHash
+}
@@ -17,3 +17,13 @@ func TestPasswordHashAndCheck(t *testing.T) {
 		t.Fatalf("expected mismatch for wrong password")
 	}
 }
+
+func TestDummyPasswordHash(t *testing.T) {
+	h := DummyPasswordHash()
+	if h == "" || h != DummyPasswordHash() {
+		t.Fatalf("expected a stable dummy hash")
+	}
+	if CheckPasswordHash("", h) || CheckPasswordHash("admin123", h) {
+		t.Fatalf("dummy hash must not match common passwords")
+	}
+}
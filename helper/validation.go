@@ -0,0 +1,110 @@
+package helper
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"order-service-sample/model"
+)
+
+// CheckoutLimits bounds the size of a single checkout
+type CheckoutLimits struct {
+	MaxLines         int
+	MaxQtyPerProduct int
+}
+
+// CheckoutLimitsFromEnv reads CHECKOUT_MAX_LINES and CHECKOUT_MAX_QTY_PER_PRODUCT (defaults 50 and 100)
+func CheckoutLimitsFromEnv() CheckoutLimits {
+	return CheckoutLimits{
+		MaxLines:         envInt("CHECKOUT_MAX_LINES", 50),
+		MaxQtyPerProduct: envInt("CHECKOUT_MAX_QTY_PER_PRODUCT", 100),
+	}
+}
+
+// NormalizeCheckoutItems validates checkout lines and merges duplicate product_ids
+// into a single line (keeping the position of the first occurrence).
+// Field names in the returned errors refer to indexes of the original request.
+func NormalizeCheckoutItems(items []model.CheckoutItem, limits CheckoutLimits) ([]model.CheckoutItem, []model.FieldError) {
+	if len(items) == 0 {
+		return nil, []model.FieldError{{Field: "items", Message: "items cannot be empty"}}
+	}
+
+	var errs []model.FieldError
+	merged := []model.CheckoutItem{}
+	firstIndex := map[int]int{} // product_id -> index in merged
+	origIndex := []int{}        // merged index -> index in request
+	overflow := map[int]bool{}  // merged index -> total does not fit in an int
+
+	for i, item := range items {
+		ok := true
+		if item.ProductID <= 0 {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "product_id must be > 0"})
+			ok = false
+		}
+		if item.Qty < 1 {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].qty", i), Message: "qty must be >= 1"})
+			ok = false
+		}
+		if ok && limits.MaxQtyPerProduct > 0 && item.Qty > limits.MaxQtyPerProduct {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].qty", i), Message: fmt.Sprintf("qty must be <= %d", limits.MaxQtyPerProduct)})
+			ok = false
+		}
+		if !ok {
+			continue
+		}
+
+		if idx, dup := firstIndex[item.ProductID]; dup {
+			// jangan sampai total overflow jadi negatif dan lolos validasi
+			if item.Qty > math.MaxInt-merged[idx].Qty {
+				overflow[idx] = true
+				continue
+			}
+			merged[idx].Qty += item.Qty
+			continue
+		}
+		firstIndex[item.ProductID] = len(merged)
+		origIndex = append(origIndex, i)
+		merged = append(merged, item)
+	}
+
+	for idx, item := range merged {
+		if overflow[idx] {
+			errs = append(errs, model.FieldError{
+				Field:   fmt.Sprintf("items[%d].qty", origIndex[idx]),
+				Message: fmt.Sprintf("total qty for product %d is too large", item.ProductID),
+			})
+		} else if limits.MaxQtyPerProduct > 0 && item.Qty > limits.MaxQtyPerProduct {
+			errs = append(errs, model.FieldError{
+				Field:   fmt.Sprintf("items[%d].qty", origIndex[idx]),
+				Message: fmt.Sprintf("total qty for product %d must be <= %d", item.ProductID, limits.MaxQtyPerProduct),
+			})
+		}
+	}
+
+	if limits.MaxLines > 0 && len(merged) > limits.MaxLines {
+		errs = append(errs, model.FieldError{Field: "items", Message: fmt.Sprintf("at most %d different products per checkout", limits.MaxLines)})
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return merged, nil
+}
+
+// WriteValidationErrorJSON mengirim response 400 dengan daftar error per field
+func WriteValidationErrorJSON(w http.ResponseWriter, errs []model.FieldError) {
+	WriteJSON(w, http.StatusBadRequest, map[string]any{
+		"error":  "validation failed",
+		"fields": errs,
+	})
+}
+
+func envInt(k string, def int) int {
+	v, err := strconv.Atoi(GetEnv(k, ""))
+	if err != nil {
+		return def
+	}
+	return v
+}
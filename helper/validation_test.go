@@ -0,0 +1,128 @@
+package helper
+
+import (
+	"encoding/json"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"order-service-sample/model"
+)
+
+func TestNormalizeCheckoutItems_MergesDuplicates(t *testing.T) {
+	items, errs := NormalizeCheckoutItems([]model.CheckoutItem{
+		{ProductID: 1, Qty: 2},
+		{ProductID: 2, Qty: 1},
+		{ProductID: 1, Qty: 3},
+	}, CheckoutLimits{MaxLines: 10, MaxQtyPerProduct: 10})
+
+	if len(errs) != 0 {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 merged lines, got %+v", items)
+	}
+	if items[0].ProductID != 1 || items[0].Qty != 5 {
+		t.Fatalf("expected product 1 with qty 5 first, got %+v", items[0])
+	}
+}
+
+func TestNormalizeCheckoutItems_FieldErrors(t *testing.T) {
+	_, errs := NormalizeCheckoutItems([]model.CheckoutItem{
+		{ProductID: 1, Qty: 0},
+		{ProductID: 0, Qty: 1},
+		{ProductID: 3, Qty: -2},
+	}, CheckoutLimits{})
+
+	want := []string{"items[0].qty", "items[1].product_id", "items[2].qty"}
+	if len(errs) != len(want) {
+		t.Fatalf("expected %d errors, got %+v", len(want), errs)
+	}
+	for i, f := range want {
+		if errs[i].Field != f {
+			t.Fatalf("expected field %s, got %s", f, errs[i].Field)
+		}
+	}
+}
+
+func TestNormalizeCheckoutItems_Empty(t *testing.T) {
+	_, errs := NormalizeCheckoutItems(nil, CheckoutLimits{})
+	if len(errs) != 1 || errs[0].Field != "items" {
+		t.Fatalf("expected items error, got %+v", errs)
+	}
+}
+
+func TestNormalizeCheckoutItems_MaxQtyAppliesAfterMerge(t *testing.T) {
+	_, errs := NormalizeCheckoutItems([]model.CheckoutItem{
+		{ProductID: 1, Qty: 6},
+		{ProductID: 1, Qty: 5},
+	}, CheckoutLimits{MaxQtyPerProduct: 10})
+
+	if len(errs) != 1 || errs[0].Field != "items[0].qty" {
+		t.Fatalf("expected max qty error on items[0].qty, got %+v", errs)
+	}
+}
+
+func TestNormalizeCheckoutItems_LineOverMaxIsRejectedBeforeMerge(t *testing.T) {
+	_, errs := NormalizeCheckoutItems([]model.CheckoutItem{
+		{ProductID: 1, Qty: math.MaxInt},
+		{ProductID: 1, Qty: 2},
+	}, CheckoutLimits{MaxQtyPerProduct: 100})
+
+	if len(errs) != 1 || errs[0].Field != "items[0].qty" {
+		t.Fatalf("expected max qty error on items[0].qty, got %+v", errs)
+	}
+}
+
+func TestNormalizeCheckoutItems_OverflowWithoutLimit(t *testing.T) {
+	items, errs := NormalizeCheckoutItems([]model.CheckoutItem{
+		{ProductID: 1, Qty: math.MaxInt},
+		{ProductID: 1, Qty: 2},
+	}, CheckoutLimits{})
+
+	if len(errs) != 1 || errs[0].Field != "items[0].qty" || items != nil {
+		t.Fatalf("expected overflow error on items[0].qty, got %+v %+v", items, errs)
+	}
+}
+
+func TestNormalizeCheckoutItems_MaxLines(t *testing.T) {
+	_, errs := NormalizeCheckoutItems([]model.CheckoutItem{
+		{ProductID: 1, Qty: 1},
+		{ProductID: 2, Qty: 1},
+		{ProductID: 3, Qty: 1},
+	}, CheckoutLimits{MaxLines: 2})
+
+	if len(errs) != 1 || errs[0].Field != "items" {
+		t.Fatalf("expected max lines error, got %+v", errs)
+	}
+}
+
+func TestCheckoutLimitsFromEnv(t *testing.T) {
+	t.Setenv("CHECKOUT_MAX_LINES", "3")
+	t.Setenv("CHECKOUT_MAX_QTY_PER_PRODUCT", "")
+
+	limits := CheckoutLimitsFromEnv()
+	if limits.MaxLines != 3 || limits.MaxQtyPerProduct != 100 {
+		t.Fatalf("unexpected limits: %+v", limits)
+	}
+}
+
+func TestWriteValidationErrorJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	WriteValidationErrorJSON(rec, []model.FieldError{{Field: "items[0].qty", Message: "qty must be >= 1"}})
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400, got %d", rec.Code)
+	}
+	var body struct {
+		Error  string             `json:"error"`
+		Fields []model.FieldError `json:"fields"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatalf("invalid json response: %v", err)
+	}
+	if body.Error != "validation failed" || len(body.Fields) != 1 || body.Fields[0].Field != "items[0].qty" {
+		t.Fatalf("unexpected body: %+v", body)
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"order-service-sample/helper"
+	"order-service-sample/notify"
+	"order-service-sample/repository"
+)
+
+// lowStockNotifier always logs and additionally calls LOW_STOCK_WEBHOOK_URL when set
+func lowStockNotifier() notify.Notifier {
+	sinks := notify.Multi{notify.LogNotifier{}}
+	if url := helper.GetEnv("LOW_STOCK_WEBHOOK_URL", ""); url != "" {
+		sinks = append(sinks, notify.NewWebhookNotifier(url))
+	}
+	return sinks
+}
+
+// runLowStockMonitor follows the stock ledger and re-evaluates reorder points of
+// every product whose stock changed since the last check
+func runLowStockMonitor(ctx context.Context, notifier notify.Notifier) {
+	interval, err := time.ParseDuration(helper.GetEnv("LOW_STOCK_CHECK_INTERVAL", "30s"))
+	if err != nil || interval <= 0 {
+		interval = 30 * time.Second
+	}
+	// transaksi yang commit telat tetap terbaca selama masih dalam lag ini
+	lag, err := time.ParseDuration(helper.GetEnv("LOW_STOCK_LEDGER_LAG", "5m"))
+	if err != nil || lag < interval {
+		lag = max(5*time.Minute, interval)
+	}
+
+	lastID, err := repository.LatestStockMovementID(db)
+	if err != nil {
+		log.Println("low-stock: failed to read stock ledger:", err)
+	}
+
+	// evaluasi semua row sekali saat start
+	checkLowStock(ctx, notifier, nil)
+
+	log.Printf("low-stock: monitoring stock changes every %s", interval)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			productIDs, newLastID, err := repository.GetProductsMovedSince(db, lastID, lag)
+			if err != nil {
+				log.Println("low-stock: failed to read stock ledger:", err)
+				continue
+			}
+			lastID = newLastID
+			if len(productIDs) > 0 {
+				checkLowStock(ctx, notifier, productIDs)
+			}
+		}
+	}
+}
+
+func checkLowStock(ctx context.Context, notifier notify.Notifier, productIDs []int) {
+	alerts, err := repository.MarkLowStockTransitions(db, productIDs)
+	if err != nil {
+		log.Println("low-stock: failed to evaluate reorder points:", err)
+		return
+	}
+	for _, a := range alerts {
+		if err := notifier.NotifyLowStock(ctx, a); err != nil {
+			log.Printf("low-stock: failed to notify for warehouse_id=%d product_id=%d: %v", a.WarehouseID, a.ProductID, err)
+		}
+	}
+}
@@ -12,9 +12,14 @@ import (
 	"syscall"
 	"time"
 
+	"order-service-sample/account"
+	"order-service-sample/cart"
 	"order-service-sample/helper"
 	"order-service-sample/middleware"
+	"order-service-sample/oidc"
 	"order-service-sample/repository"
+	"order-service-sample/shipping"
+	"order-service-sample/storage"
 
 	"github.com/gorilla/mux"
 	_ "github.com/lib/pq"
@@ -22,8 +27,23 @@ import (
 )
 
 var (
-	db  *sql.DB
-	rdb *redis.Client
+	db         *sql.DB
+	rdb        *redis.Client
+	blobStore  storage.BlobStore
+	cartStore  cart.Store
+	mediaStore *storage.LocalStore
+
+	codeStore       account.CodeStore
+	codeSender      account.Sender
+	tokenDenylist   *account.RedisDenylist
+	loginLimiter    account.LoginLimiter
+	loginChallenges account.ChallengeStore
+
+	oidcProvider    *oidc.Provider
+	oidcStates      oidc.StateStore
+	oidcDefaultRole string
+
+	shippingRates shipping.ShippingRateProvider
 )
 
 func main() {
@@ -35,6 +55,8 @@ func main() {
 		dsn = "postgres://admin:nimda@db:5432/ecommerce?sslmode=disable"
 	}
 	redisAddr := helper.GetEnv("REDIS_ADDR", "redis:6379")
+	mediaDir := helper.GetEnv("MEDIA_DIR", "./media")
+	mediaBaseURL := helper.GetEnv("MEDIA_BASE_URL", "/media")
 
 	// === Setup Postgres ===
 	db, err = sql.Open("postgres", dsn)
@@ -52,6 +74,46 @@ func main() {
 	})
 	log.Println("connected to Redis:", redisAddr)
 
+	// === Setup cart storage (Redis, Postgres sebagai fallback) ===
+	cartStore = cart.NewFallbackStore(
+		cart.NewRedisStore(rdb, 7*24*time.Hour),
+		cart.NewPostgresStore(db),
+	)
+
+	// === Setup verification codes & reset tokens ===
+	codeStore = account.NewRedisCodeStore(rdb)
+
+	// === Setup login lockout ===
+	loginLimiter = account.NewRedisLoginLimiter(rdb, account.DefaultAccountPolicy, account.DefaultIPPolicy)
+	loginChallenges = account.NewRedisChallengeStore(rdb)
+
+	// === Setup OIDC login (optional) ===
+	setupOIDC()
+
+	// === Setup shipping rates ===
+	rates := shipping.DefaultRates
+	if path := os.Getenv("SHIPPING_RATES_FILE"); path != "" {
+		rates, err = shipping.LoadRatesFile(path)
+		if err != nil {
+			log.Fatal("failed to load shipping rates:", err)
+		}
+	}
+	shippingRates = shipping.NewTableRateProvider(rates)
+
+	// === Setup access token denylist ===
+	tokenDenylist = account.NewRedisDenylist(rdb)
+	middleware.TokenDenylist = tokenDenylist
+	middleware.ForbiddenAuditor = dbAuditor{}
+	middleware.APIKeys = apiKeyVerifier{}
+	middleware.RequireStaffMFA = helper.GetEnv("REQUIRE_STAFF_2FA", "false") == "true"
+
+	// === Setup media storage ===
+	mediaStore, err = storage.NewLocalStore(mediaDir, mediaBaseURL)
+	if err != nil {
+		log.Fatal("failed to init media storage:", err)
+	}
+	blobStore = mediaStore
+
 	// === Determine run mode ===
 	mode := "app"
 	if len(os.Args) > 1 {
@@ -61,24 +123,69 @@ func main() {
 	switch mode {
 	case "worker":
 		log.Println("Running in WORKER ONLY mode...")
-		runWorker(context.Background())
+		ctx := context.Background()
+		go runLowStockMonitor(ctx, lowStockNotifier())
+		go runReconcileJob(ctx)
+		runWorker(ctx)
 		return
 
 	case "app":
 		log.Println("Running in HTTP SERVER mode only...")
+		setupJWTKeys()
+		setupCodeSender()
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		runHTTPServerWithShutdown(ctx, cancel)
 
 	case "all":
 		log.Println("Running in FULL mode (server + worker)...")
+		setupJWTKeys()
+		setupCodeSender()
 		ctx, cancel := context.WithCancel(context.Background())
 		defer cancel()
 		go runWorker(ctx)
+		go runLowStockMonitor(ctx, lowStockNotifier())
+		go runReconcileJob(ctx)
 		runHTTPServerWithShutdown(ctx, cancel)
 
+	case "import", "import-stock":
+		os.Exit(runImportCommand(db, mode, os.Args[2:]))
+
+	case "export":
+		os.Exit(runExportCommand(db, os.Args[2:]))
+
+	case "rebalance":
+		os.Exit(runRebalanceCommand(db, os.Args[2:]))
+
+	case "reconcile":
+		os.Exit(runReconcileCommand(db, os.Args[2:]))
+
 	default:
-		log.Fatalf("Unknown mode: %s (expected 'app', 'worker', or 'all')", mode)
+		log.Fatalf("Unknown mode: %s (expected 'app', 'worker', 'all', 'import', 'import-stock', 'export', 'rebalance' or 'reconcile')", mode)
+	}
+}
+
+// setupJWTKeys loads the access token signing keys. Only the HTTP server signs and
+// verifies tokens, so the other modes do not need them.
+func setupJWTKeys() {
+	if err := helper.LoadJWTKeysFromEnv(); err != nil {
+		log.Fatal("failed to load JWT keys:", err)
+	}
+	if err := helper.CheckJWTConfig(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// setupCodeSender picks how verification codes and reset tokens are delivered.
+// Only the HTTP server sends them, so the other modes do not need it.
+func setupCodeSender() {
+	// kode & token rahasia hanya boleh ditulis ke log di mode dev
+	if url := helper.GetEnv("CODE_SENDER_WEBHOOK_URL", ""); url != "" {
+		codeSender = account.NewWebhookSender(url)
+	} else if helper.IsDevMode() {
+		codeSender = account.LogSender{}
+	} else {
+		log.Fatal("CODE_SENDER_WEBHOOK_URL is required outside dev mode")
 	}
 }
 
@@ -129,14 +236,74 @@ func setupRouter() *mux.Router {
 	api := r.PathPrefix("/").Subrouter()
 	api.Use(middleware.AuthMiddleware)
 
-	api.HandleFunc("/products", ListProductsHandler).Methods("GET")
-	api.HandleFunc("/checkout", CheckoutHandler).Methods("POST")
-	api.HandleFunc("/pay", PayHandler).Methods("POST")
-	api.HandleFunc("/transfer-product", TransferHandler).Methods("POST")
-	api.HandleFunc("/warehouse/{id}/update-status", WarehouseUpdateStatusHandler).Methods("POST")
+	// role yang dibutuhkan dideklarasikan per route. API key (service principal) hanya
+	// boleh ke route yang juga menyebut scope-nya.
+	users := middleware.RequireRole(helper.RoleCustomer, helper.RoleWarehouseOperator, helper.RoleAdmin)
+	admin := middleware.RequireRole(helper.RoleAdmin)
+	transfersRead := middleware.RequireAccess(helper.ScopeTransfersRead, helper.RoleWarehouseOperator, helper.RoleAdmin)
+	transfersWrite := middleware.RequireAccess(helper.ScopeTransfersWrite, helper.RoleWarehouseOperator, helper.RoleAdmin)
+	warehousesRead := middleware.RequireAccess(helper.ScopeWarehousesRead, helper.RoleWarehouseOperator, helper.RoleAdmin)
+	warehousesWrite := middleware.RequireAccess(helper.ScopeWarehousesWrite, helper.RoleWarehouseOperator, helper.RoleAdmin)
+	warehousesAdmin := middleware.RequireAccess(helper.ScopeWarehousesWrite, helper.RoleAdmin)
+
+	api.Handle("/logout", users(http.HandlerFunc(LogoutHandler))).Methods("POST")
+	api.Handle("/2fa/enroll", users(http.HandlerFunc(EnrollTOTPHandler))).Methods("POST")
+	api.Handle("/2fa/confirm", users(http.HandlerFunc(ConfirmTOTPHandler))).Methods("POST")
+	api.Handle("/2fa/disable", users(http.HandlerFunc(DisableTOTPHandler))).Methods("POST")
+	api.Handle("/products", users(http.HandlerFunc(ListProductsHandler))).Methods("GET")
+	api.Handle("/products/{id}/images", admin(http.HandlerFunc(UploadProductImageHandler))).Methods("POST")
+	api.Handle("/products/{id}/stock-movements", warehousesRead(http.HandlerFunc(ProductStockMovementsHandler))).Methods("GET")
+	api.Handle("/categories", users(http.HandlerFunc(ListCategoriesHandler))).Methods("GET")
+	api.Handle("/categories/{id}/products", users(http.HandlerFunc(CategoryProductsHandler))).Methods("GET")
+	api.Handle("/addresses", users(http.HandlerFunc(ListAddressesHandler))).Methods("GET")
+	api.Handle("/addresses", users(http.HandlerFunc(CreateAddressHandler))).Methods("POST")
+	api.Handle("/addresses/{id}", users(http.HandlerFunc(GetAddressHandler))).Methods("GET")
+	api.Handle("/addresses/{id}", users(http.HandlerFunc(UpdateAddressHandler))).Methods("PATCH")
+	api.Handle("/addresses/{id}", users(http.HandlerFunc(DeleteAddressHandler))).Methods("DELETE")
+	api.Handle("/checkout", users(http.HandlerFunc(CheckoutHandler))).Methods("POST")
+	api.Handle("/cart/items", users(http.HandlerFunc(GetCartHandler))).Methods("GET")
+	api.Handle("/cart/items", users(http.HandlerFunc(AddCartItemHandler))).Methods("POST")
+	api.Handle("/cart/items", users(http.HandlerFunc(ClearCartHandler))).Methods("DELETE")
+	api.Handle("/cart/items/{product_id}", users(http.HandlerFunc(UpdateCartItemHandler))).Methods("PATCH")
+	api.Handle("/cart/items/{product_id}", users(http.HandlerFunc(DeleteCartItemHandler))).Methods("DELETE")
+	api.Handle("/cart/checkout", users(http.HandlerFunc(CartCheckoutHandler))).Methods("POST")
+	api.Handle("/pay", users(http.HandlerFunc(PayHandler))).Methods("POST")
+	api.Handle("/transfer-product", transfersWrite(http.HandlerFunc(TransferHandler))).Methods("POST")
+	api.Handle("/transfers", transfersWrite(http.HandlerFunc(TransferHandler))).Methods("POST")
+	api.Handle("/transfers/dispatch", transfersWrite(http.HandlerFunc(DispatchTransferHandler))).Methods("POST")
+	api.Handle("/transfers/{id}", transfersRead(http.HandlerFunc(GetTransferHandler))).Methods("GET")
+	api.Handle("/transfers/{id}/receive", transfersWrite(http.HandlerFunc(ReceiveTransferHandler))).Methods("POST")
+	api.Handle("/transfers/{id}/cancel", transfersWrite(http.HandlerFunc(CancelTransferHandler))).Methods("POST")
+	api.Handle("/warehouse/{id}/update-status", warehousesWrite(http.HandlerFunc(WarehouseUpdateStatusHandler))).Methods("POST")
+	api.Handle("/warehouses", warehousesRead(http.HandlerFunc(ListWarehousesHandler))).Methods("GET")
+	api.Handle("/warehouses", warehousesAdmin(http.HandlerFunc(CreateWarehouseHandler))).Methods("POST")
+	api.Handle("/warehouses/{id}", warehousesAdmin(http.HandlerFunc(UpdateWarehouseHandler))).Methods("PATCH")
+	api.Handle("/warehouses/{id}/stock", warehousesRead(http.HandlerFunc(WarehouseStockHandler))).Methods("GET")
+	api.Handle("/warehouses/{id}/receipts", warehousesWrite(http.HandlerFunc(GoodsReceiptHandler))).Methods("POST")
+	api.Handle("/warehouses/{id}/adjustments", warehousesWrite(http.HandlerFunc(StockAdjustmentHandler))).Methods("POST")
+	api.Handle("/warehouses/{id}/stock/{product_id}/reorder-point", warehousesWrite(http.HandlerFunc(SetReorderPointHandler))).Methods("PUT")
+	api.Handle("/alerts/low-stock", warehousesRead(http.HandlerFunc(LowStockAlertsHandler))).Methods("GET")
+	api.Handle("/admin/rebalance", admin(http.HandlerFunc(RebalanceHandler))).Methods("POST")
+	api.Handle("/admin/users/{id}/role", admin(http.HandlerFunc(SetUserRoleHandler))).Methods("PUT")
+	api.Handle("/admin/api-keys", admin(http.HandlerFunc(CreateAPIKeyHandler))).Methods("POST")
+	api.Handle("/admin/api-keys", admin(http.HandlerFunc(ListAPIKeysHandler))).Methods("GET")
+	api.Handle("/admin/api-keys/{id}", admin(http.HandlerFunc(RevokeAPIKeyHandler))).Methods("DELETE")
 
-	// endpoint login tetap di luar auth
+	// endpoint login & akun tetap di luar auth
 	r.HandleFunc("/login", LoginHandler).Methods("POST")
+	r.HandleFunc("/login/2fa", LoginTOTPHandler).Methods("POST")
+	r.HandleFunc("/register", RegisterHandler).Methods("POST")
+	r.HandleFunc("/verify", VerifyHandler).Methods("POST")
+	r.HandleFunc("/verify/resend", ResendVerificationHandler).Methods("POST")
+	r.HandleFunc("/password/forgot", ForgotPasswordHandler).Methods("POST")
+	r.HandleFunc("/password/reset", ResetPasswordHandler).Methods("POST")
+	r.HandleFunc("/token/refresh", RefreshTokenHandler).Methods("POST")
+	r.HandleFunc("/.well-known/jwks.json", JWKSHandler).Methods("GET")
+	r.HandleFunc("/auth/oidc/login", OIDCLoginHandler).Methods("GET")
+	r.HandleFunc("/auth/oidc/callback", OIDCCallbackHandler).Methods("GET")
+
+	// file gambar produk bisa diakses publik tanpa token
+	r.PathPrefix(mediaStore.ServePath()).Handler(mediaStore.FileServer()).Methods("GET")
 	return r
 }
 
@@ -6,10 +6,35 @@ import (
 	"strings"
 
 	"order-service-sample/helper"
+	"order-service-sample/model"
 )
 
+// Denylist tells whether an access token was revoked before it expired
+type Denylist interface {
+	IsDenied(ctx context.Context, jti string) (bool, error)
+}
+
+// TokenDenylist is checked for every request when set (see main.go)
+var TokenDenylist Denylist
+
+// APIKeyVerifier resolves an X-API-Key header to its service principal. It returns
+// an "api_key_invalid" error for unknown, revoked or expired keys.
+type APIKeyVerifier interface {
+	VerifyAPIKey(ctx context.Context, key string) (model.APIKey, error)
+}
+
+// APIKeys enables the X-API-Key header when set (see main.go)
+var APIKeys APIKeyVerifier
+
+// AuthMiddleware accepts either a Bearer JWT of a user or, for service principals,
+// an X-API-Key header
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if apiKey := r.Header.Get("X-API-Key"); apiKey != "" && APIKeys != nil {
+			serveWithAPIKey(w, r, next, apiKey)
+			return
+		}
+
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			helper.WriteErrorJSON(w, http.StatusUnauthorized, "missing Authorization header")
@@ -34,7 +59,50 @@ func AuthMiddleware(next http.Handler) http.Handler {
 			return
 		}
 
+		if TokenDenylist != nil {
+			jti, _ := claims["jti"].(string)
+			if jti == "" {
+				helper.WriteErrorJSON(w, http.StatusUnauthorized, "invalid token payload")
+				return
+			}
+			denied, err := TokenDenylist.IsDenied(r.Context(), jti)
+			if err != nil {
+				// fail closed: token yang sudah di-revoke tidak boleh lolos saat Redis bermasalah
+				helper.WriteErrorJSON(w, http.StatusServiceUnavailable, "failed to check token")
+				return
+			}
+			if denied {
+				helper.WriteErrorJSON(w, http.StatusUnauthorized, "token has been revoked")
+				return
+			}
+		}
+
 		ctx := context.WithValue(r.Context(), helper.UserIDKey, int(userID))
+		ctx = context.WithValue(ctx, helper.TokenClaimsKey, claims)
+
+		// token lama tanpa claim role diperlakukan sebagai customer
+		role, _ := claims["role"].(string)
+		if role == "" {
+			role = helper.RoleCustomer
+		}
+		ctx = context.WithValue(ctx, helper.RoleKey, role)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+func serveWithAPIKey(w http.ResponseWriter, r *http.Request, next http.Handler, apiKey string) {
+	key, err := APIKeys.VerifyAPIKey(r.Context(), apiKey)
+	if err != nil {
+		if err.Error() == "api_key_invalid" {
+			helper.WriteErrorJSON(w, http.StatusUnauthorized, "invalid or expired API key")
+			return
+		}
+		helper.WriteErrorJSON(w, http.StatusServiceUnavailable, "failed to check API key")
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), helper.RoleKey, helper.RoleService)
+	ctx = context.WithValue(ctx, helper.ServiceKey, key.Service)
+	ctx = context.WithValue(ctx, helper.ScopesKey, key.Scopes)
+	next.ServeHTTP(w, r.WithContext(ctx))
+}
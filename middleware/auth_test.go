@@ -1,11 +1,14 @@
 package middleware
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"order-service-sample/helper"
+	"order-service-sample/model"
 )
 
 func TestAuthMiddleware_MissingHeader(t *testing.T) {
@@ -57,7 +60,7 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 
 func TestAuthMiddleware_ValidToken(t *testing.T) {
 	// generate valid jwt
-	token, err := helper.GenerateJWT(99) // userID = 99
+	token, err := helper.GenerateJWT(99, helper.RoleCustomer) // userID = 99
 	if err != nil {
 		t.Fatalf("failed to generate token: %v", err)
 	}
@@ -76,6 +79,9 @@ func TestAuthMiddleware_ValidToken(t *testing.T) {
 		if uid != 99 {
 			t.Fatalf("expected user_id 99, got %d", uid)
 		}
+		if role := helper.GetRoleFromContext(r.Context()); role != helper.RoleCustomer {
+			t.Fatalf("expected role %q, got %q", helper.RoleCustomer, role)
+		}
 
 		w.WriteHeader(200)
 	}))
@@ -90,3 +96,80 @@ func TestAuthMiddleware_ValidToken(t *testing.T) {
 		t.Fatalf("next handler was NOT called on valid token")
 	}
 }
+
+type fakeDenylist map[string]bool
+
+func (f fakeDenylist) IsDenied(ctx context.Context, jti string) (bool, error) {
+	return f[jti], nil
+}
+
+func TestAuthMiddleware_DeniedToken(t *testing.T) {
+	token, err := helper.GenerateJWT(99, helper.RoleCustomer)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	claims, err := helper.ValidateJWT(token)
+	if err != nil {
+		t.Fatalf("failed to validate token: %v", err)
+	}
+
+	TokenDenylist = fakeDenylist{claims["jti"].(string): true}
+	defer func() { TokenDenylist = nil }()
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+
+	handler := AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("next handler must not be called for a revoked token")
+	}))
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for revoked token, got %d", rec.Code)
+	}
+}
+
+type fakeAPIKeys map[string]model.APIKey
+
+func (f fakeAPIKeys) VerifyAPIKey(ctx context.Context, key string) (model.APIKey, error) {
+	k, ok := f[key]
+	if !ok {
+		return k, errors.New("api_key_invalid")
+	}
+	return k, nil
+}
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	APIKeys = fakeAPIKeys{"sk_good": {ID: 1, Service: "erp", Scopes: []string{helper.ScopeTransfersRead}}}
+	defer func() { APIKeys = nil }()
+
+	var called bool
+	handler := AuthMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if role := helper.GetRoleFromContext(r.Context()); role != helper.RoleService {
+			t.Fatalf("expected role %q, got %q", helper.RoleService, role)
+		}
+		if actor := helper.ActorFromContext(r.Context()); actor != "service:erp" {
+			t.Fatalf("expected actor service:erp, got %q", actor)
+		}
+		w.WriteHeader(200)
+	}))
+
+	req := httptest.NewRequest("GET", "/transfers/1", nil)
+	req.Header.Set("X-API-Key", "sk_good")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !called {
+		t.Fatalf("expected valid key to pass, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/transfers/1", nil)
+	req.Header.Set("X-API-Key", "sk_revoked")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for unknown key, got %d", rec.Code)
+	}
+}
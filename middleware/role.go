@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"order-service-sample/helper"
+	"order-service-sample/model"
+)
+
+// Auditor persists refused requests, next to the audit line in the log
+type Auditor interface {
+	RecordForbidden(ctx context.Context, a model.ForbiddenAttempt) error
+}
+
+// ForbiddenAuditor is called for every refused request when set (see main.go)
+var ForbiddenAuditor Auditor
+
+// RequireStaffMFA makes two-factor authentication mandatory for admins and
+// warehouse operators (REQUIRE_STAFF_2FA, see main.go). Off by default, so staff
+// without 2FA keep working until they enrol.
+var RequireStaffMFA bool
+
+// RequireRole only lets requests through whose role (set by AuthMiddleware) is one
+// of roles. It is declared per route in setupRouter, behind AuthMiddleware.
+// API keys are always refused.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return RequireAccess("", roles...)
+}
+
+// RequireAccess is RequireRole for routes that service principals may call too:
+// user tokens are checked against roles, API keys must carry scope
+func RequireAccess(scope string, roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if allowed(r.Context(), scope, roles) {
+				if missingMFA(r.Context(), roles) {
+					log.Printf("audit: two-factor required %s %s user=%d role=%q",
+						r.Method, r.URL.Path, helper.GetUserIDFromContext(r.Context()), helper.GetRoleFromContext(r.Context()))
+					helper.WriteErrorJSON(w, http.StatusForbidden, "two-factor authentication required, enable it with POST /2fa/enroll")
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			attempt := model.ForbiddenAttempt{
+				Actor:         helper.ActorFromContext(r.Context()),
+				UserID:        helper.GetUserIDFromContext(r.Context()),
+				Role:          helper.GetRoleFromContext(r.Context()),
+				RequiredRoles: roles,
+				RequiredScope: scope,
+				Method:        r.Method,
+				Path:          r.URL.Path,
+				RemoteAddr:    r.RemoteAddr,
+				CreatedAt:     time.Now(),
+			}
+			log.Printf("audit: forbidden %s %s actor=%s role=%q required=%s scope=%q remote=%s",
+				attempt.Method, attempt.Path, attempt.Actor, attempt.Role,
+				strings.Join(roles, ","), scope, attempt.RemoteAddr)
+			if ForbiddenAuditor != nil {
+				if err := ForbiddenAuditor.RecordForbidden(r.Context(), attempt); err != nil {
+					log.Println("audit: failed to record forbidden attempt:", err)
+				}
+			}
+
+			helper.WriteErrorJSON(w, http.StatusForbidden, "forbidden")
+		})
+	}
+}
+
+func allowed(ctx context.Context, scope string, roles []string) bool {
+	role := helper.GetRoleFromContext(ctx)
+	if role == helper.RoleService {
+		if scope == "" {
+			return false
+		}
+		for _, s := range helper.GetScopesFromContext(ctx) {
+			if s == scope {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, r := range roles {
+		if role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// missingMFA refuses admins and warehouse operators on routes customers cannot use
+// until their token was issued with two-factor authentication, when RequireStaffMFA
+// is set. Routes open to customers, like /2fa/enroll, stay reachable so they can enrol.
+func missingMFA(ctx context.Context, roles []string) bool {
+	if !RequireStaffMFA {
+		return false
+	}
+	role := helper.GetRoleFromContext(ctx)
+	if !helper.RequiresMFA(role) || helper.HasMFAFromContext(ctx) {
+		return false
+	}
+	for _, r := range roles {
+		if r == helper.RoleCustomer {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"order-service-sample/helper"
+	"order-service-sample/model"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type recordingAuditor struct {
+	attempts []model.ForbiddenAttempt
+}
+
+func (a *recordingAuditor) RecordForbidden(ctx context.Context, attempt model.ForbiddenAttempt) error {
+	a.attempts = append(a.attempts, attempt)
+	return nil
+}
+
+func requestWithRole(userID int, role string) *http.Request {
+	return requestWithClaims(userID, role, jwt.MapClaims{"mfa": true})
+}
+
+func requestWithClaims(userID int, role string, claims jwt.MapClaims) *http.Request {
+	req := httptest.NewRequest("POST", "/transfers", nil)
+	ctx := context.WithValue(req.Context(), helper.UserIDKey, userID)
+	ctx = context.WithValue(ctx, helper.RoleKey, role)
+	ctx = context.WithValue(ctx, helper.TokenClaimsKey, claims)
+	return req.WithContext(ctx)
+}
+
+func TestRequireRole_Allowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	var called bool
+
+	handler := RequireRole(helper.RoleWarehouseOperator, helper.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(200)
+	}))
+	handler.ServeHTTP(rec, requestWithRole(7, helper.RoleWarehouseOperator))
+
+	if rec.Code != http.StatusOK || !called {
+		t.Fatalf("expected operator to pass, got %d", rec.Code)
+	}
+}
+
+func TestRequireRole_ForbiddenIsAudited(t *testing.T) {
+	auditor := &recordingAuditor{}
+	ForbiddenAuditor = auditor
+	defer func() { ForbiddenAuditor = nil }()
+
+	rec := httptest.NewRecorder()
+	handler := RequireRole(helper.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("next handler must not be called for a customer")
+	}))
+	handler.ServeHTTP(rec, requestWithRole(9, helper.RoleCustomer))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403, got %d", rec.Code)
+	}
+	if len(auditor.attempts) != 1 {
+		t.Fatalf("expected 1 audit record, got %d", len(auditor.attempts))
+	}
+	a := auditor.attempts[0]
+	if a.UserID != 9 || a.Role != helper.RoleCustomer || a.Path != "/transfers" || a.Method != "POST" {
+		t.Fatalf("unexpected audit record: %+v", a)
+	}
+}
+
+func TestRequireRole_PrivilegedRoleNeedsMFA(t *testing.T) {
+	RequireStaffMFA = true
+	defer func() { RequireStaffMFA = false }()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(200) })
+
+	cases := []struct {
+		name    string
+		handler http.Handler
+		role    string
+		want    int
+	}{
+		{"admin route", RequireRole(helper.RoleAdmin)(ok), helper.RoleAdmin, http.StatusForbidden},
+		{"operator route", RequireRole(helper.RoleWarehouseOperator, helper.RoleAdmin)(ok), helper.RoleWarehouseOperator, http.StatusForbidden},
+		{"customer route stays open for enrolment", RequireRole(helper.RoleCustomer, helper.RoleWarehouseOperator, helper.RoleAdmin)(ok), helper.RoleAdmin, http.StatusOK},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		c.handler.ServeHTTP(rec, requestWithClaims(1, c.role, jwt.MapClaims{"mfa": false}))
+		if rec.Code != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, rec.Code)
+		}
+	}
+}
+
+func TestRequireRole_StaffMFAOffByDefault(t *testing.T) {
+	rec := httptest.NewRecorder()
+	handler := RequireRole(helper.RoleAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(200) }))
+	handler.ServeHTTP(rec, requestWithClaims(1, helper.RoleAdmin, jwt.MapClaims{"mfa": false}))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected admin without 2FA to pass while RequireStaffMFA is off, got %d", rec.Code)
+	}
+}
+
+func requestWithScopes(scopes ...string) *http.Request {
+	req := httptest.NewRequest("POST", "/transfers", nil)
+	ctx := context.WithValue(req.Context(), helper.RoleKey, helper.RoleService)
+	ctx = context.WithValue(ctx, helper.ServiceKey, "erp")
+	ctx = context.WithValue(ctx, helper.ScopesKey, scopes)
+	return req.WithContext(ctx)
+}
+
+func TestRequireAccess_ServiceScopes(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(200) })
+
+	cases := []struct {
+		name    string
+		handler http.Handler
+		scopes  []string
+		want    int
+	}{
+		{"scope granted", RequireAccess(helper.ScopeTransfersWrite, helper.RoleAdmin)(ok), []string{helper.ScopeTransfersWrite}, http.StatusOK},
+		{"scope missing", RequireAccess(helper.ScopeTransfersWrite, helper.RoleAdmin)(ok), []string{helper.ScopeTransfersRead}, http.StatusForbidden},
+		{"role-only route", RequireRole(helper.RoleAdmin)(ok), []string{helper.ScopeTransfersWrite}, http.StatusForbidden},
+	}
+	for _, c := range cases {
+		rec := httptest.NewRecorder()
+		c.handler.ServeHTTP(rec, requestWithScopes(c.scopes...))
+		if rec.Code != c.want {
+			t.Errorf("%s: expected %d, got %d", c.name, c.want, rec.Code)
+		}
+	}
+}
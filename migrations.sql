@@ -112,6 +112,331 @@ CREATE TABLE IF NOT EXISTS reservations (
 
 CREATE INDEX IF NOT EXISTS idx_reservations_expires_at ON reservations (expires_at);
 
+-- PRODUCT SKU (natural key used by the CSV import)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS sku VARCHAR(64) UNIQUE;
+
+-- CATEGORIES (self-referencing tree)
+CREATE TABLE IF NOT EXISTS categories (
+    id SERIAL PRIMARY KEY,
+    parent_id INT REFERENCES categories(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
+
+-- PRODUCT <-> CATEGORY (many-to-many)
+CREATE TABLE IF NOT EXISTS product_categories (
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    category_id INT NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (product_id, category_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_categories_category_id ON product_categories (category_id);
+
+-- Seed categories
+INSERT INTO categories (id, parent_id, name)
+VALUES
+  (1, NULL, 'Computer Accessories'),
+  (2, 1, 'Input Devices'),
+  (3, 1, 'Connectivity'),
+  (4, NULL, 'Audio & Video')
+ON CONFLICT DO NOTHING;
+
+SELECT setval('categories_id_seq', (SELECT MAX(id) FROM categories));
+
+-- Seed product categories
+INSERT INTO product_categories (product_id, category_id)
+VALUES
+  (1, 2),
+  (2, 2),
+  (3, 3),
+  (4, 4),
+  (5, 4)
+ON CONFLICT DO NOTHING;
+
+-- PRODUCT IMAGES (files live in the BlobStore, this table keeps metadata + order)
+CREATE TABLE IF NOT EXISTS product_images (
+    id SERIAL PRIMARY KEY,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    url VARCHAR(500) NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images (product_id, position);
+
+-- CART ITEMS (durable copy of the Redis cart)
+CREATE TABLE IF NOT EXISTS cart_items (
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+    qty INT NOT NULL CHECK (qty > 0),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, product_id)
+);
+
+-- WAREHOUSE DETAILS
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS address TEXT;
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS region VARCHAR(50);
+ALTER TABLE warehouses ADD COLUMN IF NOT EXISTS capacity INT DEFAULT 0 CHECK (capacity >= 0);
+
+UPDATE warehouses SET region = 'central' WHERE id = 1 AND region IS NULL;
+UPDATE warehouses SET region = 'jakarta' WHERE id = 2 AND region IS NULL;
+UPDATE warehouses SET region = 'surabaya' WHERE id = 3 AND region IS NULL;
+
+-- STOCK MOVEMENTS (append-only ledger of every warehouse_stock change)
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id BIGSERIAL PRIMARY KEY,
+    warehouse_id INT NOT NULL REFERENCES warehouses(id),
+    product_id INT NOT NULL REFERENCES products(id),
+    movement_type VARCHAR(20) NOT NULL CHECK (movement_type IN
+        ('reserve', 'release', 'sale', 'transfer_out', 'transfer_in', 'adjustment', 'return')),
+    quantity_delta INT NOT NULL DEFAULT 0,
+    reserved_delta INT NOT NULL DEFAULT 0,
+    order_id INT,
+    transfer_id INT,
+    actor VARCHAR(100) NOT NULL,
+    note TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product ON stock_movements (product_id, warehouse_id, created_at);
+
+-- ledger is append-only
+CREATE OR REPLACE FUNCTION stock_movements_append_only() RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'stock_movements is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trg_stock_movements_append_only ON stock_movements;
+CREATE TRIGGER trg_stock_movements_append_only
+    BEFORE UPDATE OR DELETE ON stock_movements
+    FOR EACH ROW EXECUTE FUNCTION stock_movements_append_only();
+
+-- GOODS RECEIPTS (inbound stock)
+-- constraint diganti, bukan CREATE TABLE diubah, supaya database lama ikut ter-update
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_movement_type_check;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_movement_type_check CHECK (movement_type IN
+    ('reserve', 'release', 'sale', 'transfer_out', 'transfer_in', 'adjustment', 'return', 'receipt'));
+
+CREATE TABLE IF NOT EXISTS goods_receipts (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INT NOT NULL REFERENCES warehouses(id),
+    reference VARCHAR(100),
+    note TEXT,
+    actor VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS goods_receipt_items (
+    id SERIAL PRIMARY KEY,
+    receipt_id INT NOT NULL REFERENCES goods_receipts(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id),
+    quantity INT NOT NULL CHECK (quantity > 0)
+);
+
+-- STOCK ADJUSTMENTS (cycle counts and other manual corrections)
+CREATE TABLE IF NOT EXISTS stock_adjustments (
+    id SERIAL PRIMARY KEY,
+    warehouse_id INT NOT NULL REFERENCES warehouses(id),
+    product_id INT NOT NULL REFERENCES products(id),
+    quantity_before INT NOT NULL,
+    quantity_after INT NOT NULL,
+    quantity_delta INT NOT NULL,
+    reason_code VARCHAR(30) NOT NULL,
+    note TEXT,
+    actor VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- TRANSFERS (transfer documents with one or more product lines)
+CREATE TABLE IF NOT EXISTS transfers (
+    id SERIAL PRIMARY KEY,
+    from_warehouse_id INT NOT NULL REFERENCES warehouses(id),
+    to_warehouse_id INT NOT NULL REFERENCES warehouses(id),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('completed')),
+    note TEXT,
+    actor VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (from_warehouse_id <> to_warehouse_id)
+);
+
+CREATE TABLE IF NOT EXISTS transfer_items (
+    id SERIAL PRIMARY KEY,
+    transfer_id INT NOT NULL REFERENCES transfers(id) ON DELETE CASCADE,
+    product_id INT NOT NULL REFERENCES products(id),
+    quantity INT NOT NULL CHECK (quantity > 0),
+    UNIQUE (transfer_id, product_id)
+);
+
+-- TWO-PHASE TRANSFERS (dispatch, then receive or cancel)
+ALTER TABLE transfers DROP CONSTRAINT IF EXISTS transfers_status_check;
+ALTER TABLE transfers ADD CONSTRAINT transfers_status_check CHECK (status IN ('completed', 'dispatched', 'received', 'cancelled'));
+
+-- filled in when a dispatched transfer is received; the rest is short
+ALTER TABLE transfer_items ADD COLUMN IF NOT EXISTS received_quantity INT CHECK (received_quantity >= 0);
+ALTER TABLE transfer_items ADD COLUMN IF NOT EXISTS damaged_quantity INT CHECK (damaged_quantity >= 0);
+
+-- stock dispatched to a warehouse but not received yet, kept on the destination row
+ALTER TABLE warehouse_stock ADD COLUMN IF NOT EXISTS in_transit INT NOT NULL DEFAULT 0 CHECK (in_transit >= 0);
+
+ALTER TABLE stock_movements DROP CONSTRAINT IF EXISTS stock_movements_movement_type_check;
+ALTER TABLE stock_movements ADD CONSTRAINT stock_movements_movement_type_check CHECK (movement_type IN
+    ('reserve', 'release', 'sale', 'transfer_out', 'transfer_in', 'transfer_cancel', 'adjustment', 'return', 'receipt'));
+
+-- isi in_transit dari transfer yang sudah terlanjur dispatched
+INSERT INTO warehouse_stock (warehouse_id, product_id, quantity, reserved)
+SELECT DISTINCT t.to_warehouse_id, ti.product_id, 0, 0
+FROM transfers t
+JOIN transfer_items ti ON ti.transfer_id = t.id
+WHERE t.status = 'dispatched'
+ON CONFLICT (warehouse_id, product_id) DO NOTHING;
+
+UPDATE warehouse_stock ws
+SET in_transit = COALESCE((
+    SELECT SUM(ti.quantity)
+    FROM transfers t
+    JOIN transfer_items ti ON ti.transfer_id = t.id
+    WHERE t.status = 'dispatched' AND t.to_warehouse_id = ws.warehouse_id AND ti.product_id = ws.product_id
+), 0);
+
+-- LOW STOCK THRESHOLDS (NULL = no reorder point)
+ALTER TABLE warehouse_stock ADD COLUMN IF NOT EXISTS reorder_point INT CHECK (reorder_point >= 0);
+ALTER TABLE warehouse_stock ADD COLUMN IF NOT EXISTS low_stock_alerted BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- ACCOUNT VERIFICATION
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_verified_at TIMESTAMP;
+UPDATE users SET email_verified_at = created_at, phone_verified_at = created_at WHERE email = 'admin@example.com';
+
+-- REFRESH TOKENS (only the SHA-256 of the token is stored)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) NOT NULL UNIQUE,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    replaced_by INT REFERENCES refresh_tokens(id),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+-- USER ROLES
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'customer'
+    CHECK (role IN ('customer', 'warehouse_operator', 'admin'));
+UPDATE users SET role = 'admin' WHERE email = 'admin@example.com';
+
+-- AUDIT LOG (refused requests and other security events)
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INT,
+    role VARCHAR(20),
+    action VARCHAR(50) NOT NULL,
+    method VARCHAR(10),
+    path TEXT,
+    remote_addr VARCHAR(100),
+    detail TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log (created_at);
+
+-- API KEYS (service principals such as the ERP; only the SHA-256 of the key is stored)
+CREATE TABLE IF NOT EXISTS api_keys (
+    id SERIAL PRIMARY KEY,
+    service VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL,
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    expires_at TIMESTAMP,
+    created_by VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+-- OIDC IDENTITIES (corporate IdP accounts linked to users)
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    issuer VARCHAR(255) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (issuer, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+-- TWO-FACTOR AUTHENTICATION (TOTP)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT;
+
+-- recovery codes are single use, only their SHA-256 is stored
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
+
+-- CUSTOMER ADDRESS BOOK
+CREATE TABLE IF NOT EXISTS user_addresses (
+    id SERIAL PRIMARY KEY,
+    user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    label VARCHAR(50) NOT NULL DEFAULT '',
+    recipient_name VARCHAR(100) NOT NULL,
+    phone VARCHAR(20) NOT NULL,
+    street VARCHAR(255) NOT NULL,
+    city VARCHAR(100) NOT NULL,
+    region VARCHAR(50) NOT NULL,
+    postal_code VARCHAR(10) NOT NULL,
+    is_default BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_addresses_user_id ON user_addresses (user_id);
+
+-- paling banyak satu alamat default per user
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_addresses_default ON user_addresses (user_id) WHERE is_default;
+
+-- shipping address snapshot, copied at checkout so later edits don't change the order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_address_id INT REFERENCES user_addresses(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_recipient_name VARCHAR(100);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_phone VARCHAR(20);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_street VARCHAR(255);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_city VARCHAR(100);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_region VARCHAR(50);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_postal_code VARCHAR(10);
+
+-- PRODUCT WEIGHT & DIMENSIONS (used for shipping cost)
+ALTER TABLE products ADD COLUMN IF NOT EXISTS weight_grams INT NOT NULL DEFAULT 0 CHECK (weight_grams >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS length_mm INT NOT NULL DEFAULT 0 CHECK (length_mm >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS width_mm INT NOT NULL DEFAULT 0 CHECK (width_mm >= 0);
+ALTER TABLE products ADD COLUMN IF NOT EXISTS height_mm INT NOT NULL DEFAULT 0 CHECK (height_mm >= 0);
+
+UPDATE products SET weight_grams = 150, length_mm = 120, width_mm = 70, height_mm = 40 WHERE name = 'Wireless Mouse' AND weight_grams = 0;
+UPDATE products SET weight_grams = 1100, length_mm = 450, width_mm = 150, height_mm = 45 WHERE name = 'Mechanical Keyboard' AND weight_grams = 0;
+UPDATE products SET weight_grams = 120, length_mm = 120, width_mm = 50, height_mm = 20 WHERE name = 'USB-C Hub' AND weight_grams = 0;
+UPDATE products SET weight_grams = 350, length_mm = 220, width_mm = 200, height_mm = 100 WHERE name = 'Gaming Headset' AND weight_grams = 0;
+UPDATE products SET weight_grams = 160, length_mm = 100, width_mm = 80, height_mm = 60 WHERE name = 'Webcam HD' AND weight_grams = 0;
+
+-- chosen shipping method and cost; total_amount includes the shipping cost
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method VARCHAR(20);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_cost NUMERIC(12,2) NOT NULL DEFAULT 0;
+
+-- LOW STOCK MONITOR (re-reads recent ledger entries by created_at)
+CREATE INDEX IF NOT EXISTS idx_stock_movements_created_at ON stock_movements (created_at);
+
 -- =====================================================
 -- END OF MIGRATION
 -- =====================================================
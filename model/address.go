@@ -0,0 +1,48 @@
+package model
+
+import "time"
+
+// ShippingAddress is the part of an address that is copied onto an order at checkout,
+// so editing or deleting the address later does not change the order
+type ShippingAddress struct {
+	RecipientName string `json:"recipient_name"`
+	Phone         string `json:"phone"`
+	Street        string `json:"street"`
+	City          string `json:"city"`
+	Region        string `json:"region"`
+	PostalCode    string `json:"postal_code"`
+}
+
+// Address is an entry of a customer's address book. Region uses the same values as
+// warehouses.region (e.g. "jakarta").
+type Address struct {
+	ID    int    `json:"id"`
+	Label string `json:"label"`
+	ShippingAddress
+	IsDefault bool      `json:"is_default"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type CreateAddressReq struct {
+	Label         string `json:"label"`
+	RecipientName string `json:"recipient_name"`
+	Phone         string `json:"phone"`
+	Street        string `json:"street"`
+	City          string `json:"city"`
+	Region        string `json:"region"`
+	PostalCode    string `json:"postal_code"`
+	IsDefault     bool   `json:"is_default"`
+}
+
+// UpdateAddressReq is a partial update, nil fields are left unchanged
+type UpdateAddressReq struct {
+	Label         *string `json:"label"`
+	RecipientName *string `json:"recipient_name"`
+	Phone         *string `json:"phone"`
+	Street        *string `json:"street"`
+	City          *string `json:"city"`
+	Region        *string `json:"region"`
+	PostalCode    *string `json:"postal_code"`
+	IsDefault     *bool   `json:"is_default"`
+}
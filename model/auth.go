@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+// ForbiddenAttempt is an audit record of an authenticated request that was
+// refused because the caller's role does not allow the route
+type ForbiddenAttempt struct {
+	Actor         string    `json:"actor"`
+	UserID        int       `json:"user_id"`
+	Role          string    `json:"role"`
+	RequiredRoles []string  `json:"required_roles"`
+	RequiredScope string    `json:"required_scope,omitempty"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	RemoteAddr    string    `json:"remote_addr"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type SetUserRoleReq struct {
+	Role string `json:"role"`
+}
+
+// APIKey is a key of a service principal (e.g. the ERP). Only the SHA-256 of the key
+// is stored; Prefix is kept to tell keys apart in listings.
+type APIKey struct {
+	ID         int        `json:"id"`
+	Service    string     `json:"service"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedBy  string     `json:"created_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+}
+
+// CreateAPIKeyReq creates a key that never expires when ExpiresAt is null
+type CreateAPIKeyReq struct {
+	Service   string     `json:"service"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateAPIKeyResp is the only response that contains the key itself
+type CreateAPIKeyResp struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+type TOTPCodeReq struct {
+	Code string `json:"code"`
+}
+
+// TOTPEnrollResp is shown once; ProvisioningURI is rendered as QR code by the client
+type TOTPEnrollResp struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type RecoveryCodesResp struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorChallengeResp is returned by /login instead of tokens when 2FA is enabled
+type TwoFactorChallengeResp struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int    `json:"expires_in"`
+}
+
+// LoginTOTPReq completes a login; Code is a TOTP code or a recovery code
+type LoginTOTPReq struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+}
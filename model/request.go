@@ -1,16 +1,74 @@
 package model
 
+import "time"
+
 type LoginReq struct {
 	EmailOrPhone string `json:"email_or_phone"`
 	Password     string `json:"password"`
 }
 
+// TokenResp is returned by login and refresh; Token is the short-lived access token
+type TokenResp struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+type RefreshTokenReq struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+type RegisterReq struct {
+	Email    string `json:"email"`
+	Phone    string `json:"phone"`
+	Password string `json:"password"`
+}
+
+type UserResp struct {
+	ID            int    `json:"id"`
+	Email         string `json:"email"`
+	Phone         string `json:"phone"`
+	EmailVerified bool   `json:"email_verified"`
+	PhoneVerified bool   `json:"phone_verified"`
+	Role          string `json:"role"`
+}
+
+// VerifyReq confirms the email or phone the code was sent to
+type VerifyReq struct {
+	EmailOrPhone string `json:"email_or_phone"`
+	Code         string `json:"code"`
+}
+
+type ForgotPasswordReq struct {
+	EmailOrPhone string `json:"email_or_phone"`
+}
+
+type ResetPasswordReq struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
 type ProductResp struct {
+	ID          int            `json:"id"`
+	Price       string         `json:"price"`
+	Name        string         `json:"name"`
+	Stock       int            `json:"stock"`
+	Description string         `json:"description"`
+	WeightGrams int            `json:"weight_grams"`
+	LengthMM    int            `json:"length_mm"`
+	WidthMM     int            `json:"width_mm"`
+	HeightMM    int            `json:"height_mm"`
+	Images      []ProductImage `json:"images"`
+}
+
+type ProductImage struct {
 	ID          int    `json:"id"`
-	Price       string `json:"price"`
-	Name        string `json:"name"`
-	Stock       int    `json:"stock"`
-	Description string `json:"description"`
+	ProductID   int    `json:"-"`
+	StorageKey  string `json:"-"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	SizeBytes   int64  `json:"size_bytes"`
+	Position    int    `json:"position"`
 }
 
 type CheckoutItem struct {
@@ -18,20 +76,40 @@ type CheckoutItem struct {
 	Qty       int `json:"qty"`
 }
 
+// CheckoutRequest ships to ShippingAddressID, or to the default address of the
+// user when it is omitted
 type CheckoutRequest struct {
-	Items  []CheckoutItem `json:"items"`
-	UserID string         `json:"-"`
+	Items             []CheckoutItem `json:"items"`
+	ShippingAddressID *int           `json:"shipping_address_id"`
+	ShippingMethod    string         `json:"shipping_method"`
+	UserID            string         `json:"-"`
+}
+
+// CartCheckoutReq is the optional body of /cart/checkout
+type CartCheckoutReq struct {
+	ShippingAddressID *int   `json:"shipping_address_id"`
+	ShippingMethod    string `json:"shipping_method"`
 }
 
+// TransferReq carries either Items or the single-line ProductID/Quantity pair
 type TransferReq struct {
-	ProductID     int `json:"product_id"`
-	FromWarehouse int `json:"from_warehouse_id"`
-	ToWarehouse   int `json:"to_warehouse_id"`
-	Quantity      int `json:"quantity"`
+	ProductID     int            `json:"product_id"`
+	FromWarehouse int            `json:"from_warehouse_id"`
+	ToWarehouse   int            `json:"to_warehouse_id"`
+	Quantity      int            `json:"quantity"`
+	Note          string         `json:"note"`
+	Items         []TransferLine `json:"items"`
 }
 
+// CheckoutResponse lists every shipping option of the order; TotalAmount includes
+// the cost of the chosen ShippingMethod
 type CheckoutResponse struct {
-	OrderID int `json:"order_id"`
+	OrderID         int              `json:"order_id"`
+	TotalAmount     int64            `json:"total_amount"`
+	ShippingAddress *ShippingAddress `json:"shipping_address,omitempty"`
+	ShippingMethod  string           `json:"shipping_method,omitempty"`
+	ShippingCost    int64            `json:"shipping_cost"`
+	ShippingOptions []ShippingOption `json:"shipping_options,omitempty"`
 }
 
 type PayRequest struct {
@@ -43,6 +121,85 @@ type PayResponse struct {
 	Status  string `json:"status"`
 }
 
+type Warehouse struct {
+	ID        int       `json:"id"`
+	Name      string    `json:"name"`
+	Address   string    `json:"address"`
+	Region    string    `json:"region"`
+	Capacity  int       `json:"capacity"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type WarehouseSummary struct {
+	Warehouse
+	ProductCount   int `json:"product_count"`
+	TotalQuantity  int `json:"total_quantity"`
+	TotalReserved  int `json:"total_reserved"`
+	TotalAvailable int `json:"total_available"`
+	TotalInTransit int `json:"total_in_transit"`
+}
+
+type CreateWarehouseReq struct {
+	Name     string `json:"name"`
+	Address  string `json:"address"`
+	Region   string `json:"region"`
+	Capacity int    `json:"capacity"`
+}
+
+// UpdateWarehouseReq is a partial update, nil fields are left unchanged
+type UpdateWarehouseReq struct {
+	Name     *string `json:"name"`
+	Address  *string `json:"address"`
+	Region   *string `json:"region"`
+	Capacity *int    `json:"capacity"`
+}
+
 type UpdateWarehouseStatusReq struct {
 	Status string `json:"status"` // "active" | "deactive"
+	Policy string `json:"policy"` // "block" (default) | "complete" | "reassign", only used when deactivating
+}
+
+// AffectedReservation is a reservation held in a warehouse that is being deactivated
+type AffectedReservation struct {
+	OrderID         int    `json:"order_id"`
+	ProductID       int    `json:"product_id"`
+	Quantity        int    `json:"quantity"`
+	FromWarehouseID int    `json:"from_warehouse_id"`
+	ToWarehouseID   int    `json:"to_warehouse_id,omitempty"`
+	Action          string `json:"action"` // "blocked" | "kept" | "moved"
+}
+
+type Category struct {
+	ID       int        `json:"id"`
+	ParentID *int       `json:"parent_id"`
+	Name     string     `json:"name"`
+	Children []Category `json:"children,omitempty"`
+}
+
+type CartItemReq struct {
+	ProductID int `json:"product_id"`
+	Qty       int `json:"qty"`
+}
+
+type UpdateCartItemReq struct {
+	Qty int `json:"qty"`
+}
+
+type CartLine struct {
+	ProductID int   `json:"product_id"`
+	Qty       int   `json:"qty"`
+	UnitPrice int64 `json:"unit_price"`
+	Subtotal  int64 `json:"subtotal"`
+}
+
+type CartResp struct {
+	Items       []CartLine `json:"items"`
+	TotalAmount int64      `json:"total_amount"`
+}
+
+// FieldError points a validation failure at a single request field, e.g. "items[1].qty"
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
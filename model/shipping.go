@@ -0,0 +1,19 @@
+package model
+
+// ShippingOption is the cost of delivering a whole order with one shipping method
+type ShippingOption struct {
+	Method        string `json:"method"`
+	Cost          int64  `json:"cost"`
+	EstimatedDays int    `json:"estimated_days"`
+}
+
+// ShipmentLine is a reserved order line with the region of the warehouse it ships from
+type ShipmentLine struct {
+	OriginRegion string
+	ProductID    int
+	Qty          int
+	WeightGrams  int
+	LengthMM     int
+	WidthMM      int
+	HeightMM     int
+}
@@ -0,0 +1,205 @@
+package model
+
+import "time"
+
+// ProductImportRow is one validated row of a product CSV import. Nil weight and
+// dimensions keep the stored values.
+type ProductImportRow struct {
+	SKU         string
+	Name        string
+	Description string
+	Price       string
+	WeightGrams *int
+	LengthMM    *int
+	WidthMM     *int
+	HeightMM    *int
+}
+
+// StockImportRow is one validated row of a stock CSV import
+type StockImportRow struct {
+	WarehouseID int
+	ProductID   int
+	Quantity    int
+}
+
+type WarehouseStockRow struct {
+	WarehouseID   int    `json:"warehouse_id"`
+	WarehouseName string `json:"warehouse_name"`
+	ProductID     int    `json:"product_id"`
+	ProductName   string `json:"product_name"`
+	Quantity      int    `json:"quantity"`
+	Reserved      int    `json:"reserved"`
+	Available     int    `json:"available"`
+	InTransit     int    `json:"in_transit"` // dispatched to this warehouse, not received yet
+}
+
+// StockMovement is one append-only ledger entry for a warehouse_stock change
+type StockMovement struct {
+	ID            int       `json:"id"`
+	WarehouseID   int       `json:"warehouse_id"`
+	ProductID     int       `json:"product_id"`
+	Type          string    `json:"type"`
+	QuantityDelta int       `json:"quantity_delta"`
+	ReservedDelta int       `json:"reserved_delta"`
+	OrderID       int       `json:"order_id,omitempty"`
+	TransferID    int       `json:"transfer_id,omitempty"`
+	Actor         string    `json:"actor"`
+	Note          string    `json:"note,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type GoodsReceiptItem struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+}
+
+type GoodsReceiptReq struct {
+	Reference string             `json:"reference"` // e.g. supplier delivery note / PO number
+	Note      string             `json:"note"`
+	Items     []GoodsReceiptItem `json:"items"`
+}
+
+type GoodsReceipt struct {
+	ID          int                `json:"id"`
+	WarehouseID int                `json:"warehouse_id"`
+	Reference   string             `json:"reference"`
+	Note        string             `json:"note"`
+	Actor       string             `json:"actor"`
+	Items       []GoodsReceiptItem `json:"items"`
+	CreatedAt   time.Time          `json:"created_at"`
+}
+
+// StockAdjustmentReq sets either CountedQuantity (absolute, e.g. after a cycle count)
+// or QuantityDelta (relative), not both
+type StockAdjustmentReq struct {
+	ProductID       int    `json:"product_id"`
+	CountedQuantity *int   `json:"counted_quantity"`
+	QuantityDelta   int    `json:"quantity_delta"`
+	ReasonCode      string `json:"reason_code"`
+	Note            string `json:"note"`
+}
+
+type StockAdjustment struct {
+	ID             int       `json:"id"`
+	WarehouseID    int       `json:"warehouse_id"`
+	ProductID      int       `json:"product_id"`
+	QuantityBefore int       `json:"quantity_before"`
+	QuantityAfter  int       `json:"quantity_after"`
+	QuantityDelta  int       `json:"quantity_delta"`
+	ReasonCode     string    `json:"reason_code"`
+	Note           string    `json:"note"`
+	Actor          string    `json:"actor"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// TransferLine is one product of a transfer. Received, Damaged and Short are only
+// filled in once a dispatched transfer has been received.
+type TransferLine struct {
+	ProductID int `json:"product_id"`
+	Quantity  int `json:"quantity"`
+	Received  int `json:"received_quantity,omitempty"`
+	Damaged   int `json:"damaged_quantity,omitempty"`
+	Short     int `json:"short_quantity,omitempty"`
+}
+
+type ReceiveTransferLine struct {
+	ProductID        int `json:"product_id"`
+	ReceivedQuantity int `json:"received_quantity"`
+	DamagedQuantity  int `json:"damaged_quantity"`
+}
+
+// ReceiveTransferReq lists only the lines that did not arrive in full
+type ReceiveTransferReq struct {
+	Items []ReceiveTransferLine `json:"items"`
+}
+
+// Transfer is a transfer document moving one or more product lines between two warehouses
+type Transfer struct {
+	ID            int            `json:"id"`
+	FromWarehouse int            `json:"from_warehouse_id"`
+	ToWarehouse   int            `json:"to_warehouse_id"`
+	Status        string         `json:"status"`
+	Note          string         `json:"note,omitempty"`
+	Actor         string         `json:"actor"`
+	Items         []TransferLine `json:"items"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// LowStockAlert reports a warehouse_stock row whose available quantity is at or
+// below its reorder point
+type LowStockAlert struct {
+	WarehouseID   int    `json:"warehouse_id"`
+	WarehouseName string `json:"warehouse_name"`
+	ProductID     int    `json:"product_id"`
+	ProductName   string `json:"product_name"`
+	Quantity      int    `json:"quantity"`
+	Reserved      int    `json:"reserved"`
+	Available     int    `json:"available"`
+	ReorderPoint  int    `json:"reorder_point"`
+}
+
+// SetReorderPointReq clears the reorder point when ReorderPoint is null
+type SetReorderPointReq struct {
+	ReorderPoint *int `json:"reorder_point"`
+}
+
+// RebalancePosition is the available stock of a product in one warehouse together
+// with the product's total sales over the lookback window
+type RebalancePosition struct {
+	WarehouseID int  `json:"warehouse_id"`
+	Active      bool `json:"active"`
+	ProductID   int  `json:"product_id"`
+	Available   int  `json:"available"`
+	ProductSold int  `json:"product_sold"`
+}
+
+// RebalanceCover shows how many days a warehouse can serve a product before and
+// after the planned moves
+type RebalanceCover struct {
+	WarehouseID int     `json:"warehouse_id"`
+	ProductID   int     `json:"product_id"`
+	Available   int     `json:"available"`
+	DailyDemand float64 `json:"daily_demand"`
+	Target      int     `json:"target"`
+	DaysBefore  float64 `json:"days_of_cover_before"`
+	DaysAfter   float64 `json:"days_of_cover_after"`
+}
+
+type RebalanceMove struct {
+	ProductID     int `json:"product_id"`
+	FromWarehouse int `json:"from_warehouse_id"`
+	ToWarehouse   int `json:"to_warehouse_id"`
+	Quantity      int `json:"quantity"`
+}
+
+type RebalancePlan struct {
+	TargetDays   int              `json:"target_days"`
+	LookbackDays int              `json:"lookback_days"`
+	Moves        []RebalanceMove  `json:"moves"`
+	Cover        []RebalanceCover `json:"cover"`
+	Executed     bool             `json:"executed"`
+}
+
+type RebalanceReq struct {
+	TargetDays   int  `json:"target_days"`
+	LookbackDays int  `json:"lookback_days"`
+	MinQuantity  int  `json:"min_quantity"`
+	Execute      bool `json:"execute"`
+}
+
+// StockDrift is one mismatch found by the reconciliation job
+type StockDrift struct {
+	Kind        string `json:"kind"`
+	WarehouseID int    `json:"warehouse_id,omitempty"`
+	ProductID   int    `json:"product_id"`
+	Actual      int    `json:"actual"`
+	Expected    int    `json:"expected"`
+	Repaired    bool   `json:"repaired"`
+}
+
+type ReconcileReport struct {
+	CheckedAt time.Time    `json:"checked_at"`
+	Repair    bool         `json:"repair"`
+	Drifts    []StockDrift `json:"drifts"`
+}
@@ -0,0 +1,37 @@
+// Package notify delivers operational alerts (low stock) to log output or external systems.
+package notify
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	"order-service-sample/model"
+)
+
+// Notifier delivers a low-stock alert
+type Notifier interface {
+	NotifyLowStock(ctx context.Context, alert model.LowStockAlert) error
+}
+
+// LogNotifier writes alerts to the standard logger
+type LogNotifier struct{}
+
+func (LogNotifier) NotifyLowStock(ctx context.Context, a model.LowStockAlert) error {
+	log.Printf("[low-stock] warehouse_id=%d (%s) product_id=%d (%s) available=%d reorder_point=%d",
+		a.WarehouseID, a.WarehouseName, a.ProductID, a.ProductName, a.Available, a.ReorderPoint)
+	return nil
+}
+
+// Multi sends every alert to all notifiers, even when one of them fails
+type Multi []Notifier
+
+func (m Multi) NotifyLowStock(ctx context.Context, a model.LowStockAlert) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.NotifyLowStock(ctx, a); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,68 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"order-service-sample/model"
+)
+
+func TestWebhookNotifier_PostsAlert(t *testing.T) {
+	var got webhookPayload
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("unexpected request %s %s", r.Method, r.Header.Get("Content-Type"))
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("decode body: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	alert := model.LowStockAlert{WarehouseID: 1, ProductID: 2, Available: 3, ReorderPoint: 5}
+	if err := NewWebhookNotifier(srv.URL).NotifyLowStock(context.Background(), alert); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Event != "low_stock" || got.Alert != alert {
+		t.Fatalf("unexpected payload: %+v", got)
+	}
+}
+
+func TestWebhookNotifier_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+
+	if err := NewWebhookNotifier(srv.URL).NotifyLowStock(context.Background(), model.LowStockAlert{}); err == nil {
+		t.Fatalf("expected error on 502")
+	}
+}
+
+type countingNotifier struct {
+	calls int
+	err   error
+}
+
+func (c *countingNotifier) NotifyLowStock(ctx context.Context, a model.LowStockAlert) error {
+	c.calls++
+	return c.err
+}
+
+func TestMulti_DeliversToAllSinks(t *testing.T) {
+	failing := &countingNotifier{err: errors.New("down")}
+	ok := &countingNotifier{}
+
+	err := Multi{failing, ok}.NotifyLowStock(context.Background(), model.LowStockAlert{})
+	if err == nil {
+		t.Fatalf("expected error from failing sink")
+	}
+	if failing.calls != 1 || ok.calls != 1 {
+		t.Fatalf("expected both sinks called, got %d and %d", failing.calls, ok.calls)
+	}
+}
@@ -0,0 +1,225 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// stubIdP is a minimal OpenID provider: discovery, JWKS and a token endpoint that
+// answers every code with the ID token prepared by the test
+type stubIdP struct {
+	srv      *httptest.Server
+	key      *rsa.PrivateKey
+	kid      string
+	idToken  string
+	lastForm url.Values
+	jwksHits int
+}
+
+func newStubIdP(t *testing.T) *stubIdP {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	idp := &stubIdP{key: key, kid: "stub-1"}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 idp.srv.URL,
+			"authorization_endpoint": idp.srv.URL + "/authorize",
+			"token_endpoint":         idp.srv.URL + "/token",
+			"jwks_uri":               idp.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		idp.jwksHits++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": idp.kid,
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(idp.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(idp.key.E)).Bytes()),
+		}}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		idp.lastForm = r.PostForm
+		if user, pass, ok := r.BasicAuth(); !ok || user != "order-service" || pass != "s3cret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "x", "id_token": idp.idToken})
+	})
+	idp.srv = httptest.NewServer(mux)
+	t.Cleanup(idp.srv.Close)
+	return idp
+}
+
+func (idp *stubIdP) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	tok.Header["kid"] = idp.kid
+	s, err := tok.SignedString(idp.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (idp *stubIdP) claims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            idp.srv.URL,
+		"aud":            "order-service",
+		"sub":            "staff-42",
+		"email":          "sari@corp.example",
+		"email_verified": true,
+		"nonce":          nonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+	}
+}
+
+func newTestProvider(idp *stubIdP) *Provider {
+	return NewProvider(Config{
+		Issuer:       idp.srv.URL,
+		ClientID:     "order-service",
+		ClientSecret: "s3cret",
+		RedirectURL:  "http://localhost:8085/auth/oidc/callback",
+		Scopes:       []string{"email"},
+	}, idp.srv.Client())
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestProvider(idp)
+
+	raw, err := p.AuthCodeURL(context.Background(), "st", "no", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, _ := url.Parse(raw)
+	q := u.Query()
+	if u.Path != "/authorize" || q.Get("state") != "st" || q.Get("nonce") != "no" ||
+		q.Get("scope") != "openid email" || q.Get("code_challenge") != codeChallenge("verifier") ||
+		q.Get("code_challenge_method") != "S256" {
+		t.Fatalf("unexpected auth url %s", raw)
+	}
+}
+
+func TestExchange_Success(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestProvider(idp)
+	idp.idToken = idp.sign(t, idp.claims("n-1"))
+
+	c, err := p.Exchange(context.Background(), "code-1", "verifier-1", "n-1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if c.Subject != "staff-42" || c.Email != "sari@corp.example" || !c.EmailVerified || c.Issuer != idp.srv.URL {
+		t.Fatalf("unexpected claims %+v", c)
+	}
+	if idp.lastForm.Get("code") != "code-1" || idp.lastForm.Get("code_verifier") != "verifier-1" {
+		t.Fatalf("unexpected token request %v", idp.lastForm)
+	}
+}
+
+func TestVerifyIDToken_Rejects(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestProvider(idp)
+
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	cases := map[string]func() string{
+		"wrong nonce": func() string { return idp.sign(t, idp.claims("other")) },
+		"wrong audience": func() string {
+			c := idp.claims("n")
+			c["aud"] = "someone-else"
+			return idp.sign(t, c)
+		},
+		"wrong issuer": func() string {
+			c := idp.claims("n")
+			c["iss"] = "https://evil.example"
+			return idp.sign(t, c)
+		},
+		"expired": func() string {
+			c := idp.claims("n")
+			c["exp"] = time.Now().Add(-time.Minute).Unix()
+			return idp.sign(t, c)
+		},
+		"foreign key": func() string {
+			tok := jwt.NewWithClaims(jwt.SigningMethodRS256, idp.claims("n"))
+			tok.Header["kid"] = idp.kid
+			s, _ := tok.SignedString(otherKey)
+			return s
+		},
+		"unsigned": func() string {
+			s, _ := jwt.NewWithClaims(jwt.SigningMethodNone, idp.claims("n")).SignedString(jwt.UnsafeAllowNoneSignatureType)
+			return s
+		},
+	}
+	for name, token := range cases {
+		if _, err := p.VerifyIDToken(context.Background(), token(), "n"); err == nil {
+			t.Errorf("%s: expected id token to be rejected", name)
+		}
+	}
+}
+
+func TestVerifyIDToken_RefetchesKeysOnRotation(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestProvider(idp)
+
+	if _, err := p.VerifyIDToken(context.Background(), idp.sign(t, idp.claims("n")), "n"); err != nil {
+		t.Fatal(err)
+	}
+
+	// IdP rotates its key, the cached JWKS no longer knows the kid
+	idp.key, _ = rsa.GenerateKey(rand.Reader, 2048)
+	idp.kid = "stub-2"
+	p.keysFetchedAt = time.Now().Add(-MinKeyRefreshInterval)
+
+	if _, err := p.VerifyIDToken(context.Background(), idp.sign(t, idp.claims("n")), "n"); err != nil {
+		t.Fatalf("expected rotated key to be picked up: %v", err)
+	}
+}
+
+func TestVerifyIDToken_UnknownKidDoesNotRefetchWithinInterval(t *testing.T) {
+	idp := newStubIdP(t)
+	p := newTestProvider(idp)
+
+	if _, err := p.VerifyIDToken(context.Background(), idp.sign(t, idp.claims("n")), "n"); err != nil {
+		t.Fatal(err)
+	}
+
+	idp.kid = "random-kid"
+	for i := 0; i < 5; i++ {
+		if _, err := p.VerifyIDToken(context.Background(), idp.sign(t, idp.claims("n")), "n"); err == nil {
+			t.Fatalf("expected unknown kid to be rejected")
+		}
+	}
+	if idp.jwksHits != 1 {
+		t.Fatalf("expected a single JWKS fetch, got %d", idp.jwksHits)
+	}
+}
+
+func TestDiscovery_IssuerMismatch(t *testing.T) {
+	idp := newStubIdP(t)
+	p := NewProvider(Config{Issuer: strings.Replace(idp.srv.URL, "127.0.0.1", "localhost", 1), ClientID: "order-service"}, idp.srv.Client())
+
+	if _, err := p.AuthCodeURL(context.Background(), "s", "n", "v"); err == nil {
+		t.Fatalf("expected discovery to reject a different issuer")
+	}
+}
@@ -0,0 +1,53 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrStateNotFound = errors.New("oidc_state_not_found")
+
+// LoginState is kept between /auth/oidc/login and the callback
+type LoginState struct {
+	Nonce    string `json:"nonce"`
+	Verifier string `json:"verifier"`
+}
+
+type StateStore interface {
+	Save(ctx context.Context, state string, s LoginState, ttl time.Duration) error
+	// Take returns the state and deletes it, so a callback can only be used once
+	Take(ctx context.Context, state string) (LoginState, error)
+}
+
+type RedisStateStore struct {
+	rdb *redis.Client
+}
+
+func NewRedisStateStore(rdb *redis.Client) *RedisStateStore {
+	return &RedisStateStore{rdb: rdb}
+}
+
+func (s *RedisStateStore) Save(ctx context.Context, state string, ls LoginState, ttl time.Duration) error {
+	b, err := json.Marshal(ls)
+	if err != nil {
+		return err
+	}
+	return s.rdb.Set(ctx, "oidc-state:"+state, b, ttl).Err()
+}
+
+func (s *RedisStateStore) Take(ctx context.Context, state string) (LoginState, error) {
+	var ls LoginState
+	b, err := s.rdb.GetDel(ctx, "oidc-state:"+state).Bytes()
+	if err == redis.Nil {
+		return ls, ErrStateNotFound
+	}
+	if err != nil {
+		return ls, err
+	}
+	err = json.Unmarshal(b, &ls)
+	return ls, err
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"order-service-sample/account"
+	"order-service-sample/helper"
+	"order-service-sample/oidc"
+	"order-service-sample/repository"
+)
+
+const oidcStateTTL = 10 * time.Minute
+
+// setupOIDC enables /auth/oidc/* when OIDC_ISSUER is set
+func setupOIDC() {
+	issuer := helper.GetEnv("OIDC_ISSUER", "")
+	if issuer == "" {
+		return
+	}
+
+	oidcDefaultRole = helper.GetEnv("OIDC_DEFAULT_ROLE", helper.RoleCustomer)
+	if !helper.IsValidRole(oidcDefaultRole) {
+		log.Fatalf("invalid OIDC_DEFAULT_ROLE %q", oidcDefaultRole)
+	}
+
+	oidcProvider = oidc.NewProvider(oidc.Config{
+		Issuer:       issuer,
+		ClientID:     helper.GetEnv("OIDC_CLIENT_ID", ""),
+		ClientSecret: helper.GetEnv("OIDC_CLIENT_SECRET", ""),
+		RedirectURL:  helper.GetEnv("OIDC_REDIRECT_URL", "http://localhost:8085/auth/oidc/callback"),
+		Scopes:       []string{"email", "profile"},
+	}, nil)
+	oidcStates = oidc.NewRedisStateStore(rdb)
+}
+
+// OIDCLoginHandler redirects the browser to the IdP
+func OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	if oidcProvider == nil {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "oidc login is not configured")
+		return
+	}
+
+	var ls oidc.LoginState
+	state, err := oidc.NewRandom()
+	if err == nil {
+		ls.Nonce, err = oidc.NewRandom()
+	}
+	if err == nil {
+		ls.Verifier, err = oidc.NewRandom()
+	}
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to start login")
+		return
+	}
+
+	if err := oidcStates.Save(r.Context(), state, ls, oidcStateTTL); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to start login")
+		return
+	}
+
+	target, err := oidcProvider.AuthCodeURL(r.Context(), state, ls.Nonce, ls.Verifier)
+	if err != nil {
+		log.Println("oidc:", err)
+		helper.WriteErrorJSON(w, http.StatusBadGateway, "identity provider is unavailable")
+		return
+	}
+	http.Redirect(w, r, target, http.StatusFound)
+}
+
+// OIDCCallbackHandler validates the IdP response, links or provisions the user and
+// answers with our own tokens, like /login
+func OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	if oidcProvider == nil {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "oidc login is not configured")
+		return
+	}
+
+	q := r.URL.Query()
+	if e := q.Get("error"); e != "" {
+		helper.WriteErrorJSON(w, http.StatusUnauthorized, "identity provider refused login: "+e)
+		return
+	}
+
+	ls, err := oidcStates.Take(ctx, q.Get("state"))
+	if errors.Is(err, oidc.ErrStateNotFound) || q.Get("code") == "" {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid or expired login state")
+		return
+	}
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to check login state")
+		return
+	}
+
+	claims, err := oidcProvider.Exchange(ctx, q.Get("code"), ls.Verifier, ls.Nonce)
+	if err != nil {
+		log.Println("oidc: login failed:", err)
+		helper.WriteErrorJSON(w, http.StatusUnauthorized, "oidc login failed")
+		return
+	}
+
+	// password acak yang tidak pernah diberikan ke siapa pun, user OIDC login lewat IdP
+	secret, err := account.NewToken()
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to provision user")
+		return
+	}
+	hash, err := helper.HashPassword(secret[:32])
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to provision user")
+		return
+	}
+
+	user, err := repository.LinkOrProvisionOIDCUser(ctx, db, repository.OIDCIdentity{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+	}, hash, oidcDefaultRole)
+	if err != nil {
+		switch err.Error() {
+		case "oidc_email_missing":
+			helper.WriteErrorJSON(w, http.StatusUnauthorized, "identity provider did not share an email address")
+		case "oidc_email_unverified":
+			helper.WriteErrorJSON(w, http.StatusConflict, "an account with this email exists, but the identity provider has not verified the email")
+		default:
+			helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to link user")
+		}
+		return
+	}
+
+	// IdP tidak menggantikan 2FA akun ini
+	totp, err := repository.GetTOTPState(db, user.ID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	if totp.Enabled {
+		writeTwoFactorChallenge(ctx, w, user.ID)
+		return
+	}
+
+	tokens, err := issueTokens(user.ID, user.Role, false)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	helper.WriteJSON(w, http.StatusOK, tokens)
+}
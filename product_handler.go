@@ -0,0 +1,201 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// allowedImageTypes maps the sniffed content type to the file extension used for the blob key
+var allowedImageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/webp": ".webp",
+	"image/gif":  ".gif",
+}
+
+func UploadProductImageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	productID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || productID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid product id")
+		return
+	}
+
+	// 1. Batasi ukuran body sebelum parsing multipart
+	maxBytes := maxImageUploadBytes()
+	r.Body = http.MaxBytesReader(w, r.Body, maxBytes+1<<20) // +1MB untuk overhead multipart
+	if err := r.ParseMultipartForm(maxBytes); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			helper.WriteErrorJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("image must be at most %d bytes", maxBytes))
+			return
+		}
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid multipart form")
+		return
+	}
+
+	file, header, err := r.FormFile("image")
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "image file is required")
+		return
+	}
+	defer file.Close()
+
+	if header.Size > maxBytes {
+		helper.WriteErrorJSON(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("image must be at most %d bytes", maxBytes))
+		return
+	}
+
+	// 2. Validasi content type dari isi file, bukan dari header client
+	sniff := make([]byte, 512)
+	n, _ := io.ReadFull(file, sniff)
+	contentType := http.DetectContentType(sniff[:n])
+	ext, ok := allowedImageTypes[contentType]
+	if !ok {
+		helper.WriteErrorJSON(w, http.StatusUnsupportedMediaType, "unsupported image type "+contentType)
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to read image")
+		return
+	}
+
+	var position *int
+	if v := r.FormValue("position"); v != "" {
+		p, err := strconv.Atoi(v)
+		if err != nil || p < 0 {
+			helper.WriteErrorJSON(w, http.StatusBadRequest, "position must be an integer >= 0")
+			return
+		}
+		position = &p
+	}
+
+	// 3. Validasi product exists
+	exists, err := repository.ProductExists(db, productID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !exists {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "product not found")
+		return
+	}
+
+	// 4. Simpan file ke blob store
+	key := fmt.Sprintf("products/%d/%s%s", productID, randomHex(16), ext)
+	if err := blobStore.Put(ctx, key, file); err != nil {
+		log.Println("failed to store image:", err)
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to store image")
+		return
+	}
+
+	// 5. Simpan metadata
+	img, err := repository.InsertProductImage(db, model.ProductImage{
+		ProductID:   productID,
+		StorageKey:  key,
+		URL:         blobStore.URL(key),
+		ContentType: contentType,
+		SizeBytes:   header.Size,
+	}, position)
+	if err != nil {
+		blobStore.Delete(ctx, key)
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to save image")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusCreated, img)
+}
+
+// attachProductImages fills ProductResp.Images for every product in the slice
+func attachProductImages(products []model.ProductResp) error {
+	ids := make([]int, 0, len(products))
+	for _, p := range products {
+		ids = append(ids, p.ID)
+	}
+
+	images, err := repository.GetProductImages(db, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range products {
+		products[i].Images = images[products[i].ID]
+		if products[i].Images == nil {
+			products[i].Images = []model.ProductImage{}
+		}
+	}
+	return nil
+}
+
+func maxImageUploadBytes() int64 {
+	v, err := strconv.ParseInt(helper.GetEnv("MAX_IMAGE_UPLOAD_BYTES", ""), 10, 64)
+	if err != nil || v <= 0 {
+		return 5 << 20 // 5MB
+	}
+	return v
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func ProductStockMovementsHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || productID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid product id")
+		return
+	}
+
+	q := r.URL.Query()
+
+	warehouseID := 0
+	if v := q.Get("warehouse_id"); v != "" {
+		warehouseID, err = strconv.Atoi(v)
+		if err != nil || warehouseID <= 0 {
+			helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid warehouse_id")
+			return
+		}
+	}
+
+	limit := 100
+	if v := q.Get("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit <= 0 || limit > 1000 {
+			helper.WriteErrorJSON(w, http.StatusBadRequest, "limit must be between 1 and 1000")
+			return
+		}
+	}
+
+	exists, err := repository.ProductExists(db, productID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !exists {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "product not found")
+		return
+	}
+
+	movements, err := repository.GetStockMovements(db, productID, warehouseID, limit)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load stock movements")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, movements)
+}
@@ -0,0 +1,176 @@
+// Package rebalance proposes stock transfers between warehouses so that every active
+// warehouse holds enough stock for a target number of days of sales.
+//
+// Reservations always pick the active warehouse with the lowest id, so sales per
+// warehouse reflect allocation rather than demand. The planner therefore spreads a
+// product's recent sales evenly over the active warehouses. Inactive warehouses have
+// a target of zero so their stock is drained to active ones.
+package rebalance
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"sort"
+
+	"order-service-sample/model"
+	"order-service-sample/repository"
+)
+
+const (
+	DefaultTargetDays   = 14
+	DefaultLookbackDays = 30
+)
+
+type Options struct {
+	TargetDays   int
+	LookbackDays int
+	// MinQuantity skips moves smaller than this, to avoid shipping single units around
+	MinQuantity int
+}
+
+func (o Options) withDefaults() Options {
+	if o.TargetDays <= 0 {
+		o.TargetDays = DefaultTargetDays
+	}
+	if o.LookbackDays <= 0 {
+		o.LookbackDays = DefaultLookbackDays
+	}
+	if o.MinQuantity <= 0 {
+		o.MinQuantity = 1
+	}
+	return o
+}
+
+type balance struct {
+	warehouseID int
+	qty         int
+}
+
+// BuildPlan computes the moves for the given positions. Positions must be grouped by product.
+func BuildPlan(positions []model.RebalancePosition, opts Options) model.RebalancePlan {
+	opts = opts.withDefaults()
+	plan := model.RebalancePlan{
+		TargetDays:   opts.TargetDays,
+		LookbackDays: opts.LookbackDays,
+		Moves:        []model.RebalanceMove{},
+		Cover:        []model.RebalanceCover{},
+	}
+
+	for start := 0; start < len(positions); {
+		end := start
+		for end < len(positions) && positions[end].ProductID == positions[start].ProductID {
+			end++
+		}
+		planProduct(&plan, positions[start:end], opts)
+		start = end
+	}
+	return plan
+}
+
+func planProduct(plan *model.RebalancePlan, positions []model.RebalancePosition, opts Options) {
+	active := 0
+	for _, p := range positions {
+		if p.Active {
+			active++
+		}
+	}
+	if active == 0 || positions[0].ProductSold <= 0 {
+		return
+	}
+
+	dailyDemand := float64(positions[0].ProductSold) / float64(opts.LookbackDays) / float64(active)
+	target := int(math.Ceil(dailyDemand * float64(opts.TargetDays)))
+
+	var surplus, deficit []balance
+	covers := make([]model.RebalanceCover, 0, len(positions))
+	for _, p := range positions {
+		c := model.RebalanceCover{WarehouseID: p.WarehouseID, ProductID: p.ProductID, Available: p.Available}
+		if p.Active {
+			c.DailyDemand = dailyDemand
+			c.Target = target
+		}
+		covers = append(covers, c)
+
+		switch diff := p.Available - c.Target; {
+		case diff > 0:
+			surplus = append(surplus, balance{p.WarehouseID, diff})
+		case diff < 0:
+			deficit = append(deficit, balance{p.WarehouseID, -diff})
+		}
+	}
+
+	// biggest gaps first, warehouse id as tie breaker so plans are deterministic
+	byQty := func(b []balance) func(i, j int) bool {
+		return func(i, j int) bool {
+			if b[i].qty != b[j].qty {
+				return b[i].qty > b[j].qty
+			}
+			return b[i].warehouseID < b[j].warehouseID
+		}
+	}
+	sort.Slice(surplus, byQty(surplus))
+	sort.Slice(deficit, byQty(deficit))
+
+	moved := map[int]int{}
+	for _, d := range deficit {
+		need := d.qty
+		for i := range surplus {
+			if need == 0 {
+				break
+			}
+			qty := min(need, surplus[i].qty)
+			if qty < opts.MinQuantity {
+				continue
+			}
+			plan.Moves = append(plan.Moves, model.RebalanceMove{
+				ProductID:     positions[0].ProductID,
+				FromWarehouse: surplus[i].warehouseID,
+				ToWarehouse:   d.warehouseID,
+				Quantity:      qty,
+			})
+			surplus[i].qty -= qty
+			need -= qty
+			moved[surplus[i].warehouseID] -= qty
+			moved[d.warehouseID] += qty
+		}
+	}
+
+	for _, c := range covers {
+		if c.DailyDemand > 0 {
+			c.DaysBefore = round1(float64(c.Available) / c.DailyDemand)
+			c.DaysAfter = round1(float64(c.Available+moved[c.WarehouseID]) / c.DailyDemand)
+		}
+		plan.Cover = append(plan.Cover, c)
+	}
+}
+
+func round1(f float64) float64 {
+	return math.Round(f*10) / 10
+}
+
+// Plan loads the current positions and builds a plan
+func Plan(db *sql.DB, opts Options) (model.RebalancePlan, error) {
+	opts = opts.withDefaults()
+	positions, err := repository.GetRebalancePositions(db, opts.LookbackDays)
+	if err != nil {
+		return model.RebalancePlan{}, err
+	}
+	return BuildPlan(positions, opts), nil
+}
+
+// Execute applies all moves with TransferStock in one transaction: either every move
+// is applied or none
+func Execute(ctx context.Context, db *sql.DB, moves []model.RebalanceMove, actor string) error {
+	if len(moves) == 0 {
+		return nil
+	}
+	return repository.RunInTx(ctx, db, func(tx *sql.Tx) error {
+		for _, m := range moves {
+			if err := repository.TransferStock(tx, m.FromWarehouse, m.ToWarehouse, m.ProductID, m.Quantity, actor); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
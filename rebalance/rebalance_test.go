@@ -0,0 +1,60 @@
+package rebalance
+
+import (
+	"reflect"
+	"testing"
+
+	"order-service-sample/model"
+)
+
+func TestBuildPlan_MovesSurplusToShortWarehouses(t *testing.T) {
+	// 300 sold in 30 days over 3 active warehouses → 10/day/warehouse after
+	// spreading by 3, target 14 days → 47 per warehouse (ceil of 46.7)
+	positions := []model.RebalancePosition{
+		{WarehouseID: 1, Active: true, ProductID: 7, Available: 200, ProductSold: 300},
+		{WarehouseID: 2, Active: true, ProductID: 7, Available: 10, ProductSold: 300},
+		{WarehouseID: 3, Active: true, ProductID: 7, Available: 0, ProductSold: 300},
+	}
+
+	plan := BuildPlan(positions, Options{})
+
+	want := []model.RebalanceMove{
+		{ProductID: 7, FromWarehouse: 1, ToWarehouse: 3, Quantity: 47},
+		{ProductID: 7, FromWarehouse: 1, ToWarehouse: 2, Quantity: 37},
+	}
+	if !reflect.DeepEqual(plan.Moves, want) {
+		t.Fatalf("unexpected moves: %+v", plan.Moves)
+	}
+	if len(plan.Cover) != 3 || plan.Cover[2].DaysBefore != 0 || plan.Cover[2].DaysAfter < 14 {
+		t.Fatalf("unexpected cover: %+v", plan.Cover)
+	}
+}
+
+func TestBuildPlan_DrainsInactiveWarehouse(t *testing.T) {
+	positions := []model.RebalancePosition{
+		{WarehouseID: 1, Active: true, ProductID: 3, Available: 0, ProductSold: 30},
+		{WarehouseID: 2, Active: false, ProductID: 3, Available: 40, ProductSold: 30},
+	}
+
+	plan := BuildPlan(positions, Options{TargetDays: 10, LookbackDays: 30})
+
+	want := []model.RebalanceMove{{ProductID: 3, FromWarehouse: 2, ToWarehouse: 1, Quantity: 10}}
+	if !reflect.DeepEqual(plan.Moves, want) {
+		t.Fatalf("unexpected moves: %+v", plan.Moves)
+	}
+}
+
+func TestBuildPlan_SkipsSmallMovesAndUnsoldProducts(t *testing.T) {
+	positions := []model.RebalancePosition{
+		{WarehouseID: 1, Active: true, ProductID: 1, Available: 50, ProductSold: 0},
+		{WarehouseID: 2, Active: true, ProductID: 1, Available: 0, ProductSold: 0},
+		{WarehouseID: 1, Active: true, ProductID: 2, Available: 16, ProductSold: 60},
+		{WarehouseID: 2, Active: true, ProductID: 2, Available: 12, ProductSold: 60},
+	}
+
+	plan := BuildPlan(positions, Options{TargetDays: 14, LookbackDays: 30, MinQuantity: 5})
+
+	if len(plan.Moves) != 0 {
+		t.Fatalf("expected no moves, got %+v", plan.Moves)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"order-service-sample/rebalance"
+)
+
+// runRebalanceCommand handles the "rebalance" run mode. It prints the plan as JSON
+// to stdout and applies it when --execute is given.
+//
+//	order-service-sample rebalance [--target-days=14] [--lookback-days=30] [--min-qty=1] [--execute]
+func runRebalanceCommand(db *sql.DB, args []string) int {
+	fs := flag.NewFlagSet("rebalance", flag.ContinueOnError)
+	targetDays := fs.Int("target-days", rebalance.DefaultTargetDays, "days of sales every active warehouse should cover")
+	lookbackDays := fs.Int("lookback-days", rebalance.DefaultLookbackDays, "days of sales used to compute velocity")
+	minQty := fs.Int("min-qty", 1, "skip moves smaller than this")
+	execute := fs.Bool("execute", false, "apply the proposed transfers in one transaction")
+
+	if extra, err := parseArgs(fs, args); err != nil || len(extra) > 0 {
+		fmt.Fprintln(os.Stderr, "usage: order-service-sample rebalance [--target-days=N] [--lookback-days=N] [--min-qty=N] [--execute]")
+		return 2
+	}
+
+	plan, err := rebalance.Plan(db, rebalance.Options{
+		TargetDays:   *targetDays,
+		LookbackDays: *lookbackDays,
+		MinQuantity:  *minQty,
+	})
+	if err != nil {
+		log.Println("rebalance: failed to build plan:", err)
+		return 1
+	}
+
+	if *execute {
+		if err := rebalance.Execute(context.Background(), db, plan.Moves, "system:rebalance"); err != nil {
+			log.Println("rebalance: failed to execute plan:", err)
+			return 1
+		}
+		plan.Executed = true
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(plan); err != nil {
+		log.Println("rebalance: failed to write plan:", err)
+		return 1
+	}
+
+	log.Printf("rebalance: %d moves proposed, executed=%v", len(plan.Moves), plan.Executed)
+	return 0
+}
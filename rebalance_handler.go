@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/rebalance"
+)
+
+// RebalanceHandler returns a rebalancing plan and applies it when execute is true
+func RebalanceHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.RebalanceReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+
+	var errs []model.FieldError
+	if req.TargetDays < 0 || req.TargetDays > 365 {
+		errs = append(errs, model.FieldError{Field: "target_days", Message: "target_days must be between 1 and 365 (0 or omitted uses 14)"})
+	}
+	if req.LookbackDays < 0 || req.LookbackDays > 365 {
+		errs = append(errs, model.FieldError{Field: "lookback_days", Message: "lookback_days must be between 1 and 365 (0 or omitted uses 30)"})
+	}
+	if req.MinQuantity < 0 {
+		errs = append(errs, model.FieldError{Field: "min_quantity", Message: "min_quantity must be >= 1 (0 or omitted uses 1)"})
+	}
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	plan, err := rebalance.Plan(db, rebalance.Options{
+		TargetDays:   req.TargetDays,
+		LookbackDays: req.LookbackDays,
+		MinQuantity:  req.MinQuantity,
+	})
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to build rebalance plan")
+		return
+	}
+
+	if req.Execute {
+		if err := rebalance.Execute(ctx, db, plan.Moves, helper.ActorFromContext(ctx)); err != nil {
+			// stok bisa berubah antara plan dan eksekusi
+			helper.WriteErrorJSON(w, http.StatusConflict, "failed to execute rebalance plan: "+err.Error())
+			return
+		}
+		plan.Executed = true
+	}
+
+	helper.WriteJSON(w, http.StatusOK, plan)
+}
@@ -0,0 +1,136 @@
+// Package reconcile detects (and optionally repairs) drift between the stock
+// counters and the rows they are derived from:
+//
+//   - warehouse_stock.reserved must equal the sum of the live reservations
+//   - reserved must never exceed quantity
+//   - products.stock must equal the available stock over all warehouses
+//   - warehouse_stock.in_transit must equal the lines of the transfers still dispatched
+package reconcile
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"time"
+
+	"order-service-sample/model"
+	"order-service-sample/repository"
+)
+
+// Run checks every stock row. With repair, reserved and products.stock are
+// recomputed in one transaction; reserved > quantity is only reported because
+// it needs a human decision.
+func Run(ctx context.Context, db *sql.DB, repair bool, actor string) (model.ReconcileReport, error) {
+	report := model.ReconcileReport{CheckedAt: time.Now(), Repair: repair, Drifts: []model.StockDrift{}}
+
+	reserved, err := repository.GetReservedDrift(db)
+	if err != nil {
+		return report, err
+	}
+
+	if repair && len(reserved) > 0 {
+		err := repository.RunInTx(ctx, db, func(tx *sql.Tx) error {
+			for i, d := range reserved {
+				if _, _, err := repository.RepairReserved(tx, d.WarehouseID, d.ProductID, actor); err != nil {
+					return err
+				}
+				if err := repository.SyncProductStock(tx, d.ProductID); err != nil {
+					return err
+				}
+				reserved[i].Repaired = true
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	report.Drifts = append(report.Drifts, reserved...)
+
+	overStock, err := repository.GetReservedOverStock(db)
+	if err != nil {
+		return report, err
+	}
+	report.Drifts = append(report.Drifts, overStock...)
+
+	// dicek setelah repair reserved, karena repair itu juga menyinkronkan products.stock
+	productStock, err := repository.GetProductStockDrift(db)
+	if err != nil {
+		return report, err
+	}
+	if repair && len(productStock) > 0 {
+		err := repository.RunInTx(ctx, db, func(tx *sql.Tx) error {
+			for i, d := range productStock {
+				if err := repository.SyncProductStock(tx, d.ProductID); err != nil {
+					return err
+				}
+				productStock[i].Repaired = true
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	report.Drifts = append(report.Drifts, productStock...)
+
+	inTransit, err := repository.GetInTransitDrift(db)
+	if err != nil {
+		return report, err
+	}
+	if repair && len(inTransit) > 0 {
+		err := repository.RunInTx(ctx, db, func(tx *sql.Tx) error {
+			for i, d := range inTransit {
+				if err := repository.RepairInTransit(tx, d.WarehouseID, d.ProductID); err != nil {
+					return err
+				}
+				inTransit[i].Repaired = true
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+	}
+	report.Drifts = append(report.Drifts, inTransit...)
+
+	return report, nil
+}
+
+// WriteJSON writes the whole report as indented JSON
+func WriteJSON(w io.Writer, report model.ReconcileReport) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// WriteCSV writes one line per drift
+func WriteCSV(w io.Writer, report model.ReconcileReport) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"kind", "warehouse_id", "product_id", "actual", "expected", "difference", "repaired"}); err != nil {
+		return err
+	}
+	for _, d := range report.Drifts {
+		warehouse := ""
+		if d.WarehouseID != 0 {
+			warehouse = strconv.Itoa(d.WarehouseID)
+		}
+		err := cw.Write([]string{
+			d.Kind,
+			warehouse,
+			strconv.Itoa(d.ProductID),
+			strconv.Itoa(d.Actual),
+			strconv.Itoa(d.Expected),
+			strconv.Itoa(d.Actual - d.Expected),
+			strconv.FormatBool(d.Repaired),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,123 @@
+package reconcile
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service-sample/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRun_ReportOnly(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FULL OUTER JOIN live`).
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "reserved", "expected"}).AddRow(1, 2, 5, 3))
+	mock.ExpectQuery(`WHERE reserved > quantity`).
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "reserved", "quantity"}))
+	mock.ExpectQuery(`FROM products p LEFT JOIN warehouse_stock`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "stock", "expected"}).AddRow(2, 10, 12))
+	mock.ExpectQuery(`FULL OUTER JOIN transit`).
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "in_transit", "expected"}))
+
+	report, err := Run(context.Background(), db, false, "system:reconcile")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Drifts) != 2 || report.Drifts[0].Repaired || report.Drifts[1].Kind != "product_stock" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestRun_RepairReserved(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FULL OUTER JOIN live`).
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "reserved", "expected"}).AddRow(1, 2, 5, 3))
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).WithArgs(1, 2).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`UPDATE warehouse_stock ws SET reserved`).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"reserved", "reserved"}).AddRow(5, 3))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 2, "adjustment", 0, -2, nil, nil, "system:reconcile", "reconciliation: reserved 5 -> 3").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE products`).WithArgs(2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery(`WHERE reserved > quantity`).
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "reserved", "quantity"}))
+	mock.ExpectQuery(`FROM products p LEFT JOIN warehouse_stock`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "stock", "expected"}))
+	mock.ExpectQuery(`FULL OUTER JOIN transit`).
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "in_transit", "expected"}))
+
+	report, err := Run(context.Background(), db, true, "system:reconcile")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Drifts) != 1 || !report.Drifts[0].Repaired {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestRun_RepairInTransit(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FULL OUTER JOIN live`).
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "reserved", "expected"}))
+	mock.ExpectQuery(`WHERE reserved > quantity`).
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "reserved", "quantity"}))
+	mock.ExpectQuery(`FROM products p LEFT JOIN warehouse_stock`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "stock", "expected"}))
+	mock.ExpectQuery(`FULL OUTER JOIN transit`).
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id", "product_id", "in_transit", "expected"}).AddRow(2, 4, 0, 6))
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).WithArgs(2, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE warehouse_stock\s+SET in_transit = \(`).WithArgs(2, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	report, err := Run(context.Background(), db, true, "system:reconcile")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Drifts) != 1 || report.Drifts[0].Kind != "in_transit" || !report.Drifts[0].Repaired {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteCSV(&buf, model.ReconcileReport{
+		CheckedAt: time.Now(),
+		Drifts: []model.StockDrift{
+			{Kind: "reserved", WarehouseID: 1, ProductID: 2, Actual: 5, Expected: 3},
+			{Kind: "product_stock", ProductID: 2, Actual: 10, Expected: 12, Repaired: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := "kind,warehouse_id,product_id,actual,expected,difference,repaired\n" +
+		"reserved,1,2,5,3,2,false\n" +
+		"product_stock,,2,10,12,-2,true\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("unexpected csv:\n%s", strings.TrimSpace(got))
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"time"
+
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/reconcile"
+)
+
+// runReconcileCommand handles the "reconcile" run mode.
+//
+//	order-service-sample reconcile [--repair] [--format=json|csv] [report-file]
+//
+// The report goes to the given file or to stdout. It returns 1 when any drift was
+// found (even if it was repaired) or the check failed, 0 otherwise.
+func runReconcileCommand(db *sql.DB, args []string) int {
+	fs := flag.NewFlagSet("reconcile", flag.ContinueOnError)
+	repair := fs.Bool("repair", false, "recompute reserved and products.stock where they drifted")
+	format := fs.String("format", "json", "report format: json or csv")
+
+	files, err := parseArgs(fs, args)
+	if err != nil || len(files) > 1 || (*format != "json" && *format != "csv") {
+		fmt.Fprintln(os.Stderr, "usage: order-service-sample reconcile [--repair] [--format=json|csv] [report-file]")
+		return 2
+	}
+
+	report, err := reconcile.Run(context.Background(), db, *repair, "system:reconcile")
+	if err != nil {
+		log.Println("reconcile: failed:", err)
+		return 1
+	}
+
+	var out io.Writer = os.Stdout
+	if len(files) == 1 {
+		f, err := os.Create(files[0])
+		if err != nil {
+			log.Println("reconcile: failed to create report file:", err)
+			return 1
+		}
+		defer f.Close()
+		out = f
+	}
+
+	if *format == "csv" {
+		err = reconcile.WriteCSV(out, report)
+	} else {
+		err = reconcile.WriteJSON(out, report)
+	}
+	if err != nil {
+		log.Println("reconcile: failed to write report:", err)
+		return 1
+	}
+
+	logDrifts(report)
+	if len(report.Drifts) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// runReconcileJob runs the reconciliation every RECONCILE_INTERVAL (default 1h) as
+// part of the worker. Repair is only done when RECONCILE_REPAIR=true.
+func runReconcileJob(ctx context.Context) {
+	interval, err := time.ParseDuration(helper.GetEnv("RECONCILE_INTERVAL", "1h"))
+	if err != nil || interval <= 0 {
+		interval = time.Hour
+	}
+	repair := helper.GetEnv("RECONCILE_REPAIR", "false") == "true"
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			report, err := reconcile.Run(ctx, db, repair, "system:reconcile")
+			if err != nil {
+				log.Println("reconcile: failed:", err)
+				continue
+			}
+			logDrifts(report)
+		}
+	}
+}
+
+func logDrifts(report model.ReconcileReport) {
+	for _, d := range report.Drifts {
+		log.Printf("reconcile: %s drift warehouse_id=%d product_id=%d actual=%d expected=%d repaired=%v",
+			d.Kind, d.WarehouseID, d.ProductID, d.Actual, d.Expected, d.Repaired)
+	}
+	log.Printf("reconcile: %d drifts found", len(report.Drifts))
+}
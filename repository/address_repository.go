@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"order-service-sample/model"
+)
+
+const addressColumns = `id, label, recipient_name, phone, street, city, region, postal_code, is_default, created_at, updated_at`
+
+func scanAddress(row interface{ Scan(...any) error }, a *model.Address) error {
+	return row.Scan(&a.ID, &a.Label, &a.RecipientName, &a.Phone, &a.Street, &a.City,
+		&a.Region, &a.PostalCode, &a.IsDefault, &a.CreatedAt, &a.UpdatedAt)
+}
+
+// ListAddresses returns the address book of a user, default address first
+func ListAddresses(db *sql.DB, userID int) ([]model.Address, error) {
+	rows, err := db.Query(`
+		SELECT `+addressColumns+`
+		FROM user_addresses
+		WHERE user_id = $1
+		ORDER BY is_default DESC, id
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	addresses := []model.Address{}
+	for rows.Next() {
+		var a model.Address
+		if err := scanAddress(rows, &a); err != nil {
+			return nil, err
+		}
+		addresses = append(addresses, a)
+	}
+	return addresses, rows.Err()
+}
+
+// GetAddress returns an address of the user; addresses of other users are
+// reported as "address_not_found"
+func GetAddress(db *sql.DB, userID, addressID int) (model.Address, error) {
+	var a model.Address
+	err := scanAddress(db.QueryRow(`
+		SELECT `+addressColumns+`
+		FROM user_addresses
+		WHERE id = $1 AND user_id = $2
+	`, addressID, userID), &a)
+	if err == sql.ErrNoRows {
+		return a, errors.New("address_not_found")
+	}
+	return a, err
+}
+
+// GetDefaultAddress returns the default address of the user, "address_not_found" when none is set
+func GetDefaultAddress(db *sql.DB, userID int) (model.Address, error) {
+	var a model.Address
+	err := scanAddress(db.QueryRow(`
+		SELECT `+addressColumns+`
+		FROM user_addresses
+		WHERE user_id = $1 AND is_default
+	`, userID), &a)
+	if err == sql.ErrNoRows {
+		return a, errors.New("address_not_found")
+	}
+	return a, err
+}
+
+// CreateAddress adds an address to the user's address book. The first address of a
+// user always becomes the default; a new default replaces the previous one.
+func CreateAddress(ctx context.Context, db *sql.DB, userID int, req model.CreateAddressReq) (model.Address, error) {
+	var a model.Address
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return a, err
+	}
+	defer tx.Rollback()
+
+	isDefault := req.IsDefault
+	if !isDefault {
+		err := tx.QueryRow(`
+			SELECT NOT EXISTS(SELECT 1 FROM user_addresses WHERE user_id = $1 AND is_default)
+		`, userID).Scan(&isDefault)
+		if err != nil {
+			return a, err
+		}
+	}
+
+	if isDefault {
+		if err := clearDefaultAddress(tx, userID, 0); err != nil {
+			return a, err
+		}
+	}
+
+	err = scanAddress(tx.QueryRow(`
+		INSERT INTO user_addresses (user_id, label, recipient_name, phone, street, city, region, postal_code, is_default)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+addressColumns,
+		userID, req.Label, req.RecipientName, req.Phone, req.Street, req.City, req.Region, req.PostalCode, isDefault), &a)
+	if err != nil {
+		return a, err
+	}
+
+	return a, tx.Commit()
+}
+
+// UpdateAddress changes only the fields that are set in req. Setting is_default
+// moves the default flag from the previous default address.
+func UpdateAddress(ctx context.Context, db *sql.DB, userID, addressID int, req model.UpdateAddressReq) (model.Address, error) {
+	var a model.Address
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return a, err
+	}
+	defer tx.Rollback()
+
+	if req.IsDefault != nil && *req.IsDefault {
+		if err := clearDefaultAddress(tx, userID, addressID); err != nil {
+			return a, err
+		}
+	}
+
+	err = scanAddress(tx.QueryRow(`
+		UPDATE user_addresses
+		SET label = COALESCE($1, label),
+		    recipient_name = COALESCE($2, recipient_name),
+		    phone = COALESCE($3, phone),
+		    street = COALESCE($4, street),
+		    city = COALESCE($5, city),
+		    region = COALESCE($6, region),
+		    postal_code = COALESCE($7, postal_code),
+		    is_default = COALESCE($8, is_default),
+		    updated_at = NOW()
+		WHERE id = $9 AND user_id = $10
+		RETURNING `+addressColumns,
+		req.Label, req.RecipientName, req.Phone, req.Street, req.City, req.Region, req.PostalCode, req.IsDefault,
+		addressID, userID), &a)
+	if err == sql.ErrNoRows {
+		return a, errors.New("address_not_found")
+	}
+	if err != nil {
+		return a, err
+	}
+
+	return a, tx.Commit()
+}
+
+// clearDefaultAddress unsets the default flag of every address of the user except keepID
+func clearDefaultAddress(tx *sql.Tx, userID, keepID int) error {
+	_, err := tx.Exec(`
+		UPDATE user_addresses
+		SET is_default = FALSE, updated_at = NOW()
+		WHERE user_id = $1 AND is_default AND id <> $2
+	`, userID, keepID)
+	return err
+}
+
+// DeleteAddress removes an address from the address book. Orders keep their
+// shipping address snapshot.
+func DeleteAddress(db *sql.DB, userID, addressID int) error {
+	res, err := db.Exec(`DELETE FROM user_addresses WHERE id = $1 AND user_id = $2`, addressID, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("address_not_found")
+	}
+	return nil
+}
+
+// SetOrderShippingAddress copies the address onto the order
+func SetOrderShippingAddress(db *sql.DB, orderID, addressID int, addr model.ShippingAddress) error {
+	_, err := db.Exec(`
+		UPDATE orders
+		SET shipping_address_id = $1,
+		    shipping_recipient_name = $2,
+		    shipping_phone = $3,
+		    shipping_street = $4,
+		    shipping_city = $5,
+		    shipping_region = $6,
+		    shipping_postal_code = $7
+		WHERE id = $8
+	`, addressID, addr.RecipientName, addr.Phone, addr.Street, addr.City, addr.Region, addr.PostalCode, orderID)
+	return err
+}
@@ -0,0 +1,190 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"order-service-sample/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var addressCols = []string{"id", "label", "recipient_name", "phone", "street", "city", "region", "postal_code", "is_default", "created_at", "updated_at"}
+
+func addressRow(id int, isDefault bool) *sqlmock.Rows {
+	now := time.Now()
+	return sqlmock.NewRows(addressCols).
+		AddRow(id, "Rumah", "Budi", "+628123456789", "Jl. Sudirman No. 1", "Jakarta Selatan", "jakarta", "12190", isDefault, now, now)
+}
+
+func TestCreateAddress_FirstBecomesDefault(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT NOT EXISTS\(SELECT 1 FROM user_addresses WHERE user_id = \$1 AND is_default\)`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"not_exists"}).AddRow(true))
+	mock.ExpectExec(`UPDATE user_addresses\s+SET is_default = FALSE`).
+		WithArgs(7, 0).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`INSERT INTO user_addresses`).
+		WithArgs(7, "Rumah", "Budi", "+628123456789", "Jl. Sudirman No. 1", "Jakarta Selatan", "jakarta", "12190", true).
+		WillReturnRows(addressRow(3, true))
+	mock.ExpectCommit()
+
+	a, err := CreateAddress(context.Background(), db, 7, model.CreateAddressReq{
+		Label:         "Rumah",
+		RecipientName: "Budi",
+		Phone:         "+628123456789",
+		Street:        "Jl. Sudirman No. 1",
+		City:          "Jakarta Selatan",
+		Region:        "jakarta",
+		PostalCode:    "12190",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if a.ID != 3 || !a.IsDefault || a.Region != "jakarta" {
+		t.Fatalf("unexpected address %+v", a)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestCreateAddress_KeepsExistingDefault(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT NOT EXISTS`).
+		WithArgs(7).
+		WillReturnRows(sqlmock.NewRows([]string{"not_exists"}).AddRow(false))
+	mock.ExpectQuery(`INSERT INTO user_addresses`).
+		WithArgs(7, "", "Budi", "+628123456789", "Jl. Sudirman No. 1", "Jakarta Selatan", "jakarta", "12190", false).
+		WillReturnRows(addressRow(4, false))
+	mock.ExpectCommit()
+
+	_, err := CreateAddress(context.Background(), db, 7, model.CreateAddressReq{
+		RecipientName: "Budi",
+		Phone:         "+628123456789",
+		Street:        "Jl. Sudirman No. 1",
+		City:          "Jakarta Selatan",
+		Region:        "jakarta",
+		PostalCode:    "12190",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestUpdateAddress_SetDefault(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	city := "Bandung"
+	isDefault := true
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE user_addresses\s+SET is_default = FALSE`).
+		WithArgs(7, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE user_addresses\s+SET label = COALESCE\(\$1, label\)`).
+		WithArgs(nil, nil, nil, nil, &city, nil, nil, &isDefault, 4, 7).
+		WillReturnRows(addressRow(4, true))
+	mock.ExpectCommit()
+
+	a, err := UpdateAddress(context.Background(), db, 7, 4, model.UpdateAddressReq{City: &city, IsDefault: &isDefault})
+	if err != nil || !a.IsDefault {
+		t.Fatalf("unexpected result %+v, %v", a, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestUpdateAddress_OtherUser(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	city := "Bandung"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`UPDATE user_addresses`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err := UpdateAddress(context.Background(), db, 8, 4, model.UpdateAddressReq{City: &city})
+	if err == nil || err.Error() != "address_not_found" {
+		t.Fatalf("expected address_not_found, got %v", err)
+	}
+}
+
+func TestGetAddress_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM user_addresses\s+WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(4, 8).
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := GetAddress(db, 8, 4); err == nil || err.Error() != "address_not_found" {
+		t.Fatalf("expected address_not_found, got %v", err)
+	}
+}
+
+func TestListAddresses(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM user_addresses\s+WHERE user_id = \$1\s+ORDER BY is_default DESC, id`).
+		WithArgs(7).
+		WillReturnRows(addressRow(3, true))
+
+	addresses, err := ListAddresses(db, 7)
+	if err != nil || len(addresses) != 1 || addresses[0].RecipientName != "Budi" {
+		t.Fatalf("unexpected result %+v, %v", addresses, err)
+	}
+}
+
+func TestDeleteAddress_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`DELETE FROM user_addresses WHERE id = \$1 AND user_id = \$2`).
+		WithArgs(4, 8).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := DeleteAddress(db, 8, 4); err == nil || err.Error() != "address_not_found" {
+		t.Fatalf("expected address_not_found, got %v", err)
+	}
+}
+
+func TestSetOrderShippingAddress(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE orders\s+SET shipping_address_id = \$1`).
+		WithArgs(3, "Budi", "+628123456789", "Jl. Sudirman No. 1", "Jakarta Selatan", "jakarta", "12190", 500).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := SetOrderShippingAddress(db, 500, 3, model.ShippingAddress{
+		RecipientName: "Budi",
+		Phone:         "+628123456789",
+		Street:        "Jl. Sudirman No. 1",
+		City:          "Jakarta Selatan",
+		Region:        "jakarta",
+		PostalCode:    "12190",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"order-service-sample/model"
+
+	"github.com/lib/pq"
+)
+
+// CreateAPIKey stores a new key by its hash and fills in ID and CreatedAt
+func CreateAPIKey(db *sql.DB, key model.APIKey, keyHash string) (model.APIKey, error) {
+	err := db.QueryRow(`
+		INSERT INTO api_keys (service, prefix, key_hash, scopes, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, key.Service, key.Prefix, keyHash, pq.Array(key.Scopes), key.ExpiresAt, key.CreatedBy).Scan(&key.ID, &key.CreatedAt)
+	return key, err
+}
+
+// ListAPIKeys returns every key, newest first, including revoked and expired ones
+func ListAPIKeys(db *sql.DB) ([]model.APIKey, error) {
+	rows, err := db.Query(`
+		SELECT id, service, prefix, scopes, expires_at, created_by, created_at, last_used_at, revoked_at
+		FROM api_keys
+		ORDER BY id DESC
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []model.APIKey{}
+	for rows.Next() {
+		var k model.APIKey
+		if err := rows.Scan(&k.ID, &k.Service, &k.Prefix, pq.Array(&k.Scopes), &k.ExpiresAt,
+			&k.CreatedBy, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt); err != nil {
+			return nil, err
+		}
+		keys = append(keys, k)
+	}
+	return keys, rows.Err()
+}
+
+// GetActiveAPIKey looks up a key by its hash; revoked and expired keys are
+// reported as "api_key_invalid" just like unknown ones
+func GetActiveAPIKey(db *sql.DB, keyHash string) (model.APIKey, error) {
+	var k model.APIKey
+	err := db.QueryRow(`
+		SELECT id, service, prefix, scopes, expires_at, created_by, created_at, last_used_at, revoked_at
+		FROM api_keys
+		WHERE key_hash = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > NOW())
+	`, keyHash).Scan(&k.ID, &k.Service, &k.Prefix, pq.Array(&k.Scopes), &k.ExpiresAt,
+		&k.CreatedBy, &k.CreatedAt, &k.LastUsedAt, &k.RevokedAt)
+	if err == sql.ErrNoRows {
+		return k, errors.New("api_key_invalid")
+	}
+	return k, err
+}
+
+// TouchAPIKey records that a key was used. To spare a write on every request the
+// timestamp is only moved once a minute.
+func TouchAPIKey(db *sql.DB, id int) error {
+	_, err := db.Exec(`
+		UPDATE api_keys SET last_used_at = NOW()
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < NOW() - INTERVAL '1 minute')
+	`, id)
+	return err
+}
+
+// RevokeAPIKey revokes a key; revoking it again is a no-op
+func RevokeAPIKey(db *sql.DB, id int) error {
+	res, err := db.Exec(`UPDATE api_keys SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("api_key_not_found")
+	}
+	return nil
+}
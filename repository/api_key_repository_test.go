@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"order-service-sample/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var apiKeyCols = []string{"id", "service", "prefix", "scopes", "expires_at", "created_by", "created_at", "last_used_at", "revoked_at"}
+
+func TestCreateAPIKey(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`INSERT INTO api_keys \(service, prefix, key_hash, scopes, expires_at, created_by\)`).
+		WithArgs("erp", "sk_ab12", "hash", `{"transfers:write"}`, nil, "user:1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+
+	key, err := CreateAPIKey(db, model.APIKey{
+		Service:   "erp",
+		Prefix:    "sk_ab12",
+		Scopes:    []string{"transfers:write"},
+		CreatedBy: "user:1",
+	}, "hash")
+	if err != nil || key.ID != 3 || !key.CreatedAt.Equal(now) {
+		t.Fatalf("unexpected result %+v, %v", key, err)
+	}
+}
+
+func TestGetActiveAPIKey(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM api_keys\s+WHERE key_hash = \$1\s+AND revoked_at IS NULL\s+AND \(expires_at IS NULL OR expires_at > NOW\(\)\)`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows(apiKeyCols).
+			AddRow(3, "erp", "sk_ab12", `{transfers:read,warehouses:read}`, nil, "user:1", time.Now(), nil, nil))
+
+	key, err := GetActiveAPIKey(db, "hash")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if key.Service != "erp" || len(key.Scopes) != 2 || key.Scopes[1] != "warehouses:read" {
+		t.Fatalf("unexpected key %+v", key)
+	}
+}
+
+func TestGetActiveAPIKey_Invalid(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM api_keys`).WillReturnError(sql.ErrNoRows)
+
+	if _, err := GetActiveAPIKey(db, "nope"); err == nil || err.Error() != "api_key_invalid" {
+		t.Fatalf("expected api_key_invalid, got %v", err)
+	}
+}
+
+func TestRevokeAPIKey_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE api_keys SET revoked_at = COALESCE\(revoked_at, NOW\(\)\) WHERE id = \$1`).
+		WithArgs(99).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := RevokeAPIKey(db, 99); err == nil || err.Error() != "api_key_not_found" {
+		t.Fatalf("expected api_key_not_found, got %v", err)
+	}
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"order-service-sample/model"
+)
+
+// RecordForbiddenAttempt appends a refused request to the audit log
+func RecordForbiddenAttempt(db *sql.DB, a model.ForbiddenAttempt) error {
+	var userID any
+	if a.UserID != 0 {
+		userID = a.UserID
+	}
+	detail := fmt.Sprintf("actor=%s required=%s", a.Actor, strings.Join(a.RequiredRoles, ","))
+	if a.RequiredScope != "" {
+		detail += " scope=" + a.RequiredScope
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO audit_log (user_id, role, action, method, path, remote_addr, detail, created_at)
+		VALUES ($1, $2, 'forbidden', $3, $4, $5, $6, $7)
+	`, userID, a.Role, a.Method, a.Path, a.RemoteAddr, detail, a.CreatedAt)
+	return err
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+
+	"order-service-sample/model"
+)
+
+// UpsertProductBySKU inserts a product or updates the existing one with the same sku.
+// Weight and dimensions that are not set keep their stored value.
+func UpsertProductBySKU(tx *sql.Tx, p model.ProductImportRow) (int, error) {
+	var id int
+	err := tx.QueryRow(`
+		INSERT INTO products (sku, name, description, price, weight_grams, length_mm, width_mm, height_mm)
+		VALUES ($1, $2, $3, $4, COALESCE($5, 0), COALESCE($6, 0), COALESCE($7, 0), COALESCE($8, 0))
+		ON CONFLICT (sku) DO UPDATE
+		SET name = EXCLUDED.name,
+		    description = EXCLUDED.description,
+		    price = EXCLUDED.price,
+		    weight_grams = COALESCE($5, products.weight_grams),
+		    length_mm = COALESCE($6, products.length_mm),
+		    width_mm = COALESCE($7, products.width_mm),
+		    height_mm = COALESCE($8, products.height_mm)
+		RETURNING id
+	`, p.SKU, p.Name, p.Description, p.Price, p.WeightGrams, p.LengthMM, p.WidthMM, p.HeightMM).Scan(&id)
+
+	return id, err
+}
+
+// UpsertWarehouseStock sets the on-hand quantity of a product in a warehouse and
+// records the difference as an adjustment in the stock ledger.
+// The update is refused when the new quantity would drop below what is already reserved.
+func UpsertWarehouseStock(tx *sql.Tx, warehouseID, productID, quantity int, actor string) error {
+	var previous sql.NullInt64
+	err := tx.QueryRow(`
+		WITH prev AS (
+			SELECT quantity FROM warehouse_stock
+			WHERE warehouse_id = $1 AND product_id = $2
+			FOR UPDATE
+		)
+		INSERT INTO warehouse_stock (warehouse_id, product_id, quantity, reserved)
+		VALUES ($1, $2, $3, 0)
+		ON CONFLICT (warehouse_id, product_id) DO UPDATE
+		SET quantity = EXCLUDED.quantity,
+		    updated_at = NOW()
+		WHERE warehouse_stock.reserved <= EXCLUDED.quantity
+		RETURNING (SELECT quantity FROM prev)
+	`, warehouseID, productID, quantity).Scan(&previous)
+	if err == sql.ErrNoRows {
+		return errors.New("quantity_below_reserved")
+	}
+	if err != nil {
+		return err
+	}
+
+	delta := quantity - int(previous.Int64)
+	if delta == 0 {
+		return nil
+	}
+
+	return RecordStockMovement(tx, model.StockMovement{
+		WarehouseID:   warehouseID,
+		ProductID:     productID,
+		Type:          MovementAdjustment,
+		QuantityDelta: delta,
+		Actor:         actor,
+		Note:          "bulk stock import",
+	})
+}
+
+// SyncProductStock recomputes products.stock as the available stock over all warehouses
+func SyncProductStock(tx *sql.Tx, productID int) error {
+	_, err := tx.Exec(`
+		UPDATE products
+		SET stock = COALESCE((
+			SELECT SUM(quantity - reserved)
+			FROM warehouse_stock
+			WHERE product_id = $1
+		), 0)
+		WHERE id = $1
+	`, productID)
+	return err
+}
+
+// GetStockPerWarehouse returns every warehouse_stock row with warehouse and product names
+func GetStockPerWarehouse(db *sql.DB) ([]model.WarehouseStockRow, error) {
+	rows, err := db.Query(`
+		SELECT ws.warehouse_id, w.name, ws.product_id, p.name, ws.quantity, ws.reserved
+		FROM warehouse_stock ws
+		JOIN warehouses w ON w.id = ws.warehouse_id
+		JOIN products p ON p.id = ws.product_id
+		ORDER BY ws.warehouse_id, ws.product_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.WarehouseStockRow{}
+	for rows.Next() {
+		var it model.WarehouseStockRow
+		if err := rows.Scan(&it.WarehouseID, &it.WarehouseName, &it.ProductID, &it.ProductName, &it.Quantity, &it.Reserved); err != nil {
+			return nil, err
+		}
+		it.Available = it.Quantity - it.Reserved
+		items = append(items, it)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
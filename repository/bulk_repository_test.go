@@ -0,0 +1,165 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"regexp"
+	"testing"
+
+	"order-service-sample/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUpsertProductBySKU_Success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO products \(sku, name, description, price, weight_grams, length_mm, width_mm, height_mm\).*ON CONFLICT \(sku\) DO UPDATE`).
+		WithArgs("SKU-1", "Mouse", "desc", "150000.00", nil, nil, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(7))
+	mock.ExpectCommit()
+
+	tx, _ := db.Begin()
+	id, err := UpsertProductBySKU(tx, model.ProductImportRow{
+		SKU: "SKU-1", Name: "Mouse", Description: "desc", Price: "150000.00",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if id != 7 {
+		t.Fatalf("expected id 7, got %d", id)
+	}
+	tx.Commit()
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUpsertWarehouseStock_RecordsAdjustment(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`WITH prev AS .*INSERT INTO warehouse_stock .*ON CONFLICT \(warehouse_id, product_id\) DO UPDATE.*WHERE warehouse_stock.reserved <= EXCLUDED.quantity`).
+		WithArgs(1, 2, 30).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(20))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 2, MovementAdjustment, 10, 0, nil, nil, "system:import", "bulk stock import").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, _ := db.Begin()
+	if err := UpsertWarehouseStock(tx, 1, 2, 30, "system:import"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUpsertWarehouseStock_NewRow(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO warehouse_stock`).
+		WithArgs(3, 2, 5).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(nil))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(3, 2, MovementAdjustment, 5, 0, nil, nil, "system:import", "bulk stock import").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, _ := db.Begin()
+	if err := UpsertWarehouseStock(tx, 3, 2, 5, "system:import"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestUpsertWarehouseStock_UnchangedSkipsLedger(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO warehouse_stock`).
+		WithArgs(1, 2, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity"}).AddRow(20))
+
+	tx, _ := db.Begin()
+	if err := UpsertWarehouseStock(tx, 1, 2, 20, "system:import"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUpsertWarehouseStock_BelowReserved(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO warehouse_stock`).
+		WithArgs(1, 2, 1).
+		WillReturnError(sql.ErrNoRows)
+
+	tx, _ := db.Begin()
+	err := UpsertWarehouseStock(tx, 1, 2, 1, "system:import")
+	if err == nil || err.Error() != "quantity_below_reserved" {
+		t.Fatalf("expected quantity_below_reserved, got %v", err)
+	}
+}
+
+func TestUpsertWarehouseStock_QueryError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO warehouse_stock`).
+		WithArgs(9, 2, 5).
+		WillReturnError(errors.New("fk violation"))
+
+	tx, _ := db.Begin()
+	if err := UpsertWarehouseStock(tx, 9, 2, 5, "system:import"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestSyncProductStock(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE products SET stock = COALESCE\(\(.*SUM\(quantity - reserved\)`).
+		WithArgs(3).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	tx, _ := db.Begin()
+	if err := SyncProductStock(tx, 3); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGetStockPerWarehouse(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"warehouse_id", "name", "product_id", "name", "quantity", "reserved"}).
+		AddRow(1, "Central", 1, "Mouse", 20, 5).
+		AddRow(2, "Jakarta", 4, "Headset", 15, 0)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT ws.warehouse_id, w.name, ws.product_id, p.name, ws.quantity, ws.reserved`)).
+		WillReturnRows(rows)
+
+	items, err := GetStockPerWarehouse(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(items) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(items))
+	}
+	if items[0].Available != 15 {
+		t.Fatalf("expected available 15, got %d", items[0].Available)
+	}
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"database/sql"
+)
+
+// GetCartItems returns product_id -> qty of a user's persisted cart
+func GetCartItems(db *sql.DB, userID int) (map[int]int, error) {
+	rows, err := db.Query(`
+		SELECT product_id, qty
+		FROM cart_items
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := map[int]int{}
+	for rows.Next() {
+		var productID, qty int
+		if err := rows.Scan(&productID, &qty); err != nil {
+			return nil, err
+		}
+		items[productID] = qty
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func UpsertCartItem(db *sql.DB, userID, productID, qty int) error {
+	_, err := db.Exec(`
+		INSERT INTO cart_items (user_id, product_id, qty, updated_at)
+		VALUES ($1, $2, $3, NOW())
+		ON CONFLICT (user_id, product_id) DO UPDATE
+		SET qty = EXCLUDED.qty,
+		    updated_at = NOW()
+	`, userID, productID, qty)
+	return err
+}
+
+func DeleteCartItem(db *sql.DB, userID, productID int) error {
+	_, err := db.Exec(`DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2`, userID, productID)
+	return err
+}
+
+func ClearCart(db *sql.DB, userID int) error {
+	_, err := db.Exec(`DELETE FROM cart_items WHERE user_id = $1`, userID)
+	return err
+}
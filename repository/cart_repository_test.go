@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetCartItems_Success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"product_id", "qty"}).
+		AddRow(1, 2).
+		AddRow(3, 1)
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT product_id, qty FROM cart_items WHERE user_id = $1`)).
+		WithArgs(7).
+		WillReturnRows(rows)
+
+	items, err := GetCartItems(db, 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if items[1] != 2 || items[3] != 1 {
+		t.Fatalf("unexpected items: %v", items)
+	}
+}
+
+func TestGetCartItems_QueryError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT product_id, qty FROM cart_items`).
+		WithArgs(7).
+		WillReturnError(errors.New("db down"))
+
+	if _, err := GetCartItems(db, 7); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestUpsertCartItem(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO cart_items .*ON CONFLICT \(user_id, product_id\) DO UPDATE`).
+		WithArgs(7, 1, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := UpsertCartItem(db, 7, 1, 5); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestDeleteCartItemAndClearCart(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM cart_items WHERE user_id = $1 AND product_id = $2`)).
+		WithArgs(7, 1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`DELETE FROM cart_items WHERE user_id = $1`)).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	if err := DeleteCartItem(db, 7, 1); err != nil {
+		t.Fatalf("DeleteCartItem err: %v", err)
+	}
+	if err := ClearCart(db, 7); err != nil {
+		t.Fatalf("ClearCart err: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"database/sql"
+
+	"order-service-sample/model"
+)
+
+// GetAllCategories returns every category as a flat list ordered by id
+func GetAllCategories(db *sql.DB) ([]model.Category, error) {
+	rows, err := db.Query(`
+		SELECT id, parent_id, name
+		FROM categories
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	categories := []model.Category{}
+	for rows.Next() {
+		var c model.Category
+		var parentID sql.NullInt64
+		if err := rows.Scan(&c.ID, &parentID, &c.Name); err != nil {
+			return nil, err
+		}
+		if parentID.Valid {
+			pid := int(parentID.Int64)
+			c.ParentID = &pid
+		}
+		categories = append(categories, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// BuildCategoryTree nests a flat category list under its parents.
+// Categories whose parent is missing from the list are treated as roots.
+func BuildCategoryTree(flat []model.Category) []model.Category {
+	children := map[int][]model.Category{}
+	known := map[int]bool{}
+	for _, c := range flat {
+		known[c.ID] = true
+	}
+
+	var roots []model.Category
+	for _, c := range flat {
+		if c.ParentID == nil || !known[*c.ParentID] {
+			roots = append(roots, c)
+			continue
+		}
+		children[*c.ParentID] = append(children[*c.ParentID], c)
+	}
+
+	var attach func(c model.Category) model.Category
+	attach = func(c model.Category) model.Category {
+		c.Children = []model.Category{}
+		for _, child := range children[c.ID] {
+			c.Children = append(c.Children, attach(child))
+		}
+		return c
+	}
+
+	tree := []model.Category{}
+	for _, r := range roots {
+		tree = append(tree, attach(r))
+	}
+	return tree
+}
+
+func CategoryExists(db *sql.DB, categoryID int) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1)
+	`, categoryID).Scan(&exists)
+
+	return exists, err
+}
+
+// GetProductsByCategory returns products linked to a category or any of its descendants
+func GetProductsByCategory(db *sql.DB, categoryID int) ([]model.ProductResp, error) {
+	rows, err := db.Query(`
+		WITH RECURSIVE tree AS (
+			SELECT id FROM categories WHERE id = $1
+			UNION
+			SELECT c.id FROM categories c JOIN tree t ON c.parent_id = t.id
+		)
+		SELECT DISTINCT p.id, p.name, p.stock, p.price, p.description, p.weight_grams, p.length_mm, p.width_mm, p.height_mm
+		FROM products p
+		JOIN product_categories pc ON pc.product_id = p.id
+		JOIN tree t ON t.id = pc.category_id
+		ORDER BY p.id
+	`, categoryID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	products := []model.ProductResp{}
+	for rows.Next() {
+		var p model.ProductResp
+		if err := rows.Scan(&p.ID, &p.Name, &p.Stock, &p.Price, &p.Description, &p.WeightGrams, &p.LengthMM, &p.WidthMM, &p.HeightMM); err != nil {
+			return nil, err
+		}
+		products = append(products, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return products, nil
+}
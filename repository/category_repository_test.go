@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"order-service-sample/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetAllCategories_Success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "parent_id", "name"}).
+		AddRow(1, nil, "Accessories").
+		AddRow(2, 1, "Input")
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT id, parent_id, name FROM categories ORDER BY id`)).
+		WillReturnRows(rows)
+
+	categories, err := GetAllCategories(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(categories) != 2 {
+		t.Fatalf("expected 2 categories, got %d", len(categories))
+	}
+	if categories[0].ParentID != nil {
+		t.Fatalf("expected root parent_id nil")
+	}
+	if categories[1].ParentID == nil || *categories[1].ParentID != 1 {
+		t.Fatalf("expected parent_id 1")
+	}
+}
+
+func TestGetAllCategories_QueryError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT id, parent_id, name FROM categories`).
+		WillReturnError(errors.New("db down"))
+
+	if _, err := GetAllCategories(db); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestBuildCategoryTree(t *testing.T) {
+	one, two := 1, 2
+	orphanParent := 99
+
+	tree := BuildCategoryTree([]model.Category{
+		{ID: 1, Name: "Accessories"},
+		{ID: 2, ParentID: &one, Name: "Input"},
+		{ID: 3, ParentID: &two, Name: "Keyboards"},
+		{ID: 4, ParentID: &one, Name: "Connectivity"},
+		{ID: 5, ParentID: &orphanParent, Name: "Orphan"},
+	})
+
+	if len(tree) != 2 {
+		t.Fatalf("expected 2 roots, got %d", len(tree))
+	}
+	if len(tree[0].Children) != 2 {
+		t.Fatalf("expected 2 children under root, got %d", len(tree[0].Children))
+	}
+	if tree[0].Children[0].Children[0].Name != "Keyboards" {
+		t.Fatalf("expected nested Keyboards, got %+v", tree[0].Children[0])
+	}
+	if tree[1].Name != "Orphan" {
+		t.Fatalf("expected orphan to be a root, got %s", tree[1].Name)
+	}
+}
+
+func TestCategoryExists(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM categories WHERE id = $1)`)).
+		WithArgs(3).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := CategoryExists(db, 3)
+	if err != nil || !exists {
+		t.Fatalf("expected exists=true, got %v (err=%v)", exists, err)
+	}
+}
+
+func TestGetProductsByCategory_IncludesDescendants(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "name", "stock", "price", "description", "weight_grams", "length_mm", "width_mm", "height_mm"}).
+		AddRow(1, "Mouse", 50, "150000.00", "desc", 150, 120, 70, 40).
+		AddRow(2, "Keyboard", 30, "700000.00", "desc", 1100, 450, 150, 45)
+
+	mock.ExpectQuery(`WITH RECURSIVE tree AS .*JOIN tree t ON c.parent_id = t.id.*FROM products p`).
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	products, err := GetProductsByCategory(db, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(products) != 2 {
+		t.Fatalf("expected 2 products, got %d", len(products))
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"order-service-sample/model"
 	"strconv"
 	"strings"
+
+	"github.com/lib/pq"
 )
 
 func GetProductPrice(db *sql.DB, productID int) (int64, error) {
@@ -83,6 +85,16 @@ func CreateOrder(db *sql.DB, userID int, totalAmount int64) (int, error) {
 	return orderID, err
 }
 
+// CancelOrder marks a pending order as cancelled
+func CancelOrder(db *sql.DB, orderID int) error {
+	_, err := db.Exec(`
+		UPDATE orders
+		SET status = 'cancelled'
+		WHERE id = $1 AND status = 'pending'
+	`, orderID)
+	return err
+}
+
 func InsertOrderItem(db *sql.DB, orderID int, item model.CheckoutItem, price int64) error {
 	_, err := db.Exec(`
 		INSERT INTO order_items (order_id, product_id, quantity, price)
@@ -91,3 +103,37 @@ func InsertOrderItem(db *sql.DB, orderID int, item model.CheckoutItem, price int
 
 	return err
 }
+
+// GetProductPrices returns the price in cents of every given product that exists.
+// Products missing from the result do not exist.
+func GetProductPrices(db *sql.DB, productIDs []int) (map[int]int64, error) {
+	rows, err := db.Query(`
+		SELECT id, price
+		FROM products
+		WHERE id = ANY($1)
+	`, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := map[int]int64{}
+	for rows.Next() {
+		var id int
+		var priceStr string
+		if err := rows.Scan(&id, &priceStr); err != nil {
+			return nil, err
+		}
+		cents, err := convertPriceToCents(priceStr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert price to cents: %w", err)
+		}
+		prices[id] = cents
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prices, nil
+}
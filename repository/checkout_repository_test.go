@@ -191,6 +191,22 @@ func TestCreateOrder_Success(t *testing.T) {
 	}
 }
 
+func TestCancelOrder_OnlyPending(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE orders SET status = 'cancelled' WHERE id = \$1 AND status = 'pending'`).
+		WithArgs(123).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := CancelOrder(db, 123); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
 func TestInsertOrderItem_Success(t *testing.T) {
 	db, mock, _ := sqlmock.New()
 	defer db.Close()
@@ -221,3 +237,38 @@ func TestInsertOrderItem_Success(t *testing.T) {
 		t.Fatalf("expectations not met: %v", err)
 	}
 }
+
+func TestGetProductPrices_Success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "price"}).
+		AddRow(1, "150000.00").
+		AddRow(2, "700000.5")
+
+	mock.ExpectQuery(`SELECT id, price FROM products WHERE id = ANY\(\$1\)`).
+		WillReturnRows(rows)
+
+	prices, err := GetProductPrices(db, []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if prices[1] != 15000000 || prices[2] != 70000050 {
+		t.Fatalf("unexpected prices: %v", prices)
+	}
+	if _, ok := prices[3]; ok {
+		t.Fatalf("missing product must not be in result")
+	}
+}
+
+func TestGetProductPrices_QueryError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT id, price FROM products`).
+		WillReturnError(errors.New("db down"))
+
+	if _, err := GetProductPrices(db, []int{1}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
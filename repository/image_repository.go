@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"database/sql"
+
+	"order-service-sample/model"
+
+	"github.com/lib/pq"
+)
+
+func ProductExists(db *sql.DB, productID int) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)
+	`, productID).Scan(&exists)
+
+	return exists, err
+}
+
+// InsertProductImage stores image metadata. When position is nil the image is
+// appended after the product's current last image.
+func InsertProductImage(db *sql.DB, img model.ProductImage, position *int) (model.ProductImage, error) {
+	var pos sql.NullInt64
+	if position != nil {
+		pos = sql.NullInt64{Int64: int64(*position), Valid: true}
+	}
+
+	err := db.QueryRow(`
+		INSERT INTO product_images (product_id, storage_key, url, content_type, size_bytes, position)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6, (
+			SELECT COALESCE(MAX(position), -1) + 1 FROM product_images WHERE product_id = $1
+		)))
+		RETURNING id, position
+	`, img.ProductID, img.StorageKey, img.URL, img.ContentType, img.SizeBytes, pos).Scan(&img.ID, &img.Position)
+
+	return img, err
+}
+
+// GetProductImages returns the images of the given products keyed by product id, in display order
+func GetProductImages(db *sql.DB, productIDs []int) (map[int][]model.ProductImage, error) {
+	images := map[int][]model.ProductImage{}
+	if len(productIDs) == 0 {
+		return images, nil
+	}
+
+	rows, err := db.Query(`
+		SELECT id, product_id, storage_key, url, content_type, size_bytes, position
+		FROM product_images
+		WHERE product_id = ANY($1)
+		ORDER BY product_id, position, id
+	`, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var img model.ProductImage
+		if err := rows.Scan(&img.ID, &img.ProductID, &img.StorageKey, &img.URL, &img.ContentType, &img.SizeBytes, &img.Position); err != nil {
+			return nil, err
+		}
+		images[img.ProductID] = append(images[img.ProductID], img)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
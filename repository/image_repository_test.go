@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"order-service-sample/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestProductExists(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)`)).
+		WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := ProductExists(db, 1)
+	if err != nil || !exists {
+		t.Fatalf("expected exists=true, got %v (err=%v)", exists, err)
+	}
+}
+
+func TestInsertProductImage_AppendsWhenNoPosition(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO product_images .*COALESCE\(\$6, \(.*MAX\(position\)`).
+		WithArgs(1, "products/1/a.png", "/media/products/1/a.png", "image/png", int64(10), nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "position"}).AddRow(5, 2))
+
+	img, err := InsertProductImage(db, model.ProductImage{
+		ProductID:   1,
+		StorageKey:  "products/1/a.png",
+		URL:         "/media/products/1/a.png",
+		ContentType: "image/png",
+		SizeBytes:   10,
+	}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.ID != 5 || img.Position != 2 {
+		t.Fatalf("unexpected image: %+v", img)
+	}
+}
+
+func TestInsertProductImage_ExplicitPosition(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	pos := 0
+	mock.ExpectQuery(`INSERT INTO product_images`).
+		WithArgs(1, "k", "u", "image/jpeg", int64(1), int64(0)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "position"}).AddRow(6, 0))
+
+	img, err := InsertProductImage(db, model.ProductImage{
+		ProductID: 1, StorageKey: "k", URL: "u", ContentType: "image/jpeg", SizeBytes: 1,
+	}, &pos)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if img.Position != 0 {
+		t.Fatalf("expected position 0, got %d", img.Position)
+	}
+}
+
+func TestGetProductImages_GroupsByProduct(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"id", "product_id", "storage_key", "url", "content_type", "size_bytes", "position"}).
+		AddRow(1, 1, "k1", "u1", "image/png", 10, 0).
+		AddRow(2, 1, "k2", "u2", "image/png", 10, 1).
+		AddRow(3, 2, "k3", "u3", "image/jpeg", 10, 0)
+
+	mock.ExpectQuery(`SELECT id, product_id, storage_key, url, content_type, size_bytes, position FROM product_images WHERE product_id = ANY\(\$1\)`).
+		WillReturnRows(rows)
+
+	images, err := GetProductImages(db, []int{1, 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(images[1]) != 2 || images[1][1].URL != "u2" {
+		t.Fatalf("unexpected images for product 1: %+v", images[1])
+	}
+	if len(images[2]) != 1 {
+		t.Fatalf("unexpected images for product 2: %+v", images[2])
+	}
+}
+
+func TestGetProductImages_EmptyIDsSkipsQuery(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	images, err := GetProductImages(db, nil)
+	if err != nil || len(images) != 0 {
+		t.Fatalf("expected empty map, got %v (err=%v)", images, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unexpected query: %v", err)
+	}
+}
+
+func TestGetProductImages_QueryError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT id, product_id`).WillReturnError(errors.New("db down"))
+
+	if _, err := GetProductImages(db, []int{1}); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -0,0 +1,189 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"order-service-sample/model"
+)
+
+// AdjustmentReasonCodes are the accepted reasons for a manual stock adjustment
+var AdjustmentReasonCodes = map[string]bool{
+	"cycle_count": true,
+	"damaged":     true,
+	"lost":        true,
+	"found":       true,
+	"expired":     true,
+	"correction":  true,
+}
+
+// CreateGoodsReceipt books inbound goods into a warehouse. Missing warehouse_stock
+// rows are created the same way TransferStock does it.
+func CreateGoodsReceipt(ctx context.Context, db *sql.DB, warehouseID int, req model.GoodsReceiptReq, actor string) (model.GoodsReceipt, error) {
+	receipt := model.GoodsReceipt{
+		WarehouseID: warehouseID,
+		Reference:   req.Reference,
+		Note:        req.Note,
+		Actor:       actor,
+		Items:       append([]model.GoodsReceiptItem(nil), req.Items...),
+	}
+
+	// kunci row warehouse_stock selalu dalam urutan product_id yang sama
+	sort.Slice(receipt.Items, func(i, j int) bool { return receipt.Items[i].ProductID < receipt.Items[j].ProductID })
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return receipt, err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(`
+		INSERT INTO goods_receipts (warehouse_id, reference, note, actor)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, warehouseID, req.Reference, req.Note, actor).Scan(&receipt.ID, &receipt.CreatedAt)
+	if err != nil {
+		return receipt, err
+	}
+
+	note := fmt.Sprintf("goods receipt #%d", receipt.ID)
+	if req.Reference != "" {
+		note += " (" + req.Reference + ")"
+	}
+
+	for _, it := range receipt.Items {
+		if err := ensureStockRowExists(tx, warehouseID, it.ProductID); err != nil {
+			return receipt, err
+		}
+
+		_, err := tx.Exec(`
+			UPDATE warehouse_stock
+			SET quantity = quantity + $1, updated_at = NOW()
+			WHERE warehouse_id = $2 AND product_id = $3
+		`, it.Quantity, warehouseID, it.ProductID)
+		if err != nil {
+			return receipt, err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO goods_receipt_items (receipt_id, product_id, quantity)
+			VALUES ($1, $2, $3)
+		`, receipt.ID, it.ProductID, it.Quantity)
+		if err != nil {
+			return receipt, err
+		}
+
+		err = RecordStockMovement(tx, model.StockMovement{
+			WarehouseID:   warehouseID,
+			ProductID:     it.ProductID,
+			Type:          MovementReceipt,
+			QuantityDelta: it.Quantity,
+			Actor:         actor,
+			Note:          note,
+		})
+		if err != nil {
+			return receipt, err
+		}
+
+		if err := SyncProductStock(tx, it.ProductID); err != nil {
+			return receipt, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return receipt, err
+	}
+	return receipt, nil
+}
+
+// CreateStockAdjustment corrects the on-hand quantity of a product in a warehouse,
+// either to an absolute counted quantity or by a signed delta. The quantity may
+// never drop below what is reserved.
+func CreateStockAdjustment(ctx context.Context, db *sql.DB, warehouseID int, req model.StockAdjustmentReq, actor string) (model.StockAdjustment, error) {
+	adj := model.StockAdjustment{
+		WarehouseID: warehouseID,
+		ProductID:   req.ProductID,
+		ReasonCode:  req.ReasonCode,
+		Note:        req.Note,
+		Actor:       actor,
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return adj, err
+	}
+	defer tx.Rollback()
+
+	if err := ensureStockRowExists(tx, warehouseID, req.ProductID); err != nil {
+		return adj, err
+	}
+
+	var reserved int
+	err = tx.QueryRow(`
+		SELECT quantity, reserved
+		FROM warehouse_stock
+		WHERE warehouse_id = $1 AND product_id = $2
+		FOR UPDATE
+	`, warehouseID, req.ProductID).Scan(&adj.QuantityBefore, &reserved)
+	if err != nil {
+		return adj, err
+	}
+
+	if req.CountedQuantity != nil {
+		adj.QuantityAfter = *req.CountedQuantity
+	} else {
+		adj.QuantityAfter = adj.QuantityBefore + req.QuantityDelta
+	}
+	adj.QuantityDelta = adj.QuantityAfter - adj.QuantityBefore
+
+	if adj.QuantityAfter < reserved {
+		return adj, errors.New("quantity_below_reserved")
+	}
+	if adj.QuantityDelta == 0 {
+		return adj, errors.New("no_change")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE warehouse_stock
+		SET quantity = $1, updated_at = NOW()
+		WHERE warehouse_id = $2 AND product_id = $3
+	`, adj.QuantityAfter, warehouseID, req.ProductID)
+	if err != nil {
+		return adj, err
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO stock_adjustments
+			(warehouse_id, product_id, quantity_before, quantity_after, quantity_delta, reason_code, note, actor)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at
+	`, warehouseID, req.ProductID, adj.QuantityBefore, adj.QuantityAfter, adj.QuantityDelta,
+		req.ReasonCode, req.Note, actor).Scan(&adj.ID, &adj.CreatedAt)
+	if err != nil {
+		return adj, err
+	}
+
+	err = RecordStockMovement(tx, model.StockMovement{
+		WarehouseID:   warehouseID,
+		ProductID:     req.ProductID,
+		Type:          MovementAdjustment,
+		QuantityDelta: adj.QuantityDelta,
+		Actor:         actor,
+		Note:          fmt.Sprintf("stock adjustment #%d (%s)", adj.ID, req.ReasonCode),
+	})
+	if err != nil {
+		return adj, err
+	}
+
+	if err := SyncProductStock(tx, req.ProductID); err != nil {
+		return adj, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return adj, err
+	}
+	return adj, nil
+}
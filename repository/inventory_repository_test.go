@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"order-service-sample/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestCreateGoodsReceipt_Success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO goods_receipts`).
+		WithArgs(1, "PO-1", "", "user:7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(5, now))
+
+	// items are booked in product_id order
+	for _, it := range []struct{ product, qty int }{{2, 3}, {9, 10}} {
+		mock.ExpectExec(`INSERT INTO warehouse_stock`).
+			WithArgs(1, it.product).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE warehouse_stock SET quantity = quantity \+ \$1`).
+			WithArgs(it.qty, 1, it.product).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO goods_receipt_items`).
+			WithArgs(5, it.product, it.qty).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO stock_movements`).
+			WithArgs(1, it.product, MovementReceipt, it.qty, 0, nil, nil, "user:7", "goods receipt #5 (PO-1)").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE products`).
+			WithArgs(it.product).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	receipt, err := CreateGoodsReceipt(context.Background(), db, 1, model.GoodsReceiptReq{
+		Reference: "PO-1",
+		Items: []model.GoodsReceiptItem{
+			{ProductID: 9, Quantity: 10},
+			{ProductID: 2, Quantity: 3},
+		},
+	}, "user:7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if receipt.ID != 5 || len(receipt.Items) != 2 {
+		t.Fatalf("unexpected receipt: %+v", receipt)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestCreateStockAdjustment_CountedQuantity(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	counted := 18
+	now := time.Now()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT quantity, reserved`)).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reserved"}).AddRow(20, 5))
+	mock.ExpectExec(`UPDATE warehouse_stock SET quantity = \$1`).
+		WithArgs(18, 1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`INSERT INTO stock_adjustments`).
+		WithArgs(1, 2, 20, 18, -2, "cycle_count", "", "user:7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow(3, now))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 2, MovementAdjustment, -2, 0, nil, nil, "user:7", "stock adjustment #3 (cycle_count)").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE products`).
+		WithArgs(2).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	adj, err := CreateStockAdjustment(context.Background(), db, 1, model.StockAdjustmentReq{
+		ProductID:       2,
+		CountedQuantity: &counted,
+		ReasonCode:      "cycle_count",
+	}, "user:7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if adj.QuantityDelta != -2 || adj.QuantityAfter != 18 {
+		t.Fatalf("unexpected adjustment: %+v", adj)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestCreateStockAdjustment_BelowReserved(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).
+		WithArgs(1, 2).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT quantity, reserved`)).
+		WithArgs(1, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reserved"}).AddRow(10, 8))
+	mock.ExpectRollback()
+
+	_, err := CreateStockAdjustment(context.Background(), db, 1, model.StockAdjustmentReq{
+		ProductID:     2,
+		QuantityDelta: -5,
+		ReasonCode:    "damaged",
+	}, "user:7")
+	if err == nil || err.Error() != "quantity_below_reserved" {
+		t.Fatalf("expected quantity_below_reserved, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
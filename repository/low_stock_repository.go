@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"database/sql"
+	"time"
+
+	"order-service-sample/model"
+
+	"github.com/lib/pq"
+)
+
+// SetReorderPoint sets (or clears, when reorderPoint is nil) the reorder point of a
+// product in a warehouse. A missing stock row is created with zero quantity.
+func SetReorderPoint(db *sql.DB, warehouseID, productID int, reorderPoint *int) error {
+	var value sql.NullInt64
+	if reorderPoint != nil {
+		value = sql.NullInt64{Int64: int64(*reorderPoint), Valid: true}
+	}
+
+	_, err := db.Exec(`
+		INSERT INTO warehouse_stock (warehouse_id, product_id, quantity, reserved, reorder_point)
+		VALUES ($1, $2, 0, 0, $3)
+		ON CONFLICT (warehouse_id, product_id)
+		DO UPDATE SET reorder_point = EXCLUDED.reorder_point, low_stock_alerted = FALSE
+	`, warehouseID, productID, value)
+	return err
+}
+
+// GetLowStockBreaches lists every stock row whose available quantity is at or below
+// its reorder point. warehouseID 0 means all warehouses.
+func GetLowStockBreaches(db *sql.DB, warehouseID int) ([]model.LowStockAlert, error) {
+	rows, err := db.Query(`
+		SELECT ws.warehouse_id, w.name, ws.product_id, p.name, ws.quantity, ws.reserved, ws.reorder_point
+		FROM warehouse_stock ws
+		JOIN warehouses w ON w.id = ws.warehouse_id
+		JOIN products p ON p.id = ws.product_id
+		WHERE ws.reorder_point IS NOT NULL
+		AND ws.quantity - ws.reserved <= ws.reorder_point
+		AND ($1 = 0 OR ws.warehouse_id = $1)
+		ORDER BY ws.warehouse_id, ws.product_id
+	`, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanLowStockAlerts(rows)
+}
+
+// LatestStockMovementID returns the id of the newest ledger entry, 0 when empty
+func LatestStockMovementID(db *sql.DB) (int64, error) {
+	var id int64
+	err := db.QueryRow(`SELECT COALESCE(MAX(id), 0) FROM stock_movements`).Scan(&id)
+	return id, err
+}
+
+// GetProductsMovedSince returns the products with ledger entries newer than afterID
+// and the id of the newest entry seen. Ids are assigned at insert, so a transaction
+// that commits late can add a lower id after the cursor passed it; entries created
+// within lag are therefore scanned again on every call.
+func GetProductsMovedSince(db *sql.DB, afterID int64, lag time.Duration) ([]int, int64, error) {
+	rows, err := db.Query(`
+		SELECT product_id, MAX(id)
+		FROM stock_movements
+		WHERE id > $1
+		OR created_at >= LOCALTIMESTAMP - make_interval(secs => $2)
+		GROUP BY product_id
+	`, afterID, lag.Seconds())
+	if err != nil {
+		return nil, afterID, err
+	}
+	defer rows.Close()
+
+	var productIDs []int
+	lastID := afterID
+	for rows.Next() {
+		var productID int
+		var maxID int64
+		if err := rows.Scan(&productID, &maxID); err != nil {
+			return nil, afterID, err
+		}
+		productIDs = append(productIDs, productID)
+		if maxID > lastID {
+			lastID = maxID
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, afterID, err
+	}
+	return productIDs, lastID, nil
+}
+
+// MarkLowStockTransitions flips low_stock_alerted for rows that crossed their reorder
+// point and returns the rows that newly fell to or below it, so every breach is
+// alerted once until the stock recovers. productIDs nil evaluates every row.
+func MarkLowStockTransitions(db *sql.DB, productIDs []int) ([]model.LowStockAlert, error) {
+	rows, err := db.Query(`
+		UPDATE warehouse_stock ws
+		SET low_stock_alerted = (ws.quantity - ws.reserved <= ws.reorder_point)
+		FROM warehouses w, products p
+		WHERE w.id = ws.warehouse_id
+		AND p.id = ws.product_id
+		AND ws.reorder_point IS NOT NULL
+		AND ws.low_stock_alerted <> (ws.quantity - ws.reserved <= ws.reorder_point)
+		AND ($1 OR ws.product_id = ANY($2))
+		RETURNING ws.warehouse_id, w.name, ws.product_id, p.name, ws.quantity, ws.reserved, ws.reorder_point
+	`, productIDs == nil, pq.Array(productIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	changed, err := scanLowStockAlerts(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	// rows that recovered are only reset, not alerted
+	breaches := []model.LowStockAlert{}
+	for _, a := range changed {
+		if a.Available <= a.ReorderPoint {
+			breaches = append(breaches, a)
+		}
+	}
+	return breaches, nil
+}
+
+func scanLowStockAlerts(rows *sql.Rows) ([]model.LowStockAlert, error) {
+	alerts := []model.LowStockAlert{}
+	for rows.Next() {
+		var a model.LowStockAlert
+		if err := rows.Scan(&a.WarehouseID, &a.WarehouseName, &a.ProductID, &a.ProductName,
+			&a.Quantity, &a.Reserved, &a.ReorderPoint); err != nil {
+			return nil, err
+		}
+		a.Available = a.Quantity - a.Reserved
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
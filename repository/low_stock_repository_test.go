@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var lowStockColumns = []string{"warehouse_id", "warehouse_name", "product_id", "product_name", "quantity", "reserved", "reorder_point"}
+
+func TestSetReorderPoint_Clear(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`INSERT INTO warehouse_stock .* ON CONFLICT \(warehouse_id, product_id\) DO UPDATE SET reorder_point`).
+		WithArgs(1, 2, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := SetReorderPoint(db, 1, 2, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestGetLowStockBreaches(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`ws.quantity - ws.reserved <= ws.reorder_point`).
+		WithArgs(0).
+		WillReturnRows(sqlmock.NewRows(lowStockColumns).AddRow(1, "Jakarta", 2, "Mouse", 6, 3, 5))
+
+	alerts, err := GetLowStockBreaches(db, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].Available != 3 {
+		t.Fatalf("unexpected alerts: %+v", alerts)
+	}
+}
+
+func TestGetProductsMovedSince(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM stock_movements WHERE id > \$1 OR created_at >= LOCALTIMESTAMP - make_interval\(secs => \$2\) GROUP BY product_id`).
+		WithArgs(int64(10), float64(300)).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "max"}).AddRow(2, 14).AddRow(5, 12))
+
+	productIDs, lastID, err := GetProductsMovedSince(db, 10, 5*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(productIDs) != 2 || lastID != 14 {
+		t.Fatalf("unexpected result: %v %d", productIDs, lastID)
+	}
+}
+
+func TestMarkLowStockTransitions_OnlyReturnsNewBreaches(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	// product 2 fell below its reorder point, product 3 recovered
+	mock.ExpectQuery(`UPDATE warehouse_stock ws SET low_stock_alerted`).
+		WithArgs(false, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows(lowStockColumns).
+			AddRow(1, "Jakarta", 2, "Mouse", 4, 0, 5).
+			AddRow(1, "Jakarta", 3, "Keyboard", 20, 0, 5))
+
+	alerts, err := MarkLowStockTransitions(db, []int{2, 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(alerts) != 1 || alerts[0].ProductID != 2 {
+		t.Fatalf("expected only product 2, got %+v", alerts)
+	}
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+)
+
+// OIDCIdentity is the identity an IdP vouched for in a validated ID token
+type OIDCIdentity struct {
+	Issuer        string
+	Subject       string
+	Email         string
+	EmailVerified bool
+}
+
+// LinkOrProvisionOIDCUser returns the user of an OIDC identity. An identity seen
+// before maps to its linked user; otherwise it is linked to the user with the same
+// email (only when the IdP verified that email) or a new user is created with an
+// unusable password hash and defaultRole.
+func LinkOrProvisionOIDCUser(ctx context.Context, db *sql.DB, id OIDCIdentity, passwordHash, defaultRole string) (User, error) {
+	var u User
+	// sama seperti register, email disimpan lowercase
+	email := strings.ToLower(strings.TrimSpace(id.Email))
+
+	err := RunInTx(ctx, db, func(tx *sql.Tx) error {
+		err := tx.QueryRow(`
+			SELECT u.id, u.email, COALESCE(u.phone, ''), u.password_hash, u.role
+			FROM user_identities i
+			JOIN users u ON u.id = i.user_id
+			WHERE i.issuer = $1 AND i.subject = $2
+		`, id.Issuer, id.Subject).Scan(&u.ID, &u.Email, &u.Phone, &u.PasswordHash, &u.Role)
+		if err == nil {
+			return nil
+		}
+		if err != sql.ErrNoRows {
+			return err
+		}
+
+		if email == "" {
+			return errors.New("oidc_email_missing")
+		}
+
+		err = tx.QueryRow(`
+			SELECT id, email, COALESCE(phone, ''), password_hash, role
+			FROM users
+			WHERE email = $1
+			FOR UPDATE
+		`, email).Scan(&u.ID, &u.Email, &u.Phone, &u.PasswordHash, &u.Role)
+		switch {
+		case err == sql.ErrNoRows:
+			err = tx.QueryRow(`
+				INSERT INTO users (email, password_hash, role, email_verified_at)
+				VALUES ($1, $2, $3, CASE WHEN $4 THEN NOW() END)
+				RETURNING id, email, password_hash, role
+			`, email, passwordHash, defaultRole, id.EmailVerified).Scan(&u.ID, &u.Email, &u.PasswordHash, &u.Role)
+			if err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		case !id.EmailVerified:
+			// tanpa email terverifikasi dari IdP, akun yang sudah ada tidak boleh diambil alih
+			return errors.New("oidc_email_unverified")
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO user_identities (user_id, issuer, subject)
+			VALUES ($1, $2, $3)
+		`, u.ID, id.Issuer, id.Subject)
+		return err
+	})
+	return u, err
+}
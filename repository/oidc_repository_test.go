@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var oidcUserCols = []string{"id", "email", "phone", "password_hash", "role"}
+
+func staffIdentity(verified bool) OIDCIdentity {
+	return OIDCIdentity{Issuer: "https://idp.example", Subject: "staff-42", Email: "sari@corp.example", EmailVerified: verified}
+}
+
+func TestLinkOrProvisionOIDCUser_KnownIdentity(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM user_identities i\s+JOIN users u ON u.id = i.user_id\s+WHERE i.issuer = \$1 AND i.subject = \$2`).
+		WithArgs("https://idp.example", "staff-42").
+		WillReturnRows(sqlmock.NewRows(oidcUserCols).AddRow(4, "sari@corp.example", "", "hash", "warehouse_operator"))
+	mock.ExpectCommit()
+
+	u, err := LinkOrProvisionOIDCUser(context.Background(), db, staffIdentity(true), "unused", "customer")
+	if err != nil || u.ID != 4 || u.Role != "warehouse_operator" {
+		t.Fatalf("unexpected result %+v, %v", u, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLinkOrProvisionOIDCUser_LinksVerifiedEmail(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM user_identities`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`FROM users\s+WHERE email = \$1\s+FOR UPDATE`).
+		WithArgs("sari@corp.example").
+		WillReturnRows(sqlmock.NewRows(oidcUserCols).AddRow(7, "sari@corp.example", "0812", "hash", "admin"))
+	mock.ExpectExec(`INSERT INTO user_identities \(user_id, issuer, subject\)`).
+		WithArgs(7, "https://idp.example", "staff-42").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	u, err := LinkOrProvisionOIDCUser(context.Background(), db, staffIdentity(true), "unused", "customer")
+	if err != nil || u.ID != 7 {
+		t.Fatalf("unexpected result %+v, %v", u, err)
+	}
+}
+
+func TestLinkOrProvisionOIDCUser_NormalizesEmail(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM user_identities`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`FROM users\s+WHERE email = \$1\s+FOR UPDATE`).
+		WithArgs("sari@corp.example").
+		WillReturnRows(sqlmock.NewRows(oidcUserCols).AddRow(7, "sari@corp.example", "0812", "hash", "admin"))
+	mock.ExpectExec(`INSERT INTO user_identities`).
+		WithArgs(7, "https://idp.example", "staff-42").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	id := staffIdentity(true)
+	id.Email = "  Sari@Corp.Example "
+	u, err := LinkOrProvisionOIDCUser(context.Background(), db, id, "unused", "customer")
+	if err != nil || u.ID != 7 {
+		t.Fatalf("unexpected result %+v, %v", u, err)
+	}
+}
+
+func TestLinkOrProvisionOIDCUser_UnverifiedEmailIsNotLinked(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM user_identities`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`FROM users`).
+		WillReturnRows(sqlmock.NewRows(oidcUserCols).AddRow(7, "sari@corp.example", "0812", "hash", "admin"))
+	mock.ExpectRollback()
+
+	_, err := LinkOrProvisionOIDCUser(context.Background(), db, staffIdentity(false), "unused", "customer")
+	if err == nil || err.Error() != "oidc_email_unverified" {
+		t.Fatalf("expected oidc_email_unverified, got %v", err)
+	}
+}
+
+func TestLinkOrProvisionOIDCUser_Provisions(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM user_identities`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`FROM users`).WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`INSERT INTO users \(email, password_hash, role, email_verified_at\)`).
+		WithArgs("sari@corp.example", "random-hash", "customer", true).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "password_hash", "role"}).AddRow(9, "sari@corp.example", "random-hash", "customer"))
+	mock.ExpectExec(`INSERT INTO user_identities`).
+		WithArgs(9, "https://idp.example", "staff-42").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	u, err := LinkOrProvisionOIDCUser(context.Background(), db, staffIdentity(true), "random-hash", "customer")
+	if err != nil || u.ID != 9 || u.Role != "customer" {
+		t.Fatalf("unexpected result %+v, %v", u, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
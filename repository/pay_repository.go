@@ -3,9 +3,12 @@ package repository
 import (
 	"database/sql"
 	"errors"
+
+	"order-service-sample/model"
 )
 
 type ReservationItem struct {
+	OrderID     int
 	ProductID   int
 	WarehouseID int
 	Qty         int
@@ -49,7 +52,7 @@ func GetOrderReservationItems(db *sql.DB, orderID int) ([]ReservationItem, error
 	items := []ReservationItem{}
 
 	for rows.Next() {
-		var it ReservationItem
+		it := ReservationItem{OrderID: orderID}
 		err := rows.Scan(&it.ProductID, &it.WarehouseID, &it.Qty)
 		if err != nil {
 			return nil, err
@@ -60,7 +63,8 @@ func GetOrderReservationItems(db *sql.DB, orderID int) ([]ReservationItem, error
 	return items, nil
 }
 
-func ApplyStockPayment(tx *sql.Tx, items []ReservationItem) error {
+// ApplyStockPayment turns reserved stock into a sale and records it in the stock ledger
+func ApplyStockPayment(tx *sql.Tx, items []ReservationItem, actor string) error {
 	for _, it := range items {
 		_, err := tx.Exec(`
 			UPDATE warehouse_stock
@@ -73,6 +77,19 @@ func ApplyStockPayment(tx *sql.Tx, items []ReservationItem) error {
 		if err != nil {
 			return err
 		}
+
+		err = RecordStockMovement(tx, model.StockMovement{
+			WarehouseID:   it.WarehouseID,
+			ProductID:     it.ProductID,
+			Type:          MovementSale,
+			QuantityDelta: -it.Qty,
+			ReservedDelta: -it.Qty,
+			OrderID:       it.OrderID,
+			Actor:         actor,
+		})
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
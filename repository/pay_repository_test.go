@@ -30,7 +30,7 @@ func TestGetOrderReservationItems_Success(t *testing.T) {
 	if len(items) != 2 {
 		t.Fatalf("expected 2 items, got %d", len(items))
 	}
-	if items[0].ProductID != 1 || items[0].WarehouseID != 1 || items[0].Qty != 2 {
+	if items[0].OrderID != 7 || items[0].ProductID != 1 || items[0].WarehouseID != 1 || items[0].Qty != 2 {
 		t.Fatalf("unexpected first item: %+v", items[0])
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
@@ -53,6 +53,9 @@ func TestApplyStockPayment_Success(t *testing.T) {
 		`)).
 		WithArgs(2, 1, 1).
 		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 1, MovementSale, -2, -2, int64(7), nil, "user:3", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
 	mock.ExpectCommit()
 
 	tx, err := db.Begin()
@@ -61,9 +64,9 @@ func TestApplyStockPayment_Success(t *testing.T) {
 	}
 
 	items := []ReservationItem{
-		{ProductID: 1, WarehouseID: 1, Qty: 2},
+		{OrderID: 7, ProductID: 1, WarehouseID: 1, Qty: 2},
 	}
-	if err := ApplyStockPayment(tx, items); err != nil {
+	if err := ApplyStockPayment(tx, items, "user:3"); err != nil {
 		t.Fatalf("apply stock payment err: %v", err)
 	}
 	if err := tx.Commit(); err != nil {
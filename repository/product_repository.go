@@ -7,7 +7,7 @@ import (
 
 func GetAllProducts(db *sql.DB) ([]model.ProductResp, error) {
 	rows, err := db.Query(`
-        SELECT id, name, stock, price, description
+        SELECT id, name, stock, price, description, weight_grams, length_mm, width_mm, height_mm
         FROM products
         ORDER BY id
     `)
@@ -20,7 +20,7 @@ func GetAllProducts(db *sql.DB) ([]model.ProductResp, error) {
 
 	for rows.Next() {
 		var p model.ProductResp
-		if err := rows.Scan(&p.ID, &p.Name, &p.Stock, &p.Price, &p.Description); err != nil {
+		if err := rows.Scan(&p.ID, &p.Name, &p.Stock, &p.Price, &p.Description, &p.WeightGrams, &p.LengthMM, &p.WidthMM, &p.HeightMM); err != nil {
 			return nil, err
 		}
 		products = append(products, p)
@@ -13,11 +13,11 @@ func TestGetAllProducts_SuccessSingleRow(t *testing.T) {
 	defer db.Close()
 
 	rows := sqlmock.NewRows([]string{
-		"id", "name", "stock", "price", "description",
-	}).AddRow(1, "Product A", 10, 5000, "Desc A")
+		"id", "name", "stock", "price", "description", "weight_grams", "length_mm", "width_mm", "height_mm",
+	}).AddRow(1, "Product A", 10, 5000, "Desc A", 150, 120, 70, 40)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, stock, price, description 
+		SELECT id, name, stock, price, description, weight_grams, length_mm, width_mm, height_mm
 		FROM products 
 		ORDER BY id
 	`)).WillReturnRows(rows)
@@ -40,13 +40,13 @@ func TestGetAllProducts_SuccessMultipleRows(t *testing.T) {
 	defer db.Close()
 
 	rows := sqlmock.NewRows([]string{
-		"id", "name", "stock", "price", "description",
+		"id", "name", "stock", "price", "description", "weight_grams", "length_mm", "width_mm", "height_mm",
 	}).
-		AddRow(1, "Product A", 10, 5000, "Desc A").
-		AddRow(2, "Product B", 3, 9999, "Desc B")
+		AddRow(1, "Product A", 10, 5000, "Desc A", 150, 120, 70, 40).
+		AddRow(2, "Product B", 3, 9999, "Desc B", 0, 0, 0, 0)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, stock, price, description 
+		SELECT id, name, stock, price, description, weight_grams, length_mm, width_mm, height_mm
 		FROM products 
 		ORDER BY id
 	`)).WillReturnRows(rows)
@@ -69,11 +69,11 @@ func TestGetAllProducts_EmptyResult(t *testing.T) {
 	defer db.Close()
 
 	rows := sqlmock.NewRows([]string{
-		"id", "name", "stock", "price", "description",
+		"id", "name", "stock", "price", "description", "weight_grams", "length_mm", "width_mm", "height_mm",
 	}) // no AddRow → empty
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, stock, price, description 
+		SELECT id, name, stock, price, description, weight_grams, length_mm, width_mm, height_mm
 		FROM products 
 		ORDER BY id
 	`)).WillReturnRows(rows)
@@ -93,7 +93,7 @@ func TestGetAllProducts_QueryError(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, stock, price, description 
+		SELECT id, name, stock, price, description, weight_grams, length_mm, width_mm, height_mm
 		FROM products 
 		ORDERORDER id
 	`)).WillReturnError(errors.New("db failure"))
@@ -110,11 +110,11 @@ func TestGetAllProducts_ScanError(t *testing.T) {
 
 	// returning invalid string for stock (expected int)
 	rows := sqlmock.NewRows([]string{
-		"id", "name", "stock", "price", "description",
-	}).AddRow(1, "Product A", "NOT_INT", 5000, "Desc A")
+		"id", "name", "stock", "price", "description", "weight_grams", "length_mm", "width_mm", "height_mm",
+	}).AddRow(1, "Product A", "NOT_INT", 5000, "Desc A", 0, 0, 0, 0)
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, stock, price, description 
+		SELECT id, name, stock, price, description, weight_grams, length_mm, width_mm, height_mm
 		FROM products 
 		ORDER BY id
 	`)).WillReturnRows(rows)
@@ -130,12 +130,12 @@ func TestGetAllProducts_RowsNextError(t *testing.T) {
 	defer db.Close()
 
 	rows := sqlmock.NewRows([]string{
-		"id", "name", "stock", "price", "description",
-	}).AddRow(1, "Prod X", 10, 1000, "Desc X").
+		"id", "name", "stock", "price", "description", "weight_grams", "length_mm", "width_mm", "height_mm",
+	}).AddRow(1, "Prod X", 10, 1000, "Desc X", 0, 0, 0, 0).
 		RowError(0, errors.New("row next error"))
 
 	mock.ExpectQuery(regexp.QuoteMeta(`
-		SELECT id, name, stock, price, description 
+		SELECT id, name, stock, price, description, weight_grams, length_mm, width_mm, height_mm
 		FROM products 
 		ORDER BY id
 	`)).WillReturnRows(rows)
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"database/sql"
+
+	"order-service-sample/model"
+)
+
+// GetRebalancePositions returns, for every product sold in the last lookbackDays,
+// its available stock in each active warehouse and in inactive warehouses that
+// still hold some of it
+func GetRebalancePositions(db *sql.DB, lookbackDays int) ([]model.RebalancePosition, error) {
+	rows, err := db.Query(`
+		WITH sales AS (
+			SELECT product_id, -SUM(quantity_delta) AS sold
+			FROM stock_movements
+			WHERE movement_type = 'sale'
+			AND created_at >= NOW() - make_interval(days => $1)
+			GROUP BY product_id
+		)
+		SELECT w.id, w.active, s.product_id, GREATEST(COALESCE(ws.quantity - ws.reserved, 0), 0), s.sold
+		FROM sales s
+		CROSS JOIN warehouses w
+		LEFT JOIN warehouse_stock ws ON ws.warehouse_id = w.id AND ws.product_id = s.product_id
+		WHERE w.active OR COALESCE(ws.quantity - ws.reserved, 0) > 0
+		ORDER BY s.product_id, w.id
+	`, lookbackDays)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	positions := []model.RebalancePosition{}
+	for rows.Next() {
+		var p model.RebalancePosition
+		if err := rows.Scan(&p.WarehouseID, &p.Active, &p.ProductID, &p.Available, &p.ProductSold); err != nil {
+			return nil, err
+		}
+		positions = append(positions, p)
+	}
+	return positions, rows.Err()
+}
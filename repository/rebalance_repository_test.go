@@ -0,0 +1,26 @@
+package repository
+
+import (
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestGetRebalancePositions(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`WITH sales AS .* movement_type = 'sale'`).
+		WithArgs(30).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "active", "product_id", "available", "sold"}).
+			AddRow(1, true, 7, 200, 300).
+			AddRow(2, false, 7, 15, 300))
+
+	positions, err := GetRebalancePositions(db, 30)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(positions) != 2 || positions[1].Active || positions[1].Available != 15 {
+		t.Fatalf("unexpected positions: %+v", positions)
+	}
+}
@@ -0,0 +1,179 @@
+package repository
+
+import (
+	"database/sql"
+	"fmt"
+
+	"order-service-sample/model"
+)
+
+// Drift kinds reported by the reconciliation job
+const (
+	DriftReserved          = "reserved"            // warehouse_stock.reserved != SUM(reservations.quantity)
+	DriftReservedOverStock = "reserved_over_stock" // reserved > quantity, needs a manual fix
+	DriftProductStock      = "product_stock"       // products.stock != SUM(quantity - reserved)
+	DriftInTransit         = "in_transit"          // warehouse_stock.in_transit != SUM(dispatched transfer lines)
+)
+
+// GetReservedDrift compares warehouse_stock.reserved with the live reservations rows
+func GetReservedDrift(db *sql.DB) ([]model.StockDrift, error) {
+	rows, err := db.Query(`
+		WITH live AS (
+			SELECT warehouse_id, product_id, SUM(quantity) AS reserved
+			FROM reservations
+			GROUP BY warehouse_id, product_id
+		)
+		SELECT COALESCE(ws.warehouse_id, live.warehouse_id),
+		       COALESCE(ws.product_id, live.product_id),
+		       COALESCE(ws.reserved, 0),
+		       COALESCE(live.reserved, 0)
+		FROM warehouse_stock ws
+		FULL OUTER JOIN live ON live.warehouse_id = ws.warehouse_id AND live.product_id = ws.product_id
+		WHERE COALESCE(ws.reserved, 0) <> COALESCE(live.reserved, 0)
+		ORDER BY 1, 2
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanDrifts(rows, DriftReserved, true)
+}
+
+// GetInTransitDrift compares warehouse_stock.in_transit with the lines of the
+// transfers still dispatched to the warehouse
+func GetInTransitDrift(db *sql.DB) ([]model.StockDrift, error) {
+	rows, err := db.Query(`
+		WITH transit AS (
+			SELECT t.to_warehouse_id AS warehouse_id, ti.product_id, SUM(ti.quantity) AS in_transit
+			FROM transfers t
+			JOIN transfer_items ti ON ti.transfer_id = t.id
+			WHERE t.status = 'dispatched'
+			GROUP BY t.to_warehouse_id, ti.product_id
+		)
+		SELECT COALESCE(ws.warehouse_id, transit.warehouse_id),
+		       COALESCE(ws.product_id, transit.product_id),
+		       COALESCE(ws.in_transit, 0),
+		       COALESCE(transit.in_transit, 0)
+		FROM warehouse_stock ws
+		FULL OUTER JOIN transit ON transit.warehouse_id = ws.warehouse_id AND transit.product_id = ws.product_id
+		WHERE COALESCE(ws.in_transit, 0) <> COALESCE(transit.in_transit, 0)
+		ORDER BY 1, 2
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanDrifts(rows, DriftInTransit, true)
+}
+
+// RepairInTransit recomputes in_transit of one stock row from the dispatched transfers
+func RepairInTransit(tx *sql.Tx, warehouseID, productID int) error {
+	if err := ensureStockRowExists(tx, warehouseID, productID); err != nil {
+		return err
+	}
+	_, err := tx.Exec(`
+		UPDATE warehouse_stock
+		SET in_transit = (
+			SELECT COALESCE(SUM(ti.quantity), 0)
+			FROM transfers t
+			JOIN transfer_items ti ON ti.transfer_id = t.id
+			WHERE t.status = 'dispatched' AND t.to_warehouse_id = $1 AND ti.product_id = $2
+		), updated_at = NOW()
+		WHERE warehouse_id = $1 AND product_id = $2
+	`, warehouseID, productID)
+	return err
+}
+
+// GetReservedOverStock lists rows reserving more than they hold
+func GetReservedOverStock(db *sql.DB) ([]model.StockDrift, error) {
+	rows, err := db.Query(`
+		SELECT warehouse_id, product_id, reserved, quantity
+		FROM warehouse_stock
+		WHERE reserved > quantity
+		ORDER BY warehouse_id, product_id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanDrifts(rows, DriftReservedOverStock, true)
+}
+
+// GetProductStockDrift compares products.stock with the available stock of all warehouses
+func GetProductStockDrift(db *sql.DB) ([]model.StockDrift, error) {
+	rows, err := db.Query(`
+		SELECT p.id, COALESCE(p.stock, 0), COALESCE(SUM(ws.quantity - ws.reserved), 0)
+		FROM products p
+		LEFT JOIN warehouse_stock ws ON ws.product_id = p.id
+		GROUP BY p.id, p.stock
+		HAVING COALESCE(p.stock, 0) <> COALESCE(SUM(ws.quantity - ws.reserved), 0)
+		ORDER BY p.id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	return scanDrifts(rows, DriftProductStock, false)
+}
+
+func scanDrifts(rows *sql.Rows, kind string, withWarehouse bool) ([]model.StockDrift, error) {
+	defer rows.Close()
+
+	drifts := []model.StockDrift{}
+	for rows.Next() {
+		d := model.StockDrift{Kind: kind}
+		var err error
+		if withWarehouse {
+			err = rows.Scan(&d.WarehouseID, &d.ProductID, &d.Actual, &d.Expected)
+		} else {
+			err = rows.Scan(&d.ProductID, &d.Actual, &d.Expected)
+		}
+		if err != nil {
+			return nil, err
+		}
+		drifts = append(drifts, d)
+	}
+	return drifts, rows.Err()
+}
+
+// RepairReserved recomputes reserved of one stock row from the live reservations
+// inside tx and records the correction in the stock ledger. It returns the old
+// and new reserved values.
+func RepairReserved(tx *sql.Tx, warehouseID, productID int, actor string) (int, int, error) {
+	if err := ensureStockRowExists(tx, warehouseID, productID); err != nil {
+		return 0, 0, err
+	}
+
+	var before, after int
+	err := tx.QueryRow(`
+		WITH prev AS (
+			SELECT reserved
+			FROM warehouse_stock
+			WHERE warehouse_id = $1 AND product_id = $2
+			FOR UPDATE
+		)
+		UPDATE warehouse_stock ws
+		SET reserved = (
+			SELECT COALESCE(SUM(quantity), 0)
+			FROM reservations
+			WHERE warehouse_id = $1 AND product_id = $2
+		), updated_at = NOW()
+		FROM prev
+		WHERE ws.warehouse_id = $1 AND ws.product_id = $2
+		RETURNING prev.reserved, ws.reserved
+	`, warehouseID, productID).Scan(&before, &after)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if before != after {
+		err = RecordStockMovement(tx, model.StockMovement{
+			WarehouseID:   warehouseID,
+			ProductID:     productID,
+			Type:          MovementAdjustment,
+			ReservedDelta: after - before,
+			Actor:         actor,
+			Note:          fmt.Sprintf("reconciliation: reserved %d -> %d", before, after),
+		})
+		if err != nil {
+			return before, after, err
+		}
+	}
+	return before, after, nil
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// CreateRefreshToken stores the hash of a new refresh token for a user
+func CreateRefreshToken(db *sql.DB, userID int, tokenHash string, expiresAt time.Time) error {
+	_, err := db.Exec(`
+		INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, tokenHash, expiresAt)
+	return err
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one and returns its user.
+// Each refresh token works once: presenting one that was already rotated means it
+// leaked, so every token of that user is revoked and "refresh_token_reused" is returned.
+func RotateRefreshToken(ctx context.Context, db *sql.DB, oldHash, newHash string, expiresAt time.Time) (int, error) {
+	var userID int
+	var reused bool
+
+	err := RunInTx(ctx, db, func(tx *sql.Tx) error {
+		reused = false
+
+		var id int
+		var tokenExpiresAt time.Time
+		var revoked, rotated bool
+		err := tx.QueryRow(`
+			SELECT id, user_id, expires_at, revoked_at IS NOT NULL, replaced_by IS NOT NULL
+			FROM refresh_tokens
+			WHERE token_hash = $1
+			FOR UPDATE
+		`, oldHash).Scan(&id, &userID, &tokenExpiresAt, &revoked, &rotated)
+		if err == sql.ErrNoRows {
+			return errors.New("refresh_token_invalid")
+		}
+		if err != nil {
+			return err
+		}
+
+		if rotated {
+			// commit pencabutan semua token user, error dikembalikan setelah transaksi selesai
+			reused = true
+			_, err := tx.Exec(`
+				UPDATE refresh_tokens SET revoked_at = NOW()
+				WHERE user_id = $1 AND revoked_at IS NULL
+			`, userID)
+			return err
+		}
+		if revoked || !tokenExpiresAt.After(time.Now()) {
+			return errors.New("refresh_token_invalid")
+		}
+
+		var newID int
+		if err := tx.QueryRow(`
+			INSERT INTO refresh_tokens (user_id, token_hash, expires_at)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`, userID, newHash, expiresAt).Scan(&newID); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			UPDATE refresh_tokens SET revoked_at = NOW(), replaced_by = $2
+			WHERE id = $1
+		`, id, newID)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if reused {
+		return 0, errors.New("refresh_token_reused")
+	}
+	return userID, nil
+}
+
+// RevokeRefreshToken revokes a single refresh token of a user (logout)
+func RevokeRefreshToken(db *sql.DB, userID int, tokenHash string) error {
+	_, err := db.Exec(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND token_hash = $2 AND revoked_at IS NULL
+	`, userID, tokenHash)
+	return err
+}
+
+// RevokeUserRefreshTokens revokes every refresh token of a user, e.g. after a password reset
+func RevokeUserRefreshTokens(db *sql.DB, userID int) error {
+	_, err := db.Exec(`
+		UPDATE refresh_tokens SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`, userID)
+	return err
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var refreshTokenCols = []string{"id", "user_id", "expires_at", "revoked", "rotated"}
+
+func TestRotateRefreshToken_Success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	exp := time.Now().Add(time.Hour)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, user_id, expires_at, revoked_at IS NOT NULL, replaced_by IS NOT NULL\s+FROM refresh_tokens\s+WHERE token_hash = \$1\s+FOR UPDATE`).
+		WithArgs("old").
+		WillReturnRows(sqlmock.NewRows(refreshTokenCols).AddRow(3, 7, exp, false, false))
+	mock.ExpectQuery(`INSERT INTO refresh_tokens`).
+		WithArgs(7, "new", exp).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(4))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = NOW\(\), replaced_by = \$2`).
+		WithArgs(3, 4).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	userID, err := RotateRefreshToken(context.Background(), db, "old", "new", exp)
+	if err != nil || userID != 7 {
+		t.Fatalf("expected user 7, got %d, %v", userID, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotateRefreshToken_ReuseRevokesAll(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM refresh_tokens`).
+		WithArgs("old").
+		WillReturnRows(sqlmock.NewRows(refreshTokenCols).AddRow(3, 7, time.Now().Add(time.Hour), true, true))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = NOW\(\)\s+WHERE user_id = \$1 AND revoked_at IS NULL`).
+		WithArgs(7).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// pencabutan harus di-commit, bukan di-rollback
+	mock.ExpectCommit()
+
+	_, err := RotateRefreshToken(context.Background(), db, "old", "new", time.Now().Add(time.Hour))
+	if err == nil || err.Error() != "refresh_token_reused" {
+		t.Fatalf("expected refresh_token_reused, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRotateRefreshToken_ExpiredOrLoggedOut(t *testing.T) {
+	cases := map[string][]driver.Value{
+		"expired":    {3, 7, time.Now().Add(-time.Minute), false, false},
+		"logged out": {3, 7, time.Now().Add(time.Hour), true, false},
+	}
+	for name, row := range cases {
+		db, mock, _ := sqlmock.New()
+
+		mock.ExpectBegin()
+		mock.ExpectQuery(`FROM refresh_tokens`).
+			WillReturnRows(sqlmock.NewRows(refreshTokenCols).AddRow(row...))
+		mock.ExpectRollback()
+
+		_, err := RotateRefreshToken(context.Background(), db, "old", "new", time.Now().Add(time.Hour))
+		if err == nil || err.Error() != "refresh_token_invalid" {
+			t.Errorf("%s: expected refresh_token_invalid, got %v", name, err)
+		}
+		db.Close()
+	}
+}
+
+func TestRotateRefreshToken_Unknown(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FROM refresh_tokens`).WillReturnRows(sqlmock.NewRows(refreshTokenCols))
+	mock.ExpectRollback()
+
+	_, err := RotateRefreshToken(context.Background(), db, "nope", "new", time.Now().Add(time.Hour))
+	if err == nil || err.Error() != "refresh_token_invalid" {
+		t.Fatalf("expected refresh_token_invalid, got %v", err)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"order-service-sample/helper"
 	"order-service-sample/model"
 )
 
@@ -13,19 +14,21 @@ type User struct {
 	Email        string
 	Phone        string
 	PasswordHash string
+	Role         string
 }
 
 func GetUserByEmail(db *sql.DB, email string) (User, error) {
 	var u User
-	row := db.QueryRow(`SELECT id, email, phone, password_hash FROM users WHERE email = $1`, email)
-	err := row.Scan(&u.ID, &u.Email, &u.Phone, &u.PasswordHash)
+	// phone bisa NULL untuk user dari OIDC
+	row := db.QueryRow(`SELECT id, email, COALESCE(phone, ''), password_hash, role FROM users WHERE email = $1`, email)
+	err := row.Scan(&u.ID, &u.Email, &u.Phone, &u.PasswordHash, &u.Role)
 	return u, err
 }
 
 func GetUserByPhone(db *sql.DB, phone string) (User, error) {
 	var u User
-	row := db.QueryRow(`SELECT id, email, phone, password_hash FROM users WHERE phone = $1`, phone)
-	err := row.Scan(&u.ID, &u.Email, &u.Phone, &u.PasswordHash)
+	row := db.QueryRow(`SELECT id, email, phone, password_hash, role FROM users WHERE phone = $1`, phone)
+	err := row.Scan(&u.ID, &u.Email, &u.Phone, &u.PasswordHash, &u.Role)
 	return u, err
 }
 
@@ -63,17 +66,47 @@ func ReleaseReservationByOrderID(db *sql.DB, orderID int) error {
 		items = append(items, item)
 	}
 
-	// Step 2: Update stok (kurangi reserved)
+	// Step 2: Update stok (kurangi reserved), ledger mencatat selisih yang benar-benar terjadi
 	for _, item := range items {
-		_, err := tx.Exec(`
-			UPDATE warehouse_stock
-			SET reserved = GREATEST(reserved - $1, 0)
-			WHERE product_id = $2 AND warehouse_id = $3
-		`, item.Qty, item.ProductID, item.WarehouseID)
+		var before, after int
+		err := tx.QueryRow(`
+			UPDATE warehouse_stock ws
+			SET reserved = GREATEST(ws.reserved - $1, 0)
+			FROM (
+				SELECT reserved FROM warehouse_stock
+				WHERE product_id = $2 AND warehouse_id = $3
+				FOR UPDATE
+			) old
+			WHERE ws.product_id = $2 AND ws.warehouse_id = $3
+			RETURNING old.reserved, ws.reserved
+		`, item.Qty, item.ProductID, item.WarehouseID).Scan(&before, &after)
+		if err == sql.ErrNoRows {
+			log.Printf("[worker] no stock row for product_id=%d in warehouse_id=%d, nothing to release", item.ProductID, item.WarehouseID)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if after-before != -item.Qty {
+			log.Printf("[worker] reserved of product_id=%d in warehouse_id=%d was %d, below the %d reserved by order %d",
+				item.ProductID, item.WarehouseID, before, item.Qty, orderID)
+		}
+		if after == before {
+			continue
+		}
+
+		err = RecordStockMovement(tx, model.StockMovement{
+			WarehouseID:   item.WarehouseID,
+			ProductID:     item.ProductID,
+			Type:          MovementRelease,
+			ReservedDelta: after - before,
+			OrderID:       orderID,
+			Actor:         "system:reservation-expiry",
+		})
 		if err != nil {
 			return err
 		}
-		log.Printf("[worker] released %d reserved stock for product_id=%d in warehouse_id=%d", item.Qty, item.ProductID, item.WarehouseID)
+		log.Printf("[worker] released %d reserved stock for product_id=%d in warehouse_id=%d", before-after, item.ProductID, item.WarehouseID)
 	}
 
 	// Step 3: Hapus data reservation terkait order ini
@@ -90,7 +123,9 @@ func ReleaseReservationByOrderID(db *sql.DB, orderID int) error {
 }
 
 // ReserveStockForOrder reserves stock for an order by finding an active warehouse.
-func ReserveStockForOrder(ctx context.Context, db *sql.DB, orderID int, items []model.CheckoutItem) error {
+// Warehouses in the given region (the shipping destination) are preferred; an
+// empty region keeps the plain warehouse order.
+func ReserveStockForOrder(ctx context.Context, db *sql.DB, orderID int, items []model.CheckoutItem, region string) error {
 	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
@@ -99,7 +134,8 @@ func ReserveStockForOrder(ctx context.Context, db *sql.DB, orderID int, items []
 
 	for _, item := range items {
 
-		// 1. Cari warehouse aktif yang punya stok cukup
+		// 1. Cari warehouse aktif yang punya stok cukup. FOR SHARE menahan DeactivateWarehouse
+		// sampai reservasi ini commit, dan gudang yang baru dinonaktifkan dilewati.
 		var warehouseID int
 		var stock, reserved int
 
@@ -110,9 +146,10 @@ func ReserveStockForOrder(ctx context.Context, db *sql.DB, orderID int, items []
 			WHERE w.active = TRUE
 			AND ws.product_id = $1
 			AND (ws.quantity - ws.reserved) >= $2
-			ORDER BY ws.warehouse_id
+			ORDER BY COALESCE(LOWER(w.region) = $3, FALSE) DESC, ws.warehouse_id
 			LIMIT 1
-		`, item.ProductID, item.Qty).Scan(&warehouseID, &stock, &reserved)
+			FOR SHARE OF w
+		`, item.ProductID, item.Qty, region).Scan(&warehouseID, &stock, &reserved)
 
 		if err == sql.ErrNoRows {
 			return fmt.Errorf("no active warehouse has enough stock for product %d", item.ProductID)
@@ -150,6 +187,19 @@ func ReserveStockForOrder(ctx context.Context, db *sql.DB, orderID int, items []
 			return err
 		}
 
+		// 5. Catat di stock ledger
+		err = RecordStockMovement(tx, model.StockMovement{
+			WarehouseID:   warehouseID,
+			ProductID:     item.ProductID,
+			Type:          MovementReserve,
+			ReservedDelta: item.Qty,
+			OrderID:       orderID,
+			Actor:         helper.ActorFromContext(ctx),
+		})
+		if err != nil {
+			return err
+		}
+
 		log.Printf("[checkout] reserved %d units of product_id=%d in warehouse_id=%d",
 			item.Qty, item.ProductID, warehouseID)
 	}
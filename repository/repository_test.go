@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"testing"
 
+	"order-service-sample/helper"
 	"order-service-sample/model"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -23,11 +24,11 @@ func TestGetUserByEmail_Success(t *testing.T) {
 	defer db.Close()
 
 	rows := sqlmock.NewRows([]string{
-		"id", "email", "phone", "password_hash",
-	}).AddRow(1, "test@example.com", "08123", "hash")
+		"id", "email", "phone", "password_hash", "role",
+	}).AddRow(1, "test@example.com", "08123", "hash", "customer")
 
 	mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT id, email, phone, password_hash FROM users WHERE email = $1`,
+		`SELECT id, email, COALESCE(phone, ''), password_hash, role FROM users WHERE email = $1`,
 	)).WithArgs("test@example.com").WillReturnRows(rows)
 
 	u, err := GetUserByEmail(db, "test@example.com")
@@ -44,7 +45,7 @@ func TestGetUserByEmail_NoRows(t *testing.T) {
 	defer db.Close()
 
 	mock.ExpectQuery(regexp.QuoteMeta(
-		`SELECT id, email, phone, password_hash FROM users WHERE email = $1`,
+		`SELECT id, email, COALESCE(phone, ''), password_hash, role FROM users WHERE email = $1`,
 	)).
 		WithArgs("x@example.com").
 		WillReturnError(sql.ErrNoRows)
@@ -66,10 +67,10 @@ func TestGetUserByPhone_Success(t *testing.T) {
 	defer db.Close()
 
 	rows := sqlmock.NewRows([]string{
-		"id", "email", "phone", "password_hash",
-	}).AddRow(1, "p@example.com", "08123", "hash")
+		"id", "email", "phone", "password_hash", "role",
+	}).AddRow(1, "p@example.com", "08123", "hash", "customer")
 
-	mock.ExpectQuery(`SELECT id, email, phone, password_hash FROM users WHERE phone = \$1`).
+	mock.ExpectQuery(`SELECT id, email, phone, password_hash, role FROM users WHERE phone = \$1`).
 		WithArgs("08123").WillReturnRows(rows)
 
 	u, err := GetUserByPhone(db, "08123")
@@ -85,7 +86,7 @@ func TestGetUserByPhone_NoRows(t *testing.T) {
 	db, mock, _ := sqlmock.New()
 	defer db.Close()
 
-	mock.ExpectQuery(`SELECT id, email, phone, password_hash FROM users WHERE phone = \$1`).
+	mock.ExpectQuery(`SELECT id, email, phone, password_hash, role FROM users WHERE phone = \$1`).
 		WithArgs("000").
 		WillReturnError(sql.ErrNoRows)
 
@@ -121,19 +122,41 @@ func TestReleaseReservationByOrderID_Success(t *testing.T) {
 		WillReturnRows(rows)
 
 	// UPDATE reserved row 1
-	mock.ExpectExec(regexp.QuoteMeta(`
-		UPDATE warehouse_stock
-		SET reserved = GREATEST(reserved - $1, 0)
-		WHERE product_id = $2 AND warehouse_id = $3
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		UPDATE warehouse_stock ws
+		SET reserved = GREATEST(ws.reserved - $1, 0)
+		FROM (
+			SELECT reserved FROM warehouse_stock
+			WHERE product_id = $2 AND warehouse_id = $3
+			FOR UPDATE
+		) old
+		WHERE ws.product_id = $2 AND ws.warehouse_id = $3
+		RETURNING old.reserved, ws.reserved
 	`)).WithArgs(5, 101, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"old", "new"}).AddRow(7, 2))
+
+	// ledger entry row 1
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 101, MovementRelease, 0, -5, int64(99), nil, "system:reservation-expiry", "").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// UPDATE reserved row 2
-	mock.ExpectExec(regexp.QuoteMeta(`
-		UPDATE warehouse_stock
-		SET reserved = GREATEST(reserved - $1, 0)
-		WHERE product_id = $2 AND warehouse_id = $3
+	mock.ExpectQuery(regexp.QuoteMeta(`
+		UPDATE warehouse_stock ws
+		SET reserved = GREATEST(ws.reserved - $1, 0)
+		FROM (
+			SELECT reserved FROM warehouse_stock
+			WHERE product_id = $2 AND warehouse_id = $3
+			FOR UPDATE
+		) old
+		WHERE ws.product_id = $2 AND ws.warehouse_id = $3
+		RETURNING old.reserved, ws.reserved
 	`)).WithArgs(3, 102, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"old", "new"}).AddRow(2, 0))
+
+	// ledger entry row 2: reserved was already below the reservation, only 2 were released
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 102, MovementRelease, 0, -2, int64(99), nil, "system:reservation-expiry", "").
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
 	// DELETE reservations
@@ -210,7 +233,7 @@ func TestReleaseReservationByOrderID_UpdateError(t *testing.T) {
 		WithArgs(9).
 		WillReturnRows(rows)
 
-	mock.ExpectExec(`UPDATE warehouse_stock SET reserved = GREATEST.*`).
+	mock.ExpectQuery(`UPDATE warehouse_stock ws SET reserved = GREATEST.*`).
 		WithArgs(2, 5, 1).
 		WillReturnError(errors.New("update fail"))
 
@@ -246,10 +269,11 @@ func TestReserveStockForOrder_Success(t *testing.T) {
 			WHERE w.active = TRUE
 			  AND ws.product_id = $1
 			  AND (ws.quantity - ws.reserved) >= $2
-			ORDER BY ws.warehouse_id
+			ORDER BY COALESCE(LOWER(w.region) = $3, FALSE) DESC, ws.warehouse_id
 			LIMIT 1
+			FOR SHARE OF w
 		`)).
-		WithArgs(101, 2).
+		WithArgs(101, 2, "jakarta").
 		WillReturnRows(rows)
 
 	// Step 2: update reserved
@@ -261,7 +285,16 @@ func TestReserveStockForOrder_Success(t *testing.T) {
 		WithArgs(2, 10, 101).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
-	// Step 3: insert reservation row
+	// Step 3: update product stock
+	mock.ExpectExec(regexp.QuoteMeta(`
+			UPDATE products
+			SET stock = stock - $1
+			WHERE id = $2
+		`)).
+		WithArgs(2, 101).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	// Step 4: insert reservation row
 	mock.ExpectExec(regexp.QuoteMeta(`
 			INSERT INTO reservations (order_id, product_id, warehouse_id, quantity, expires_at)
 			VALUES ($1, $2, $3, $4, NOW() + INTERVAL '5 minutes')
@@ -269,11 +302,17 @@ func TestReserveStockForOrder_Success(t *testing.T) {
 		WithArgs(5000, 101, 10, 2).
 		WillReturnResult(sqlmock.NewResult(1, 1))
 
+	// Step 5: ledger entry, actor taken from the request context
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(10, 101, MovementReserve, 0, 2, int64(5000), nil, "user:42", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
 	mock.ExpectCommit()
 
+	ctx = context.WithValue(ctx, helper.UserIDKey, 42)
 	err := ReserveStockForOrder(ctx, db, 5000, []model.CheckoutItem{
 		{ProductID: 101, Qty: 2},
-	})
+	}, "jakarta")
 	if err != nil {
 		t.Fatalf("unexpected reserve err: %v", err)
 	}
@@ -288,12 +327,12 @@ func TestReserveStockForOrder_NoWarehouseFound(t *testing.T) {
 	mock.ExpectBegin()
 
 	mock.ExpectQuery(`SELECT ws.warehouse_id.*`).
-		WithArgs(999, 10).
+		WithArgs(999, 10, "").
 		WillReturnError(sql.ErrNoRows)
 
 	err := ReserveStockForOrder(ctx, db, 2000, []model.CheckoutItem{
 		{ProductID: 999, Qty: 10},
-	})
+	}, "")
 	if err == nil {
 		t.Fatalf("expected error no active warehouse")
 	}
@@ -308,12 +347,12 @@ func TestReserveStockForOrder_QueryError(t *testing.T) {
 	mock.ExpectBegin()
 
 	mock.ExpectQuery(`SELECT ws.warehouse_id.*`).
-		WithArgs(5, 1).
+		WithArgs(5, 1, "").
 		WillReturnError(errors.New("query fail"))
 
 	err := ReserveStockForOrder(ctx, db, 9, []model.CheckoutItem{
 		{ProductID: 5, Qty: 1},
-	})
+	}, "")
 	if err == nil {
 		t.Fatalf("expected query error")
 	}
@@ -331,7 +370,7 @@ func TestReserveStockForOrder_UpdateFail(t *testing.T) {
 		AddRow(3, 10, 1)
 
 	mock.ExpectQuery(`SELECT ws.warehouse_id.*`).
-		WithArgs(77, 2).
+		WithArgs(77, 2, "").
 		WillReturnRows(rows)
 
 	mock.ExpectExec(`UPDATE warehouse_stock SET reserved = reserved \+ \$1.*`).
@@ -340,7 +379,7 @@ func TestReserveStockForOrder_UpdateFail(t *testing.T) {
 
 	err := ReserveStockForOrder(ctx, db, 7, []model.CheckoutItem{
 		{ProductID: 77, Qty: 2},
-	})
+	}, "")
 	if err == nil {
 		t.Fatalf("expected update error")
 	}
@@ -358,7 +397,7 @@ func TestReserveStockForOrder_InsertFail(t *testing.T) {
 		AddRow(9, 99, 0)
 
 	mock.ExpectQuery(`SELECT ws.warehouse_id.*`).
-		WithArgs(50, 3).
+		WithArgs(50, 3, "").
 		WillReturnRows(rows)
 
 	mock.ExpectExec(`UPDATE warehouse_stock SET reserved = reserved \+ \$1.*`).
@@ -371,7 +410,7 @@ func TestReserveStockForOrder_InsertFail(t *testing.T) {
 
 	err := ReserveStockForOrder(ctx, db, 9999, []model.CheckoutItem{
 		{ProductID: 50, Qty: 3},
-	})
+	}, "")
 	if err == nil {
 		t.Fatalf("expected insert error")
 	}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"order-service-sample/model"
+)
+
+// PreviewShipmentLines picks, for every item, the warehouse ReserveStockForOrder
+// would reserve it in, without locking anything, so shipping can be quoted before
+// the order is written. Items no warehouse can serve return the same error as
+// ReserveStockForOrder.
+func PreviewShipmentLines(db *sql.DB, items []model.CheckoutItem, region string) ([]model.ShipmentLine, error) {
+	lines := make([]model.ShipmentLine, 0, len(items))
+	for _, item := range items {
+		l := model.ShipmentLine{ProductID: item.ProductID, Qty: item.Qty}
+		err := db.QueryRow(`
+			SELECT LOWER(COALESCE(w.region, '')), p.weight_grams, p.length_mm, p.width_mm, p.height_mm
+			FROM warehouse_stock ws
+			JOIN warehouses w ON w.id = ws.warehouse_id
+			JOIN products p ON p.id = ws.product_id
+			WHERE w.active = TRUE
+			AND ws.product_id = $1
+			AND (ws.quantity - ws.reserved) >= $2
+			ORDER BY COALESCE(LOWER(w.region) = $3, FALSE) DESC, ws.warehouse_id
+			LIMIT 1
+		`, item.ProductID, item.Qty, region).Scan(&l.OriginRegion, &l.WeightGrams, &l.LengthMM, &l.WidthMM, &l.HeightMM)
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("no active warehouse has enough stock for product %d", item.ProductID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// GetOrderShipmentLines returns the reserved lines of an order with the region of
+// the warehouse they are reserved in and the product weight and dimensions
+func GetOrderShipmentLines(db *sql.DB, orderID int) ([]model.ShipmentLine, error) {
+	rows, err := db.Query(`
+		SELECT LOWER(COALESCE(w.region, '')), r.product_id, r.quantity,
+		       p.weight_grams, p.length_mm, p.width_mm, p.height_mm
+		FROM reservations r
+		JOIN warehouses w ON w.id = r.warehouse_id
+		JOIN products p ON p.id = r.product_id
+		WHERE r.order_id = $1
+		ORDER BY r.warehouse_id, r.product_id
+	`, orderID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []model.ShipmentLine{}
+	for rows.Next() {
+		var l model.ShipmentLine
+		if err := rows.Scan(&l.OriginRegion, &l.ProductID, &l.Qty, &l.WeightGrams, &l.LengthMM, &l.WidthMM, &l.HeightMM); err != nil {
+			return nil, err
+		}
+		lines = append(lines, l)
+	}
+	return lines, rows.Err()
+}
+
+// SetOrderShipping stores the chosen shipping method and adds its cost to the order total
+func SetOrderShipping(db *sql.DB, orderID int, method string, cost int64) error {
+	res, err := db.Exec(`
+		UPDATE orders
+		SET shipping_method = $1,
+		    shipping_cost = $2,
+		    total_amount = total_amount - shipping_cost + $2
+		WHERE id = $3
+	`, method, cost, orderID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("order_not_found")
+	}
+	return nil
+}
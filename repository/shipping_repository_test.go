@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"database/sql"
+	"testing"
+
+	"order-service-sample/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPreviewShipmentLines(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT LOWER\(COALESCE\(w.region, ''\)\), p.weight_grams.*FROM warehouse_stock ws`).
+		WithArgs(1, 2, "jakarta").
+		WillReturnRows(sqlmock.NewRows([]string{"region", "weight_grams", "length_mm", "width_mm", "height_mm"}).
+			AddRow("jakarta", 150, 120, 70, 40))
+	mock.ExpectQuery(`SELECT LOWER\(COALESCE\(w.region, ''\)\), p.weight_grams.*FROM warehouse_stock ws`).
+		WithArgs(2, 1, "jakarta").
+		WillReturnRows(sqlmock.NewRows([]string{"region", "weight_grams", "length_mm", "width_mm", "height_mm"}).
+			AddRow("surabaya", 1100, 450, 150, 45))
+
+	lines, err := PreviewShipmentLines(db, []model.CheckoutItem{{ProductID: 1, Qty: 2}, {ProductID: 2, Qty: 1}}, "jakarta")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 2 || lines[0].Qty != 2 || lines[1].OriginRegion != "surabaya" || lines[1].WeightGrams != 1100 {
+		t.Fatalf("unexpected lines %+v", lines)
+	}
+}
+
+func TestPreviewShipmentLines_NoStock(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM warehouse_stock ws`).
+		WithArgs(9, 5, "").
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := PreviewShipmentLines(db, []model.CheckoutItem{{ProductID: 9, Qty: 5}}, "")
+	if err == nil || err.Error() != "no active warehouse has enough stock for product 9" {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestGetOrderShipmentLines(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM reservations r\s+JOIN warehouses w ON w.id = r.warehouse_id\s+JOIN products p ON p.id = r.product_id\s+WHERE r.order_id = \$1`).
+		WithArgs(500).
+		WillReturnRows(sqlmock.NewRows([]string{"region", "product_id", "quantity", "weight_grams", "length_mm", "width_mm", "height_mm"}).
+			AddRow("jakarta", 1, 2, 150, 120, 70, 40).
+			AddRow("surabaya", 2, 1, 1100, 450, 150, 45))
+
+	lines, err := GetOrderShipmentLines(db, 500)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(lines) != 2 || lines[1].OriginRegion != "surabaya" || lines[1].WeightGrams != 1100 {
+		t.Fatalf("unexpected lines %+v", lines)
+	}
+}
+
+func TestSetOrderShipping(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE orders\s+SET shipping_method = \$1,\s+shipping_cost = \$2,\s+total_amount = total_amount - shipping_cost \+ \$2`).
+		WithArgs("express", int64(1800000), 500).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := SetOrderShipping(db, 500, "express", 1800000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSetOrderShipping_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE orders`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := SetOrderShipping(db, 404, "regular", 100); err == nil || err.Error() != "order_not_found" {
+		t.Fatalf("expected order_not_found, got %v", err)
+	}
+}
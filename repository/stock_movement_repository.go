@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"database/sql"
+
+	"order-service-sample/model"
+)
+
+// Stock movement types recorded in stock_movements
+const (
+	MovementReserve        = "reserve"
+	MovementRelease        = "release"
+	MovementSale           = "sale"
+	MovementTransferOut    = "transfer_out"
+	MovementTransferIn     = "transfer_in"
+	MovementTransferCancel = "transfer_cancel" // dispatched stock returned to the source
+	MovementAdjustment     = "adjustment"
+	MovementReturn         = "return"
+	MovementReceipt        = "receipt"
+)
+
+// RecordStockMovement appends one entry to the stock ledger. It must be called
+// in the same transaction as the warehouse_stock change it describes.
+func RecordStockMovement(tx *sql.Tx, m model.StockMovement) error {
+	_, err := tx.Exec(`
+		INSERT INTO stock_movements
+			(warehouse_id, product_id, movement_type, quantity_delta, reserved_delta, order_id, transfer_id, actor, note)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+	`, m.WarehouseID, m.ProductID, m.Type, m.QuantityDelta, m.ReservedDelta,
+		nullableID(m.OrderID), nullableID(m.TransferID), m.Actor, m.Note)
+	return err
+}
+
+// GetStockMovements returns the newest movements of a product, optionally limited to one warehouse
+func GetStockMovements(db *sql.DB, productID, warehouseID, limit int) ([]model.StockMovement, error) {
+	rows, err := db.Query(`
+		SELECT id, warehouse_id, product_id, movement_type, quantity_delta, reserved_delta,
+		       COALESCE(order_id, 0), COALESCE(transfer_id, 0), actor, COALESCE(note, ''), created_at
+		FROM stock_movements
+		WHERE product_id = $1
+		AND ($2 = 0 OR warehouse_id = $2)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3
+	`, productID, warehouseID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	movements := []model.StockMovement{}
+	for rows.Next() {
+		var m model.StockMovement
+		if err := rows.Scan(&m.ID, &m.WarehouseID, &m.ProductID, &m.Type, &m.QuantityDelta, &m.ReservedDelta,
+			&m.OrderID, &m.TransferID, &m.Actor, &m.Note, &m.CreatedAt); err != nil {
+			return nil, err
+		}
+		movements = append(movements, m)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return movements, nil
+}
+
+// nullableID stores 0 as NULL for optional references
+func nullableID(id int) sql.NullInt64 {
+	return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+}
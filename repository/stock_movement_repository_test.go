@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"order-service-sample/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestRecordStockMovement_NullReferences(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO stock_movements .*VALUES \(\$1, \$2, \$3, \$4, \$5, \$6, \$7, \$8, \$9\)`).
+		WithArgs(1, 2, MovementTransferOut, -5, 0, nil, nil, "user:1", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, _ := db.Begin()
+	err := RecordStockMovement(tx, model.StockMovement{
+		WarehouseID: 1, ProductID: 2, Type: MovementTransferOut, QuantityDelta: -5, Actor: "user:1",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestGetStockMovements_Success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{
+		"id", "warehouse_id", "product_id", "movement_type", "quantity_delta", "reserved_delta",
+		"order_id", "transfer_id", "actor", "note", "created_at",
+	}).
+		AddRow(2, 1, 5, MovementSale, -2, -2, 10, 0, "user:3", "", time.Now()).
+		AddRow(1, 1, 5, MovementReserve, 0, 2, 10, 0, "user:3", "", time.Now())
+
+	mock.ExpectQuery(`SELECT id, warehouse_id, product_id, movement_type, .*FROM stock_movements WHERE product_id = \$1 AND \(\$2 = 0 OR warehouse_id = \$2\)`).
+		WithArgs(5, 1, 50).
+		WillReturnRows(rows)
+
+	movements, err := GetStockMovements(db, 5, 1, 50)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(movements) != 2 || movements[0].Type != MovementSale || movements[0].OrderID != 10 {
+		t.Fatalf("unexpected movements: %+v", movements)
+	}
+}
+
+func TestGetStockMovements_QueryError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM stock_movements`).WillReturnError(errors.New("db down"))
+
+	if _, err := GetStockMovements(db, 5, 0, 50); err == nil {
+		t.Fatalf("expected error")
+	}
+}
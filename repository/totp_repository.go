@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+)
+
+// TOTPState is the 2FA state of a user. Secret is set from enrolment on; Enabled only
+// once the user proved with a first code that the authenticator app works.
+type TOTPState struct {
+	Secret  string
+	Enabled bool
+}
+
+func GetTOTPState(db *sql.DB, userID int) (TOTPState, error) {
+	var s TOTPState
+	err := db.QueryRow(`
+		SELECT COALESCE(totp_secret, ''), totp_enabled_at IS NOT NULL
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&s.Secret, &s.Enabled)
+	if err == sql.ErrNoRows {
+		return s, errors.New("user_not_found")
+	}
+	return s, err
+}
+
+// SetPendingTOTPSecret starts (or restarts) an enrolment; it fails with
+// "totp_already_enabled" when 2FA is already on
+func SetPendingTOTPSecret(db *sql.DB, userID int, secret string) error {
+	res, err := db.Exec(`
+		UPDATE users SET totp_secret = $2, totp_last_step = NULL
+		WHERE id = $1 AND totp_enabled_at IS NULL
+	`, userID, secret)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("totp_already_enabled")
+	}
+	return nil
+}
+
+// EnableTOTP turns 2FA on and replaces the recovery codes. step is the time step of
+// the code used to confirm, so that code can not be replayed at login.
+func EnableTOTP(ctx context.Context, db *sql.DB, userID int, step int64, recoveryCodeHashes []string) error {
+	return RunInTx(ctx, db, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`
+			UPDATE users SET totp_enabled_at = NOW(), totp_last_step = $2
+			WHERE id = $1 AND totp_enabled_at IS NULL AND totp_secret IS NOT NULL
+		`, userID, step)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return errors.New("totp_already_enabled")
+		}
+
+		if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+			return err
+		}
+		for _, h := range recoveryCodeHashes {
+			if _, err := tx.Exec(`
+				INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)
+			`, userID, h); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UseTOTPStep records a verified code's time step. It returns false when that step
+// (or a later one) was already used, which stops replay of an observed code.
+func UseTOTPStep(db *sql.DB, userID int, step int64) (bool, error) {
+	res, err := db.Exec(`
+		UPDATE users SET totp_last_step = $2
+		WHERE id = $1 AND (totp_last_step IS NULL OR totp_last_step < $2)
+	`, userID, step)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// UseRecoveryCode consumes a recovery code; false when it is unknown or already used
+func UseRecoveryCode(db *sql.DB, userID int, codeHash string) (bool, error) {
+	res, err := db.Exec(`
+		UPDATE user_recovery_codes SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, userID, codeHash)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+// DisableTOTP turns 2FA off and drops the secret and recovery codes
+func DisableTOTP(ctx context.Context, db *sql.DB, userID int) error {
+	return RunInTx(ctx, db, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`
+			UPDATE users SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL
+			WHERE id = $1
+		`, userID); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID)
+		return err
+	})
+}
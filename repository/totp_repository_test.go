@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUseTOTPStep_RejectsReplay(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE users SET totp_last_step = \$2\s+WHERE id = \$1 AND \(totp_last_step IS NULL OR totp_last_step < \$2\)`).
+		WithArgs(4, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE users SET totp_last_step`).
+		WithArgs(4, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if ok, err := UseTOTPStep(db, 4, 100); !ok || err != nil {
+		t.Fatalf("expected first use to pass, got %v, %v", ok, err)
+	}
+	if ok, err := UseTOTPStep(db, 4, 100); ok || err != nil {
+		t.Fatalf("expected replay to be rejected, got %v, %v", ok, err)
+	}
+}
+
+func TestEnableTOTP_StoresRecoveryCodes(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET totp_enabled_at = NOW\(\), totp_last_step = \$2`).
+		WithArgs(4, int64(100)).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM user_recovery_codes WHERE user_id = \$1`).
+		WithArgs(4).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	for _, h := range []string{"h1", "h2"} {
+		mock.ExpectExec(`INSERT INTO user_recovery_codes \(user_id, code_hash\)`).
+			WithArgs(4, h).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+	}
+	mock.ExpectCommit()
+
+	if err := EnableTOTP(context.Background(), db, 4, 100, []string{"h1", "h2"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEnableTOTP_AlreadyEnabled(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE users SET totp_enabled_at`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := EnableTOTP(context.Background(), db, 4, 100, nil)
+	if err == nil || err.Error() != "totp_already_enabled" {
+		t.Fatalf("expected totp_already_enabled, got %v", err)
+	}
+}
+
+func TestUseRecoveryCode(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE user_recovery_codes SET used_at = NOW\(\)\s+WHERE user_id = \$1 AND code_hash = \$2 AND used_at IS NULL`).
+		WithArgs(4, "hash").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if ok, err := UseRecoveryCode(db, 4, "hash"); !ok || err != nil {
+		t.Fatalf("expected recovery code to be accepted, got %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,98 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestTransferStock_OppositeDirectionsConcurrently needs a migrated Postgres database
+// (make migrate) in TEST_DATABASE_DSN. It creates two warehouses and one product of
+// its own and hammers A→B and B→A transfers at the same time.
+func TestTransferStock_OppositeDirectionsConcurrently(t *testing.T) {
+	dsn := os.Getenv("TEST_DATABASE_DSN")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_DSN not set")
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(32)
+
+	const (
+		workers   = 16
+		perWorker = 25
+		initial   = 1000
+	)
+
+	suffix := time.Now().UnixNano()
+	var whA, whB, productID int
+	for _, q := range []struct {
+		query string
+		dest  *int
+	}{
+		{`INSERT INTO warehouses (name, active) VALUES ('concurrency-a-` + fmt.Sprint(suffix) + `', true) RETURNING id`, &whA},
+		{`INSERT INTO warehouses (name, active) VALUES ('concurrency-b-` + fmt.Sprint(suffix) + `', true) RETURNING id`, &whB},
+		{`INSERT INTO products (name, stock, price) VALUES ('concurrency-` + fmt.Sprint(suffix) + `', 0, 1) RETURNING id`, &productID},
+	} {
+		if err := db.QueryRow(q.query).Scan(q.dest); err != nil {
+			t.Fatalf("setup: %v", err)
+		}
+	}
+	for _, wh := range []int{whA, whB} {
+		_, err := db.Exec(`INSERT INTO warehouse_stock (warehouse_id, product_id, quantity, reserved) VALUES ($1, $2, $3, 0)`,
+			wh, productID, initial)
+		if err != nil {
+			t.Fatalf("setup stock: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		from, to := whA, whB
+		if w%2 == 1 {
+			from, to = whB, whA
+		}
+		wg.Add(1)
+		go func(from, to int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				err := RunInTx(ctx, db, func(tx *sql.Tx) error {
+					return TransferStock(tx, from, to, productID, 1, "system:test")
+				})
+				if err != nil {
+					errs <- err
+				}
+			}
+		}(from, to)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("transfer failed: %v", err)
+	}
+
+	// every worker pair cancels out, so both warehouses end where they started
+	for _, wh := range []int{whA, whB} {
+		var qty int
+		err := db.QueryRow(`SELECT quantity FROM warehouse_stock WHERE warehouse_id = $1 AND product_id = $2`, wh, productID).Scan(&qty)
+		if err != nil {
+			t.Fatalf("read stock: %v", err)
+		}
+		if qty != initial {
+			t.Errorf("warehouse %d: expected quantity %d, got %d", wh, initial, qty)
+		}
+	}
+}
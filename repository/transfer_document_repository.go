@@ -0,0 +1,427 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sort"
+
+	"order-service-sample/model"
+)
+
+// Transfer statuses. An instant transfer is completed immediately; a two-phase
+// transfer is dispatched first and later received or cancelled.
+const (
+	TransferStatusCompleted  = "completed"
+	TransferStatusDispatched = "dispatched"
+	TransferStatusReceived   = "received"
+	TransferStatusCancelled  = "cancelled"
+)
+
+type stockKey struct {
+	warehouseID int
+	productID   int
+}
+
+type stockLevel struct {
+	quantity int
+	reserved int
+}
+
+// lockStockRows locks the given warehouse_stock rows one by one, always ordered by
+// (warehouse_id, product_id), so concurrent transactions never wait on each other
+// in a cycle. Missing rows are left out of the result.
+func lockStockRows(tx *sql.Tx, keys []stockKey) (map[stockKey]stockLevel, error) {
+	ordered := append([]stockKey(nil), keys...)
+	sort.Slice(ordered, func(i, j int) bool {
+		if ordered[i].warehouseID != ordered[j].warehouseID {
+			return ordered[i].warehouseID < ordered[j].warehouseID
+		}
+		return ordered[i].productID < ordered[j].productID
+	})
+
+	levels := make(map[stockKey]stockLevel, len(ordered))
+	for _, k := range ordered {
+		if _, done := levels[k]; done {
+			continue
+		}
+
+		var lvl stockLevel
+		err := tx.QueryRow(`
+			SELECT quantity, reserved
+			FROM warehouse_stock
+			WHERE warehouse_id = $1 AND product_id = $2
+			FOR UPDATE
+		`, k.warehouseID, k.productID).Scan(&lvl.quantity, &lvl.reserved)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		levels[k] = lvl
+	}
+	return levels, nil
+}
+
+// CreateTransfer moves every line from one warehouse to another in a single
+// transaction and stores the transfer document. Lines must have distinct products.
+func CreateTransfer(ctx context.Context, db *sql.DB, fromWarehouseID, toWarehouseID int, lines []model.TransferLine, note, actor string) (model.Transfer, error) {
+	return createTransfer(ctx, db, fromWarehouseID, toWarehouseID, lines, note, actor, TransferStatusCompleted)
+}
+
+// DispatchTransfer deducts every line from the source warehouse and adds it to the
+// in_transit quantity of the destination until ReceiveTransfer or CancelTransfer is called
+func DispatchTransfer(ctx context.Context, db *sql.DB, fromWarehouseID, toWarehouseID int, lines []model.TransferLine, note, actor string) (model.Transfer, error) {
+	return createTransfer(ctx, db, fromWarehouseID, toWarehouseID, lines, note, actor, TransferStatusDispatched)
+}
+
+func createTransfer(ctx context.Context, db *sql.DB, fromWarehouseID, toWarehouseID int, lines []model.TransferLine, note, actor, status string) (model.Transfer, error) {
+	t := model.Transfer{
+		FromWarehouse: fromWarehouseID,
+		ToWarehouse:   toWarehouseID,
+		Status:        status,
+		Note:          note,
+		Actor:         actor,
+		Items:         append([]model.TransferLine(nil), lines...),
+	}
+	sort.Slice(t.Items, func(i, j int) bool { return t.Items[i].ProductID < t.Items[j].ProductID })
+
+	if fromWarehouseID == toWarehouseID {
+		return t, errors.New("from and to warehouse must be different")
+	}
+
+	err := RunInTx(ctx, db, func(tx *sql.Tx) error {
+		return insertTransfer(tx, &t)
+	})
+	return t, err
+}
+
+// insertTransfer stores the transfer document and moves its lines inside tx
+func insertTransfer(tx *sql.Tx, t *model.Transfer) error {
+	fromWarehouseID, toWarehouseID, status, actor := t.FromWarehouse, t.ToWarehouse, t.Status, t.Actor
+
+	err := tx.QueryRow(`
+		INSERT INTO transfers (from_warehouse_id, to_warehouse_id, status, note, actor)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`, fromWarehouseID, toWarehouseID, status, t.Note, actor).Scan(&t.ID, &t.CreatedAt, &t.UpdatedAt)
+	if err != nil {
+		return err
+	}
+
+	// a dispatched transfer only changes in_transit of the destination rows
+	keys := make([]stockKey, 0, 2*len(t.Items))
+	for _, it := range t.Items {
+		keys = append(keys, stockKey{fromWarehouseID, it.ProductID})
+		if err := ensureStockRowExists(tx, toWarehouseID, it.ProductID); err != nil {
+			return err
+		}
+		keys = append(keys, stockKey{toWarehouseID, it.ProductID})
+	}
+
+	levels, err := lockStockRows(tx, keys)
+	if err != nil {
+		return err
+	}
+
+	for _, it := range t.Items {
+		src, ok := levels[stockKey{fromWarehouseID, it.ProductID}]
+		if !ok {
+			return fmt.Errorf("source_stock_not_found (product_id=%d)", it.ProductID)
+		}
+		if available := src.quantity - src.reserved; available < it.Quantity {
+			return fmt.Errorf("not enough available stock in source warehouse for product_id=%d (available=%d)", it.ProductID, available)
+		}
+	}
+
+	for _, it := range t.Items {
+		if status == TransferStatusCompleted {
+			err = moveTransferLine(tx, t.ID, fromWarehouseID, toWarehouseID, it.ProductID, it.Quantity, actor)
+		} else {
+			err = shiftTransferStock(tx, t.ID, fromWarehouseID, it.ProductID, -it.Quantity, MovementTransferOut, actor,
+				fmt.Sprintf("transfer #%d dispatched", t.ID))
+			if err == nil {
+				err = shiftInTransit(tx, toWarehouseID, it.ProductID, it.Quantity)
+			}
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO transfer_items (transfer_id, product_id, quantity)
+			VALUES ($1, $2, $3)
+		`, t.ID, it.ProductID, it.Quantity)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// shiftTransferStock changes one side of a two-phase transfer, records it in the
+// ledger and resyncs products.stock
+func shiftTransferStock(tx *sql.Tx, transferID, warehouseID, productID, delta int, movementType, actor, note string) error {
+	_, err := tx.Exec(`
+		UPDATE warehouse_stock
+		SET quantity = quantity + $1
+		WHERE warehouse_id = $2 AND product_id = $3
+	`, delta, warehouseID, productID)
+	if err != nil {
+		return err
+	}
+
+	err = RecordStockMovement(tx, model.StockMovement{
+		WarehouseID:   warehouseID,
+		ProductID:     productID,
+		Type:          movementType,
+		QuantityDelta: delta,
+		TransferID:    transferID,
+		Actor:         actor,
+		Note:          note,
+	})
+	if err != nil {
+		return err
+	}
+
+	return SyncProductStock(tx, productID)
+}
+
+// shiftInTransit changes the in_transit quantity of the destination row of a transfer
+func shiftInTransit(tx *sql.Tx, warehouseID, productID, delta int) error {
+	_, err := tx.Exec(`
+		UPDATE warehouse_stock
+		SET in_transit = in_transit + $1, updated_at = NOW()
+		WHERE warehouse_id = $2 AND product_id = $3
+	`, delta, warehouseID, productID)
+	return err
+}
+
+// lockTransitTransfer locks a transfer and its lines; it must still be in transit
+func lockTransitTransfer(tx *sql.Tx, transferID int) (model.Transfer, error) {
+	var t model.Transfer
+	err := tx.QueryRow(`
+		SELECT id, from_warehouse_id, to_warehouse_id, status, COALESCE(note, ''), actor, created_at
+		FROM transfers
+		WHERE id = $1
+		FOR UPDATE
+	`, transferID).Scan(&t.ID, &t.FromWarehouse, &t.ToWarehouse, &t.Status, &t.Note, &t.Actor, &t.CreatedAt)
+	if err == sql.ErrNoRows {
+		return t, errors.New("transfer_not_found")
+	}
+	if err != nil {
+		return t, err
+	}
+	if t.Status != TransferStatusDispatched {
+		return t, errors.New("transfer_not_in_transit")
+	}
+
+	rows, err := tx.Query(`
+		SELECT product_id, quantity
+		FROM transfer_items
+		WHERE transfer_id = $1
+		ORDER BY product_id
+	`, transferID)
+	if err != nil {
+		return t, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var it model.TransferLine
+		if err := rows.Scan(&it.ProductID, &it.Quantity); err != nil {
+			return t, err
+		}
+		t.Items = append(t.Items, it)
+	}
+	return t, rows.Err()
+}
+
+// finishTransfer moves a transfer to its final status
+func finishTransfer(tx *sql.Tx, t *model.Transfer, status string) error {
+	t.Status = status
+	return tx.QueryRow(`
+		UPDATE transfers
+		SET status = $1, updated_at = NOW()
+		WHERE id = $2
+		RETURNING updated_at
+	`, status, t.ID).Scan(&t.UpdatedAt)
+}
+
+// ReceiveTransfer moves a dispatched transfer out of in_transit and credits the
+// destination warehouse with what arrived. Lines missing from received are taken as
+// fully received; the rest of a line that is neither received nor damaged is reported as short.
+func ReceiveTransfer(ctx context.Context, db *sql.DB, transferID int, received []model.ReceiveTransferLine, actor string) (model.Transfer, error) {
+	var t model.Transfer
+	err := RunInTx(ctx, db, func(tx *sql.Tx) error {
+		var err error
+		t, err = receiveTransfer(tx, transferID, received, actor)
+		return err
+	})
+	return t, err
+}
+
+func receiveTransfer(tx *sql.Tx, transferID int, received []model.ReceiveTransferLine, actor string) (model.Transfer, error) {
+	t, err := lockTransitTransfer(tx, transferID)
+	if err != nil {
+		return t, err
+	}
+
+	byProduct := make(map[int]model.ReceiveTransferLine, len(received))
+	for _, r := range received {
+		byProduct[r.ProductID] = r
+	}
+
+	keys := make([]stockKey, 0, len(t.Items))
+	for i := range t.Items {
+		it := &t.Items[i]
+		it.Received = it.Quantity
+		if r, ok := byProduct[it.ProductID]; ok {
+			if r.ReceivedQuantity < 0 || r.DamagedQuantity < 0 || r.ReceivedQuantity+r.DamagedQuantity > it.Quantity {
+				return t, fmt.Errorf("invalid_received_quantity (product_id=%d)", it.ProductID)
+			}
+			it.Received = r.ReceivedQuantity
+			it.Damaged = r.DamagedQuantity
+			delete(byProduct, it.ProductID)
+		}
+		it.Short = it.Quantity - it.Received - it.Damaged
+
+		if err := ensureStockRowExists(tx, t.ToWarehouse, it.ProductID); err != nil {
+			return t, err
+		}
+		keys = append(keys, stockKey{t.ToWarehouse, it.ProductID})
+	}
+	if len(byProduct) > 0 {
+		return t, errors.New("product_not_in_transfer")
+	}
+
+	if _, err := lockStockRows(tx, keys); err != nil {
+		return t, err
+	}
+
+	for _, it := range t.Items {
+		// seluruh line keluar dari in_transit, termasuk yang rusak atau kurang
+		if err := shiftInTransit(tx, t.ToWarehouse, it.ProductID, -it.Quantity); err != nil {
+			return t, err
+		}
+
+		if it.Received > 0 {
+			note := fmt.Sprintf("transfer #%d received", t.ID)
+			if it.Damaged > 0 || it.Short > 0 {
+				note += fmt.Sprintf(" (damaged=%d, short=%d)", it.Damaged, it.Short)
+			}
+			if err := shiftTransferStock(tx, t.ID, t.ToWarehouse, it.ProductID, it.Received, MovementTransferIn, actor, note); err != nil {
+				return t, err
+			}
+		}
+
+		_, err := tx.Exec(`
+			UPDATE transfer_items
+			SET received_quantity = $1, damaged_quantity = $2
+			WHERE transfer_id = $3 AND product_id = $4
+		`, it.Received, it.Damaged, t.ID, it.ProductID)
+		if err != nil {
+			return t, err
+		}
+	}
+
+	if err := finishTransfer(tx, &t, TransferStatusReceived); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+// CancelTransfer returns every line of a dispatched transfer from in_transit to the
+// source warehouse
+func CancelTransfer(ctx context.Context, db *sql.DB, transferID int, actor string) (model.Transfer, error) {
+	var t model.Transfer
+	err := RunInTx(ctx, db, func(tx *sql.Tx) error {
+		var err error
+		t, err = cancelTransfer(tx, transferID, actor)
+		return err
+	})
+	return t, err
+}
+
+func cancelTransfer(tx *sql.Tx, transferID int, actor string) (model.Transfer, error) {
+	t, err := lockTransitTransfer(tx, transferID)
+	if err != nil {
+		return t, err
+	}
+
+	keys := make([]stockKey, 0, 2*len(t.Items))
+	for _, it := range t.Items {
+		if err := ensureStockRowExists(tx, t.FromWarehouse, it.ProductID); err != nil {
+			return t, err
+		}
+		keys = append(keys, stockKey{t.FromWarehouse, it.ProductID}, stockKey{t.ToWarehouse, it.ProductID})
+	}
+
+	if _, err := lockStockRows(tx, keys); err != nil {
+		return t, err
+	}
+
+	for _, it := range t.Items {
+		if err := shiftInTransit(tx, t.ToWarehouse, it.ProductID, -it.Quantity); err != nil {
+			return t, err
+		}
+		err := shiftTransferStock(tx, t.ID, t.FromWarehouse, it.ProductID, it.Quantity, MovementTransferCancel, actor,
+			fmt.Sprintf("transfer #%d cancelled", t.ID))
+		if err != nil {
+			return t, err
+		}
+	}
+
+	if err := finishTransfer(tx, &t, TransferStatusCancelled); err != nil {
+		return t, err
+	}
+
+	return t, nil
+}
+
+// GetTransfer returns a transfer document with its lines
+func GetTransfer(db *sql.DB, transferID int) (model.Transfer, error) {
+	var t model.Transfer
+	err := db.QueryRow(`
+		SELECT id, from_warehouse_id, to_warehouse_id, status, COALESCE(note, ''), actor, created_at, updated_at
+		FROM transfers
+		WHERE id = $1
+	`, transferID).Scan(&t.ID, &t.FromWarehouse, &t.ToWarehouse, &t.Status, &t.Note, &t.Actor, &t.CreatedAt, &t.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return t, errors.New("transfer_not_found")
+	}
+	if err != nil {
+		return t, err
+	}
+
+	rows, err := db.Query(`
+		SELECT product_id, quantity, received_quantity, damaged_quantity
+		FROM transfer_items
+		WHERE transfer_id = $1
+		ORDER BY product_id
+	`, transferID)
+	if err != nil {
+		return t, err
+	}
+	defer rows.Close()
+
+	t.Items = []model.TransferLine{}
+	for rows.Next() {
+		var it model.TransferLine
+		var received, damaged sql.NullInt64
+		if err := rows.Scan(&it.ProductID, &it.Quantity, &received, &damaged); err != nil {
+			return t, err
+		}
+		if received.Valid {
+			it.Received = int(received.Int64)
+			it.Damaged = int(damaged.Int64)
+			it.Short = it.Quantity - it.Received - it.Damaged
+		}
+		t.Items = append(t.Items, it)
+	}
+	return t, rows.Err()
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"order-service-sample/model"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func expectLockRow(mock sqlmock.Sqlmock, warehouseID, productID, qty, reserved int) {
+	mock.ExpectQuery(`SELECT quantity, reserved FROM warehouse_stock WHERE warehouse_id = \$1 AND product_id = \$2 FOR UPDATE`).
+		WithArgs(warehouseID, productID).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reserved"}).AddRow(qty, reserved))
+}
+
+func TestCreateTransfer_LocksInWarehouseOrder(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO transfers`).
+		WithArgs(3, 1, TransferStatusCompleted, "", "user:7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(11, now, now))
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).WithArgs(1, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).WithArgs(1, 8).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	// destination (1) is locked before source (3), products ascending
+	expectLockRow(mock, 1, 4, 0, 0)
+	expectLockRow(mock, 1, 8, 2, 0)
+	expectLockRow(mock, 3, 4, 10, 2)
+	expectLockRow(mock, 3, 8, 5, 0)
+
+	for _, l := range []struct{ product, qty int }{{4, 8}, {8, 5}} {
+		mock.ExpectExec(`UPDATE warehouse_stock SET quantity = quantity - \$1`).
+			WithArgs(l.qty, 3, l.product).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`UPDATE warehouse_stock SET quantity = quantity \+ \$1`).
+			WithArgs(l.qty, 1, l.product).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO stock_movements`).
+			WithArgs(3, l.product, MovementTransferOut, -l.qty, 0, nil, int64(11), "user:7", "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO stock_movements`).
+			WithArgs(1, l.product, MovementTransferIn, l.qty, 0, nil, int64(11), "user:7", "").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec(`INSERT INTO transfer_items`).
+			WithArgs(11, l.product, l.qty).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+
+	transfer, err := CreateTransfer(context.Background(), db, 3, 1, []model.TransferLine{
+		{ProductID: 8, Quantity: 5},
+		{ProductID: 4, Quantity: 8},
+	}, "", "user:7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transfer.ID != 11 || transfer.Status != TransferStatusCompleted || len(transfer.Items) != 2 {
+		t.Fatalf("unexpected transfer: %+v", transfer)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestCreateTransfer_InsufficientStockRollsBack(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO transfers`).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(12, now, now))
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).WithArgs(2, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectLockRow(mock, 1, 4, 5, 3)
+	expectLockRow(mock, 2, 4, 0, 0)
+	mock.ExpectRollback()
+
+	_, err := CreateTransfer(context.Background(), db, 1, 2, []model.TransferLine{
+		{ProductID: 4, Quantity: 3},
+	}, "", "user:7")
+	if err == nil || !strings.HasPrefix(err.Error(), "not enough available stock") {
+		t.Fatalf("expected not enough available stock, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestGetTransfer(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(`FROM transfers WHERE id = \$1`).
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "from_warehouse_id", "to_warehouse_id", "status", "note", "actor", "created_at", "updated_at"}).
+			AddRow(11, 3, 1, "completed", "", "user:7", now, now))
+	mock.ExpectQuery(`FROM transfer_items WHERE transfer_id = \$1`).
+		WithArgs(11).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "quantity", "received_quantity", "damaged_quantity"}).
+			AddRow(4, 8, nil, nil).AddRow(8, 5, nil, nil))
+
+	transfer, err := GetTransfer(db, 11)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(transfer.Items) != 2 || transfer.Items[1].Quantity != 5 {
+		t.Fatalf("unexpected transfer: %+v", transfer)
+	}
+}
+
+func TestGetTransfer_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`FROM transfers WHERE id = \$1`).
+		WithArgs(99).
+		WillReturnError(sql.ErrNoRows)
+
+	if _, err := GetTransfer(db, 99); err == nil || err.Error() != "transfer_not_found" {
+		t.Fatalf("expected transfer_not_found, got %v", err)
+	}
+}
+
+func expectTransitTransfer(mock sqlmock.Sqlmock, status string) {
+	mock.ExpectQuery(`FROM transfers WHERE id = \$1 FOR UPDATE`).
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "from_warehouse_id", "to_warehouse_id", "status", "note", "actor", "created_at"}).
+			AddRow(20, 1, 2, status, "", "user:7", time.Now()))
+}
+
+func TestDispatchTransfer_MovesSourceToInTransit(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO transfers`).
+		WithArgs(1, 2, TransferStatusDispatched, "", "user:7").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at", "updated_at"}).AddRow(20, now, now))
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).WithArgs(2, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectLockRow(mock, 1, 4, 10, 0)
+	expectLockRow(mock, 2, 4, 0, 0)
+	mock.ExpectExec(`UPDATE warehouse_stock SET quantity = quantity \+ \$1`).
+		WithArgs(-6, 1, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 4, MovementTransferOut, -6, 0, nil, int64(20), "user:7", "transfer #20 dispatched").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE products`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE warehouse_stock SET in_transit = in_transit \+ \$1`).
+		WithArgs(6, 2, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO transfer_items`).
+		WithArgs(20, 4, 6).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	transfer, err := DispatchTransfer(context.Background(), db, 1, 2, []model.TransferLine{{ProductID: 4, Quantity: 6}}, "", "user:7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transfer.Status != TransferStatusDispatched {
+		t.Fatalf("expected dispatched, got %s", transfer.Status)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestReceiveTransfer_ReportsShortAndDamaged(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectTransitTransfer(mock, TransferStatusDispatched)
+	mock.ExpectQuery(`FROM transfer_items WHERE transfer_id = \$1`).
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "quantity"}).AddRow(4, 10))
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).WithArgs(2, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectLockRow(mock, 2, 4, 0, 0)
+	mock.ExpectExec(`UPDATE warehouse_stock SET in_transit = in_transit \+ \$1`).
+		WithArgs(-10, 2, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE warehouse_stock SET quantity = quantity \+ \$1`).
+		WithArgs(7, 2, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(2, 4, MovementTransferIn, 7, 0, nil, int64(20), "user:7", "transfer #20 received (damaged=1, short=2)").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE products`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE transfer_items SET received_quantity = \$1, damaged_quantity = \$2`).
+		WithArgs(7, 1, 20, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE transfers SET status = \$1`).
+		WithArgs(TransferStatusReceived, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+
+	transfer, err := ReceiveTransfer(context.Background(), db, 20, []model.ReceiveTransferLine{
+		{ProductID: 4, ReceivedQuantity: 7, DamagedQuantity: 1},
+	}, "user:7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transfer.Status != TransferStatusReceived || transfer.Items[0].Short != 2 {
+		t.Fatalf("unexpected transfer: %+v", transfer)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestReceiveTransfer_RejectsTooMuch(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectTransitTransfer(mock, TransferStatusDispatched)
+	mock.ExpectQuery(`FROM transfer_items WHERE transfer_id = \$1`).
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "quantity"}).AddRow(4, 10))
+	mock.ExpectRollback()
+
+	_, err := ReceiveTransfer(context.Background(), db, 20, []model.ReceiveTransferLine{
+		{ProductID: 4, ReceivedQuantity: 10, DamagedQuantity: 1},
+	}, "user:7")
+	if err == nil || !strings.HasPrefix(err.Error(), "invalid_received_quantity") {
+		t.Fatalf("expected invalid_received_quantity, got %v", err)
+	}
+}
+
+func TestCancelTransfer_ReturnsStockToSource(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectTransitTransfer(mock, TransferStatusDispatched)
+	mock.ExpectQuery(`FROM transfer_items WHERE transfer_id = \$1`).
+		WithArgs(20).
+		WillReturnRows(sqlmock.NewRows([]string{"product_id", "quantity"}).AddRow(4, 6))
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).WithArgs(1, 4).WillReturnResult(sqlmock.NewResult(0, 0))
+	expectLockRow(mock, 1, 4, 4, 0)
+	expectLockRow(mock, 2, 4, 0, 0)
+	mock.ExpectExec(`UPDATE warehouse_stock SET in_transit = in_transit \+ \$1`).
+		WithArgs(-6, 2, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE warehouse_stock SET quantity = quantity \+ \$1`).
+		WithArgs(6, 1, 4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 4, MovementTransferCancel, 6, 0, nil, int64(20), "user:7", "transfer #20 cancelled").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE products`).WithArgs(4).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`UPDATE transfers SET status = \$1`).
+		WithArgs(TransferStatusCancelled, 20).
+		WillReturnRows(sqlmock.NewRows([]string{"updated_at"}).AddRow(time.Now()))
+	mock.ExpectCommit()
+
+	transfer, err := CancelTransfer(context.Background(), db, 20, "user:7")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if transfer.Status != TransferStatusCancelled {
+		t.Fatalf("expected cancelled, got %s", transfer.Status)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestCancelTransfer_NotInTransit(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	expectTransitTransfer(mock, TransferStatusReceived)
+	mock.ExpectRollback()
+
+	if _, err := CancelTransfer(context.Background(), db, 20, "user:7"); err == nil || err.Error() != "transfer_not_in_transit" {
+		t.Fatalf("expected transfer_not_in_transit, got %v", err)
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+
+	"order-service-sample/model"
 )
 
 // CheckWarehouseActive returns true if warehouse exists & active
@@ -52,7 +54,9 @@ func ensureStockRowExists(tx *sql.Tx, warehouseID, productID int) error {
 }
 
 // TransferStock transfers qty from source warehouse to destination warehouse
-func TransferStock(tx *sql.Tx, fromWarehouseID, toWarehouseID, productID, qty int) error {
+// and records both sides in the stock ledger. Both rows are locked ordered by
+// warehouse_id so opposite-direction transfers cannot deadlock each other.
+func TransferStock(tx *sql.Tx, fromWarehouseID, toWarehouseID, productID, qty int, actor string) error {
 
 	if fromWarehouseID == toWarehouseID {
 		return errors.New("from and to warehouse must be different")
@@ -66,44 +70,36 @@ func TransferStock(tx *sql.Tx, fromWarehouseID, toWarehouseID, productID, qty in
 		return err
 	}
 
-	// Lock and read source
-	sourceQuery := `SELECT quantity, reserved
-                    FROM warehouse_stock
-                    WHERE warehouse_id = $1 AND product_id = $2
-                    FOR UPDATE`
-
-	var srcQty, srcReserved int
-	err := tx.QueryRow(sourceQuery, fromWarehouseID, productID).Scan(&srcQty, &srcReserved)
-	if err == sql.ErrNoRows {
-		return errors.New("source_stock_not_found")
-	}
+	levels, err := lockStockRows(tx, []stockKey{
+		{fromWarehouseID, productID},
+		{toWarehouseID, productID},
+	})
 	if err != nil {
 		return err
 	}
 
-	available := srcQty - srcReserved
+	src, ok := levels[stockKey{fromWarehouseID, productID}]
+	if !ok {
+		return errors.New("source_stock_not_found")
+	}
+
+	available := src.quantity - src.reserved
 	if available < qty {
 		return fmt.Errorf("not enough available stock in source warehouse (available=%d)", available)
 	}
 
-	// Lock and read destination
-	destQuery := `SELECT quantity, reserved
-                  FROM warehouse_stock
-                  WHERE warehouse_id = $1 AND product_id = $2
-                  FOR UPDATE`
-
-	var dstQty, dstReserved int
-	err = tx.QueryRow(destQuery, toWarehouseID, productID).Scan(&dstQty, &dstReserved)
-	if err != nil {
-		return err
-	}
+	return moveTransferLine(tx, 0, fromWarehouseID, toWarehouseID, productID, qty, actor)
+}
 
+// moveTransferLine moves qty between two already locked warehouse_stock rows
+// and records both sides in the stock ledger
+func moveTransferLine(tx *sql.Tx, transferID, fromWarehouseID, toWarehouseID, productID, qty int, actor string) error {
 	// Update source
 	updateSrc := `UPDATE warehouse_stock
                   SET quantity = quantity - $1
                   WHERE warehouse_id = $2 AND product_id = $3`
 
-	_, err = tx.Exec(updateSrc, qty, fromWarehouseID, productID)
+	_, err := tx.Exec(updateSrc, qty, fromWarehouseID, productID)
 	if err != nil {
 		return err
 	}
@@ -118,5 +114,25 @@ func TransferStock(tx *sql.Tx, fromWarehouseID, toWarehouseID, productID, qty in
 		return err
 	}
 
-	return nil
+	// Record ledger entries
+	err = RecordStockMovement(tx, model.StockMovement{
+		WarehouseID:   fromWarehouseID,
+		ProductID:     productID,
+		Type:          MovementTransferOut,
+		QuantityDelta: -qty,
+		TransferID:    transferID,
+		Actor:         actor,
+	})
+	if err != nil {
+		return err
+	}
+
+	return RecordStockMovement(tx, model.StockMovement{
+		WarehouseID:   toWarehouseID,
+		ProductID:     productID,
+		Type:          MovementTransferIn,
+		QuantityDelta: qty,
+		TransferID:    transferID,
+		Actor:         actor,
+	})
 }
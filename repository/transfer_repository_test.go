@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/DATA-DOG/go-sqlmock"
@@ -188,3 +189,98 @@ func TestGetAvailableStock_QueryError(t *testing.T) {
 		t.Fatalf("avail should be 0 when error occurs, got %d", avail)
 	}
 }
+
+func TestTransferStock_RecordsLedger(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).
+		WithArgs(2, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT quantity, reserved FROM warehouse_stock WHERE warehouse_id = \$1 AND product_id = \$2 FOR UPDATE`).
+		WithArgs(1, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reserved"}).AddRow(10, 2))
+	mock.ExpectQuery(`SELECT quantity, reserved FROM warehouse_stock WHERE warehouse_id = \$1 AND product_id = \$2 FOR UPDATE`).
+		WithArgs(2, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reserved"}).AddRow(0, 0))
+	mock.ExpectExec(`UPDATE warehouse_stock SET quantity = quantity - \$1`).
+		WithArgs(5, 1, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE warehouse_stock SET quantity = quantity \+ \$1`).
+		WithArgs(5, 2, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 7, MovementTransferOut, -5, 0, nil, nil, "user:1", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(2, 7, MovementTransferIn, 5, 0, nil, nil, "user:1", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, _ := db.Begin()
+	if err := TransferStock(tx, 1, 2, 7, 5, "user:1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestTransferStock_NotEnoughAvailable(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).
+		WithArgs(2, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT quantity, reserved FROM warehouse_stock`).
+		WithArgs(1, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reserved"}).AddRow(10, 8))
+	mock.ExpectQuery(`SELECT quantity, reserved FROM warehouse_stock`).
+		WithArgs(2, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reserved"}).AddRow(0, 0))
+
+	tx, _ := db.Begin()
+	err := TransferStock(tx, 1, 2, 7, 5, "user:1")
+	if err == nil || !strings.HasPrefix(err.Error(), "not enough available stock") {
+		t.Fatalf("expected not enough stock error, got %v", err)
+	}
+}
+
+func TestTransferStock_LocksLowerWarehouseFirst(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	// B→A must lock in the same order as A→B: warehouse 1 before warehouse 2
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO warehouse_stock`).
+		WithArgs(1, 7).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT quantity, reserved FROM warehouse_stock WHERE warehouse_id = \$1 AND product_id = \$2 FOR UPDATE`).
+		WithArgs(1, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reserved"}).AddRow(0, 0))
+	mock.ExpectQuery(`SELECT quantity, reserved FROM warehouse_stock WHERE warehouse_id = \$1 AND product_id = \$2 FOR UPDATE`).
+		WithArgs(2, 7).
+		WillReturnRows(sqlmock.NewRows([]string{"quantity", "reserved"}).AddRow(10, 2))
+	mock.ExpectExec(`UPDATE warehouse_stock SET quantity = quantity - \$1`).
+		WithArgs(5, 2, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE warehouse_stock SET quantity = quantity \+ \$1`).
+		WithArgs(5, 1, 7).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(2, 7, MovementTransferOut, -5, 0, nil, nil, "user:1", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 7, MovementTransferIn, 5, 0, nil, nil, "user:1", "").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+
+	tx, _ := db.Begin()
+	if err := TransferStock(tx, 2, 1, 7, 5, "user:1"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Postgres aborts one of the transactions involved in a serialization failure or a
+// deadlock; the whole transaction can safely be run again.
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+)
+
+var (
+	txRetryAttempts = 5
+	txRetryBackoff  = 20 * time.Millisecond
+)
+
+// IsRetryableTxError reports whether err is a serialization failure or a deadlock
+func IsRetryableTxError(err error) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return false
+	}
+	return pqErr.Code == sqlStateSerializationFailure || pqErr.Code == sqlStateDeadlockDetected
+}
+
+// RunInTx runs fn in a transaction and commits it. When Postgres aborts the
+// transaction as a deadlock victim or on a serialization failure, it is retried
+// with exponential backoff and jitter.
+func RunInTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	backoff := txRetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := runInTxOnce(ctx, db, fn)
+		if err == nil || !IsRetryableTxError(err) || attempt >= txRetryAttempts {
+			return err
+		}
+
+		wait := backoff + time.Duration(rand.Int63n(int64(backoff)))
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		backoff *= 2
+	}
+}
+
+func runInTxOnce(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
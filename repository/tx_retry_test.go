@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func withFastTxRetry(t *testing.T) {
+	prev := txRetryBackoff
+	txRetryBackoff = time.Millisecond
+	t.Cleanup(func() { txRetryBackoff = prev })
+}
+
+func TestIsRetryableTxError(t *testing.T) {
+	cases := []struct {
+		err  error
+		want bool
+	}{
+		{&pq.Error{Code: "40001"}, true},
+		{&pq.Error{Code: "40P01"}, true},
+		{fmt.Errorf("wrapped: %w", &pq.Error{Code: "40P01"}), true},
+		{&pq.Error{Code: "23505"}, false},
+		{errors.New("deadlock"), false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		if got := IsRetryableTxError(c.err); got != c.want {
+			t.Errorf("IsRetryableTxError(%v) = %v, want %v", c.err, got, c.want)
+		}
+	}
+}
+
+func TestRunInTx_RetriesDeadlock(t *testing.T) {
+	withFastTxRetry(t)
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	calls := 0
+	err := RunInTx(context.Background(), db, func(tx *sql.Tx) error {
+		calls++
+		if calls == 1 {
+			return &pq.Error{Code: "40P01", Message: "deadlock detected"}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 attempts, got %d", calls)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("expectations not met: %v", err)
+	}
+}
+
+func TestRunInTx_GivesUpAfterMaxAttempts(t *testing.T) {
+	withFastTxRetry(t)
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	for i := 0; i < txRetryAttempts; i++ {
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+	}
+
+	calls := 0
+	err := RunInTx(context.Background(), db, func(tx *sql.Tx) error {
+		calls++
+		return &pq.Error{Code: "40001"}
+	})
+	if !IsRetryableTxError(err) {
+		t.Fatalf("expected serialization failure, got %v", err)
+	}
+	if calls != txRetryAttempts {
+		t.Fatalf("expected %d attempts, got %d", txRetryAttempts, calls)
+	}
+}
+
+func TestRunInTx_DoesNotRetryOtherErrors(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	calls := 0
+	err := RunInTx(context.Background(), db, func(tx *sql.Tx) error {
+		calls++
+		return errors.New("not enough available stock")
+	})
+	if err == nil || calls != 1 {
+		t.Fatalf("expected a single failed attempt, got calls=%d err=%v", calls, err)
+	}
+}
@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"order-service-sample/model"
+
+	"github.com/lib/pq"
+)
+
+const sqlStateUniqueViolation = "23505"
+
+// CreateUser inserts a new user. A taken email or phone is reported as
+// "email_taken" / "phone_taken", based on the UNIQUE constraints of users.
+func CreateUser(db *sql.DB, email, phone, passwordHash string) (int, error) {
+	var id int
+	err := db.QueryRow(`
+		INSERT INTO users (email, phone, password_hash)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, email, phone, passwordHash).Scan(&id)
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == sqlStateUniqueViolation {
+		switch pqErr.Constraint {
+		case "users_email_key":
+			return 0, errors.New("email_taken")
+		case "users_phone_key":
+			return 0, errors.New("phone_taken")
+		}
+	}
+	return id, err
+}
+
+// GetUserProfile returns a user with its verification state
+func GetUserProfile(db *sql.DB, userID int) (model.UserResp, error) {
+	var u model.UserResp
+	err := db.QueryRow(`
+		SELECT id, email, COALESCE(phone, ''), email_verified_at IS NOT NULL, phone_verified_at IS NOT NULL, role
+		FROM users
+		WHERE id = $1
+	`, userID).Scan(&u.ID, &u.Email, &u.Phone, &u.EmailVerified, &u.PhoneVerified, &u.Role)
+	if err == sql.ErrNoRows {
+		return u, errors.New("user_not_found")
+	}
+	return u, err
+}
+
+// MarkUserVerified records that the user proved ownership of the email or phone
+func MarkUserVerified(db *sql.DB, userID int, channel string) error {
+	column := "email_verified_at"
+	if channel == "phone" {
+		column = "phone_verified_at"
+	}
+	_, err := db.Exec(fmt.Sprintf(`UPDATE users SET %s = COALESCE(%s, NOW()) WHERE id = $1`, column, column), userID)
+	return err
+}
+
+// UpdateUserPassword replaces the password hash of a user
+func UpdateUserPassword(db *sql.DB, userID int, passwordHash string) error {
+	res, err := db.Exec(`UPDATE users SET password_hash = $1 WHERE id = $2`, passwordHash, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("user_not_found")
+	}
+	return nil
+}
+
+// GetUserRole returns the current role of a user, read again on every token refresh
+// so role changes take effect without a new login
+func GetUserRole(db *sql.DB, userID int) (string, error) {
+	var role string
+	err := db.QueryRow(`SELECT role FROM users WHERE id = $1`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return "", errors.New("user_not_found")
+	}
+	return role, err
+}
+
+// SetUserRole changes the role of a user
+func SetUserRole(db *sql.DB, userID int, role string) error {
+	res, err := db.Exec(`UPDATE users SET role = $1 WHERE id = $2`, role, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return errors.New("user_not_found")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+)
+
+func TestCreateUser_Success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO users \(email, phone, password_hash\)`).
+		WithArgs("budi@example.com", "08123456789", "hash").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(5))
+
+	id, err := CreateUser(db, "budi@example.com", "08123456789", "hash")
+	if err != nil || id != 5 {
+		t.Fatalf("expected id 5, got %d, %v", id, err)
+	}
+}
+
+func TestCreateUser_UniqueViolation(t *testing.T) {
+	cases := map[string]string{
+		"users_email_key": "email_taken",
+		"users_phone_key": "phone_taken",
+	}
+	for constraint, want := range cases {
+		db, mock, _ := sqlmock.New()
+
+		mock.ExpectQuery(`INSERT INTO users`).
+			WillReturnError(&pq.Error{Code: "23505", Constraint: constraint})
+
+		_, err := CreateUser(db, "budi@example.com", "08123456789", "hash")
+		if err == nil || err.Error() != want {
+			t.Errorf("%s: expected %s, got %v", constraint, want, err)
+		}
+		db.Close()
+	}
+}
+
+func TestCreateUser_OtherError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO users`).WillReturnError(errors.New("db down"))
+
+	if _, err := CreateUser(db, "a@example.com", "08123456789", "hash"); err == nil || err.Error() != "db down" {
+		t.Fatalf("expected db error, got %v", err)
+	}
+}
+
+func TestMarkUserVerified_Phone(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE users SET phone_verified_at = COALESCE\(phone_verified_at, NOW\(\)\) WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	if err := MarkUserVerified(db, 5, "phone"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestUpdateUserPassword_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE users SET password_hash`).
+		WithArgs("hash", 9).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := UpdateUserPassword(db, 9, "hash"); err == nil || err.Error() != "user_not_found" {
+		t.Fatalf("expected user_not_found, got %v", err)
+	}
+}
+
+func TestSetUserRole_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectExec(`UPDATE users SET role = \$1 WHERE id = \$2`).
+		WithArgs("admin", 404).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := SetUserRole(db, 404, "admin"); err == nil || err.Error() != "user_not_found" {
+		t.Fatalf("expected user_not_found, got %v", err)
+	}
+}
+
+func TestGetUserRole(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT role FROM users WHERE id = \$1`).
+		WithArgs(5).
+		WillReturnRows(sqlmock.NewRows([]string{"role"}).AddRow("warehouse_operator"))
+
+	role, err := GetUserRole(db, 5)
+	if err != nil || role != "warehouse_operator" {
+		t.Fatalf("expected warehouse_operator, got %q, %v", role, err)
+	}
+}
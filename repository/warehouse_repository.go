@@ -1,8 +1,13 @@
 package repository
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+
+	"order-service-sample/helper"
+	"order-service-sample/model"
 )
 
 func WarehouseExists(db *sql.DB, warehouseID int) (bool, error) {
@@ -47,3 +52,269 @@ func UpdateWarehouseStatus(db *sql.DB, warehouseID int, status string) error {
 
 	return nil
 }
+
+const warehouseColumns = `id, name, COALESCE(address, ''), COALESCE(region, ''), COALESCE(capacity, 0), active, created_at`
+
+func scanWarehouse(row interface{ Scan(...any) error }, w *model.Warehouse) error {
+	return row.Scan(&w.ID, &w.Name, &w.Address, &w.Region, &w.Capacity, &w.Active, &w.CreatedAt)
+}
+
+// ListWarehouses returns warehouses with their stock totals.
+// When active is not nil only warehouses with that status are returned.
+func ListWarehouses(db *sql.DB, active *bool) ([]model.WarehouseSummary, error) {
+	rows, err := db.Query(`
+		SELECT w.id, w.name, COALESCE(w.address, ''), COALESCE(w.region, ''), COALESCE(w.capacity, 0), w.active, w.created_at,
+		       COUNT(ws.id),
+		       COALESCE(SUM(ws.quantity), 0),
+		       COALESCE(SUM(ws.reserved), 0),
+		       COALESCE(SUM(ws.in_transit), 0)
+		FROM warehouses w
+		LEFT JOIN warehouse_stock ws ON ws.warehouse_id = w.id
+		WHERE ($1::BOOLEAN IS NULL OR w.active = $1)
+		GROUP BY w.id
+		ORDER BY w.id
+	`, active)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	warehouses := []model.WarehouseSummary{}
+	for rows.Next() {
+		var s model.WarehouseSummary
+		if err := rows.Scan(&s.ID, &s.Name, &s.Address, &s.Region, &s.Capacity, &s.Active, &s.CreatedAt,
+			&s.ProductCount, &s.TotalQuantity, &s.TotalReserved, &s.TotalInTransit); err != nil {
+			return nil, err
+		}
+		s.TotalAvailable = s.TotalQuantity - s.TotalReserved
+		warehouses = append(warehouses, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return warehouses, nil
+}
+
+// GetWarehouseStock lists the stock rows of one warehouse with available = quantity - reserved
+func GetWarehouseStock(db *sql.DB, warehouseID int) ([]model.WarehouseStockRow, error) {
+	rows, err := db.Query(`
+		SELECT ws.warehouse_id, w.name, ws.product_id, p.name, ws.quantity, ws.reserved, ws.in_transit
+		FROM warehouse_stock ws
+		JOIN warehouses w ON w.id = ws.warehouse_id
+		JOIN products p ON p.id = ws.product_id
+		WHERE ws.warehouse_id = $1
+		ORDER BY ws.product_id
+	`, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	items := []model.WarehouseStockRow{}
+	for rows.Next() {
+		var it model.WarehouseStockRow
+		if err := rows.Scan(&it.WarehouseID, &it.WarehouseName, &it.ProductID, &it.ProductName, &it.Quantity, &it.Reserved, &it.InTransit); err != nil {
+			return nil, err
+		}
+		it.Available = it.Quantity - it.Reserved
+		items = append(items, it)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
+
+func CreateWarehouse(db *sql.DB, req model.CreateWarehouseReq) (model.Warehouse, error) {
+	var w model.Warehouse
+	err := scanWarehouse(db.QueryRow(`
+		INSERT INTO warehouses (name, address, region, capacity, active)
+		VALUES ($1, $2, $3, $4, TRUE)
+		RETURNING `+warehouseColumns,
+		req.Name, req.Address, req.Region, req.Capacity), &w)
+
+	return w, err
+}
+
+// UpdateWarehouse changes only the fields that are set in req
+func UpdateWarehouse(db *sql.DB, warehouseID int, req model.UpdateWarehouseReq) (model.Warehouse, error) {
+	var w model.Warehouse
+	err := scanWarehouse(db.QueryRow(`
+		UPDATE warehouses
+		SET name = COALESCE($1, name),
+		    address = COALESCE($2, address),
+		    region = COALESCE($3, region),
+		    capacity = COALESCE($4, capacity)
+		WHERE id = $5
+		RETURNING `+warehouseColumns,
+		req.Name, req.Address, req.Region, req.Capacity, warehouseID), &w)
+
+	if err == sql.ErrNoRows {
+		return w, errors.New("warehouse_not_found")
+	}
+	return w, err
+}
+
+// Deactivation policies for reservations still held in the warehouse
+const (
+	DeactivateBlock    = "block"    // refuse while reservations exist
+	DeactivateComplete = "complete" // keep reservations, they can still be paid or expire
+	DeactivateReassign = "reassign" // move reservations to other active warehouses
+)
+
+// DeactivateWarehouse marks a warehouse inactive and applies the policy to the
+// reservations it still holds. The affected reservations are returned; with the
+// block policy they are returned together with a warehouse_has_reservations error.
+func DeactivateWarehouse(ctx context.Context, db *sql.DB, warehouseID int, policy string) ([]model.AffectedReservation, error) {
+	switch policy {
+	case DeactivateBlock, DeactivateComplete, DeactivateReassign:
+	default:
+		return nil, errors.New("invalid_policy")
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// 1. Lock warehouse: ReserveStockForOrder memegang FOR SHARE pada baris ini, jadi
+	// reservasi yang sedang berjalan selesai dulu dan reservasi baru menunggu lalu melewatinya
+	var active bool
+	err = tx.QueryRow(`SELECT active FROM warehouses WHERE id = $1 FOR UPDATE`, warehouseID).Scan(&active)
+	if err == sql.ErrNoRows {
+		return nil, errors.New("warehouse_not_found")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Ambil reservasi yang masih ada di warehouse ini
+	rows, err := tx.Query(`
+		SELECT id, order_id, product_id, quantity
+		FROM reservations
+		WHERE warehouse_id = $1
+		ORDER BY order_id, id
+		FOR UPDATE
+	`, warehouseID)
+	if err != nil {
+		return nil, err
+	}
+
+	type heldReservation struct {
+		ID int
+		model.AffectedReservation
+	}
+	var held []heldReservation
+	for rows.Next() {
+		var h heldReservation
+		if err := rows.Scan(&h.ID, &h.OrderID, &h.ProductID, &h.Quantity); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		h.FromWarehouseID = warehouseID
+		held = append(held, h)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	affected := []model.AffectedReservation{}
+
+	// 3. Terapkan policy
+	for _, h := range held {
+		switch policy {
+		case DeactivateBlock:
+			h.Action = "blocked"
+		case DeactivateComplete:
+			h.Action = "kept"
+		case DeactivateReassign:
+			toWarehouseID, err := moveReservation(tx, h.ID, h.OrderID, warehouseID, h.ProductID, h.Quantity, helper.ActorFromContext(ctx))
+			if err != nil {
+				return append(affected, h.AffectedReservation), err
+			}
+			h.Action = "moved"
+			h.ToWarehouseID = toWarehouseID
+		}
+		affected = append(affected, h.AffectedReservation)
+	}
+
+	if policy == DeactivateBlock && len(affected) > 0 {
+		return affected, errors.New("warehouse_has_reservations")
+	}
+
+	// 4. Nonaktifkan warehouse
+	if _, err := tx.Exec(`UPDATE warehouses SET active = FALSE WHERE id = $1`, warehouseID); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return affected, nil
+}
+
+// moveReservation re-reserves a reservation in the first other active warehouse
+// that has enough available stock and returns that warehouse id.
+func moveReservation(tx *sql.Tx, reservationID, orderID, fromWarehouseID, productID, qty int, actor string) (int, error) {
+	var toWarehouseID int
+	err := tx.QueryRow(`
+		SELECT ws.warehouse_id
+		FROM warehouse_stock ws
+		JOIN warehouses w ON w.id = ws.warehouse_id
+		WHERE w.active = TRUE
+		AND ws.warehouse_id <> $1
+		AND ws.product_id = $2
+		AND (ws.quantity - ws.reserved) >= $3
+		ORDER BY ws.warehouse_id
+		LIMIT 1
+		FOR UPDATE OF ws
+	`, fromWarehouseID, productID, qty).Scan(&toWarehouseID)
+	if err == sql.ErrNoRows {
+		return 0, errors.New("cannot_reassign_reservation")
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE warehouse_stock
+		SET reserved = reserved - $1, updated_at = NOW()
+		WHERE warehouse_id = $2 AND product_id = $3
+	`, qty, fromWarehouseID, productID); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`
+		UPDATE warehouse_stock
+		SET reserved = reserved + $1, updated_at = NOW()
+		WHERE warehouse_id = $2 AND product_id = $3
+	`, qty, toWarehouseID, productID); err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.Exec(`UPDATE reservations SET warehouse_id = $1 WHERE id = $2`, toWarehouseID, reservationID); err != nil {
+		return 0, err
+	}
+
+	note := fmt.Sprintf("reservation moved from warehouse %d to %d", fromWarehouseID, toWarehouseID)
+	for _, m := range []model.StockMovement{
+		{WarehouseID: fromWarehouseID, Type: MovementRelease, ReservedDelta: -qty},
+		{WarehouseID: toWarehouseID, Type: MovementReserve, ReservedDelta: qty},
+	} {
+		m.ProductID = productID
+		m.OrderID = orderID
+		m.Actor = actor
+		m.Note = note
+		if err := RecordStockMovement(tx, m); err != nil {
+			return 0, err
+		}
+	}
+
+	return toWarehouseID, nil
+}
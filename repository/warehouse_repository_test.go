@@ -1,9 +1,14 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"regexp"
 	"testing"
+	"time"
+
+	"order-service-sample/model"
 
 	"github.com/DATA-DOG/go-sqlmock"
 )
@@ -194,3 +199,288 @@ func TestUpdateWarehouseStatus_RowsAffectedError(t *testing.T) {
 		t.Fatalf("expected rows_failed, got %v", err)
 	}
 }
+
+// --------------------------------------------------
+// TEST ListWarehouses / GetWarehouseStock
+// --------------------------------------------------
+
+func TestListWarehouses_WithTotals(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	now := time.Now()
+	rows := sqlmock.NewRows([]string{
+		"id", "name", "address", "region", "capacity", "active", "created_at",
+		"count", "quantity", "reserved", "in_transit",
+	}).
+		AddRow(1, "Central", "Jl. A", "central", 1000, true, now, 3, 45, 5, 6).
+		AddRow(2, "Jakarta", "", "", 0, true, now, 0, 0, 0, 0)
+
+	active := true
+	mock.ExpectQuery(`SELECT w.id, w.name, .*FROM warehouses w LEFT JOIN warehouse_stock ws .*GROUP BY w.id`).
+		WithArgs(&active).
+		WillReturnRows(rows)
+
+	warehouses, err := ListWarehouses(db, &active)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(warehouses) != 2 {
+		t.Fatalf("expected 2 warehouses, got %d", len(warehouses))
+	}
+	if warehouses[0].TotalAvailable != 40 || warehouses[0].ProductCount != 3 || warehouses[0].TotalInTransit != 6 {
+		t.Fatalf("unexpected totals: %+v", warehouses[0])
+	}
+}
+
+func TestListWarehouses_QueryError(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`SELECT w.id`).WillReturnError(errors.New("db down"))
+
+	if _, err := ListWarehouses(db, nil); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestGetWarehouseStock(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	rows := sqlmock.NewRows([]string{"warehouse_id", "name", "product_id", "name", "quantity", "reserved", "in_transit"}).
+		AddRow(1, "Central", 1, "Mouse", 20, 4, 3)
+
+	mock.ExpectQuery(`SELECT ws.warehouse_id, .*WHERE ws.warehouse_id = \$1`).
+		WithArgs(1).
+		WillReturnRows(rows)
+
+	stock, err := GetWarehouseStock(db, 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(stock) != 1 || stock[0].Available != 16 || stock[0].InTransit != 3 {
+		t.Fatalf("unexpected stock: %+v", stock)
+	}
+}
+
+// --------------------------------------------------
+// TEST CreateWarehouse / UpdateWarehouse
+// --------------------------------------------------
+
+func warehouseRow(id int, name string) *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"id", "name", "address", "region", "capacity", "active", "created_at"}).
+		AddRow(id, name, "Jl. B", "bandung", 500, true, time.Now())
+}
+
+func TestCreateWarehouse_Success(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectQuery(`INSERT INTO warehouses \(name, address, region, capacity, active\)`).
+		WithArgs("Bandung", "Jl. B", "bandung", 500).
+		WillReturnRows(warehouseRow(4, "Bandung"))
+
+	wh, err := CreateWarehouse(db, model.CreateWarehouseReq{
+		Name: "Bandung", Address: "Jl. B", Region: "bandung", Capacity: 500,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if wh.ID != 4 || !wh.Active {
+		t.Fatalf("unexpected warehouse: %+v", wh)
+	}
+}
+
+func TestUpdateWarehouse_Partial(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	capacity := 800
+	mock.ExpectQuery(regexp.QuoteMeta(`SET name = COALESCE($1, name),`)).
+		WithArgs(nil, nil, nil, &capacity, 4).
+		WillReturnRows(warehouseRow(4, "Bandung"))
+
+	wh, err := UpdateWarehouse(db, 4, model.UpdateWarehouseReq{Capacity: &capacity})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if wh.ID != 4 {
+		t.Fatalf("unexpected warehouse: %+v", wh)
+	}
+}
+
+func TestUpdateWarehouse_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	name := "X"
+	mock.ExpectQuery(`UPDATE warehouses`).
+		WithArgs(&name, nil, nil, nil, 99).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err := UpdateWarehouse(db, 99, model.UpdateWarehouseReq{Name: &name})
+	if err == nil || err.Error() != "warehouse_not_found" {
+		t.Fatalf("expected warehouse_not_found, got %v", err)
+	}
+}
+
+// --------------------------------------------------
+// TEST DeactivateWarehouse
+// --------------------------------------------------
+
+func expectHeldReservations(mock sqlmock.Sqlmock, warehouseID int, rows *sqlmock.Rows) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT active FROM warehouses WHERE id = $1 FOR UPDATE`)).
+		WithArgs(warehouseID).
+		WillReturnRows(sqlmock.NewRows([]string{"active"}).AddRow(true))
+	mock.ExpectQuery(`SELECT id, order_id, product_id, quantity FROM reservations WHERE warehouse_id = \$1`).
+		WithArgs(warehouseID).
+		WillReturnRows(rows)
+}
+
+func TestDeactivateWarehouse_InvalidPolicy(t *testing.T) {
+	db, _, _ := sqlmock.New()
+	defer db.Close()
+
+	_, err := DeactivateWarehouse(context.Background(), db, 1, "drop")
+	if err == nil || err.Error() != "invalid_policy" {
+		t.Fatalf("expected invalid_policy, got %v", err)
+	}
+}
+
+func TestDeactivateWarehouse_NotFound(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT active FROM warehouses`).
+		WithArgs(9).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	_, err := DeactivateWarehouse(context.Background(), db, 9, DeactivateBlock)
+	if err == nil || err.Error() != "warehouse_not_found" {
+		t.Fatalf("expected warehouse_not_found, got %v", err)
+	}
+}
+
+func TestDeactivateWarehouse_BlockWithReservations(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectHeldReservations(mock, 1, sqlmock.NewRows([]string{"id", "order_id", "product_id", "quantity"}).
+		AddRow(10, 100, 1, 2))
+	mock.ExpectRollback()
+
+	affected, err := DeactivateWarehouse(context.Background(), db, 1, DeactivateBlock)
+	if err == nil || err.Error() != "warehouse_has_reservations" {
+		t.Fatalf("expected warehouse_has_reservations, got %v", err)
+	}
+	if len(affected) != 1 || affected[0].OrderID != 100 || affected[0].Action != "blocked" {
+		t.Fatalf("unexpected affected: %+v", affected)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeactivateWarehouse_BlockWithoutReservations(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectHeldReservations(mock, 1, sqlmock.NewRows([]string{"id", "order_id", "product_id", "quantity"}))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE warehouses SET active = FALSE WHERE id = $1`)).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	affected, err := DeactivateWarehouse(context.Background(), db, 1, DeactivateBlock)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(affected) != 0 {
+		t.Fatalf("expected no affected orders, got %+v", affected)
+	}
+}
+
+func TestDeactivateWarehouse_CompleteKeepsReservations(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectHeldReservations(mock, 1, sqlmock.NewRows([]string{"id", "order_id", "product_id", "quantity"}).
+		AddRow(10, 100, 1, 2))
+	mock.ExpectExec(`UPDATE warehouses SET active = FALSE`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	affected, err := DeactivateWarehouse(context.Background(), db, 1, DeactivateComplete)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(affected) != 1 || affected[0].Action != "kept" {
+		t.Fatalf("unexpected affected: %+v", affected)
+	}
+}
+
+func TestDeactivateWarehouse_ReassignMovesReservation(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectHeldReservations(mock, 1, sqlmock.NewRows([]string{"id", "order_id", "product_id", "quantity"}).
+		AddRow(10, 100, 5, 3))
+	mock.ExpectQuery(`SELECT ws.warehouse_id FROM warehouse_stock ws .*ws.warehouse_id <> \$1`).
+		WithArgs(1, 5, 3).
+		WillReturnRows(sqlmock.NewRows([]string{"warehouse_id"}).AddRow(3))
+	mock.ExpectExec(`UPDATE warehouse_stock SET reserved = reserved - \$1`).
+		WithArgs(3, 1, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`UPDATE warehouse_stock SET reserved = reserved \+ \$1`).
+		WithArgs(3, 3, 5).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE reservations SET warehouse_id = $1 WHERE id = $2`)).
+		WithArgs(3, 10).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(1, 5, MovementRelease, 0, -3, int64(100), nil, "system", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO stock_movements`).
+		WithArgs(3, 5, MovementReserve, 0, 3, int64(100), nil, "system", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`UPDATE warehouses SET active = FALSE`).
+		WithArgs(1).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	affected, err := DeactivateWarehouse(context.Background(), db, 1, DeactivateReassign)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(affected) != 1 || affected[0].Action != "moved" || affected[0].ToWarehouseID != 3 {
+		t.Fatalf("unexpected affected: %+v", affected)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestDeactivateWarehouse_ReassignNoCapacity(t *testing.T) {
+	db, mock, _ := sqlmock.New()
+	defer db.Close()
+
+	expectHeldReservations(mock, 1, sqlmock.NewRows([]string{"id", "order_id", "product_id", "quantity"}).
+		AddRow(10, 100, 5, 3))
+	mock.ExpectQuery(`SELECT ws.warehouse_id FROM warehouse_stock ws`).
+		WithArgs(1, 5, 3).
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectRollback()
+
+	affected, err := DeactivateWarehouse(context.Background(), db, 1, DeactivateReassign)
+	if err == nil || err.Error() != "cannot_reassign_reservation" {
+		t.Fatalf("expected cannot_reassign_reservation, got %v", err)
+	}
+	if len(affected) != 1 || affected[0].OrderID != 100 {
+		t.Fatalf("expected the failing order to be reported, got %+v", affected)
+	}
+}
@@ -3,6 +3,40 @@
 -- Drop tables in correct dependency order
 -- ==========================================
 
+DROP TABLE IF EXISTS user_addresses CASCADE;
+
+DROP TABLE IF EXISTS user_recovery_codes CASCADE;
+
+DROP TABLE IF EXISTS user_identities CASCADE;
+
+DROP TABLE IF EXISTS api_keys CASCADE;
+
+DROP TABLE IF EXISTS audit_log CASCADE;
+
+DROP TABLE IF EXISTS refresh_tokens CASCADE;
+
+DROP TABLE IF EXISTS transfer_items CASCADE;
+
+DROP TABLE IF EXISTS transfers CASCADE;
+
+DROP TABLE IF EXISTS stock_adjustments CASCADE;
+
+DROP TABLE IF EXISTS goods_receipt_items CASCADE;
+
+DROP TABLE IF EXISTS goods_receipts CASCADE;
+
+DROP TABLE IF EXISTS stock_movements CASCADE;
+
+DROP FUNCTION IF EXISTS stock_movements_append_only();
+
+DROP TABLE IF EXISTS cart_items CASCADE;
+
+DROP TABLE IF EXISTS product_images CASCADE;
+
+DROP TABLE IF EXISTS product_categories CASCADE;
+
+DROP TABLE IF EXISTS categories CASCADE;
+
 DROP TABLE IF EXISTS order_items CASCADE;
 
 DROP TABLE IF EXISTS reservations CASCADE;
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+
+	"order-service-sample/account"
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/repository"
+)
+
+func refreshTokenTTL() time.Duration {
+	return helper.GetEnvDuration("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+}
+
+// issueTokens creates an access token and a new refresh token for a user
+func issueTokens(userID int, role string, mfa bool) (model.TokenResp, error) {
+	access, err := helper.GenerateJWTWithMFA(userID, role, mfa)
+	if err != nil {
+		return model.TokenResp{}, err
+	}
+
+	refresh, err := account.NewToken()
+	if err != nil {
+		return model.TokenResp{}, err
+	}
+	expiresAt := time.Now().Add(refreshTokenTTL())
+	if err := repository.CreateRefreshToken(db, userID, account.HashToken(refresh), expiresAt); err != nil {
+		return model.TokenResp{}, err
+	}
+
+	return model.TokenResp{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(helper.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+func RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.RefreshTokenReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "refresh_token is required")
+		return
+	}
+
+	refresh, err := account.NewToken()
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	expiresAt := time.Now().Add(refreshTokenTTL())
+
+	// token lama yang dipakai ulang membuat semua sesi user dicabut (lihat RotateRefreshToken)
+	userID, err := repository.RotateRefreshToken(r.Context(), db, account.HashToken(req.RefreshToken), account.HashToken(refresh), expiresAt)
+	if err != nil {
+		switch err.Error() {
+		case "refresh_token_invalid", "refresh_token_reused":
+			helper.WriteErrorJSON(w, http.StatusUnauthorized, "invalid or expired refresh token")
+		default:
+			helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to refresh token")
+		}
+		return
+	}
+
+	role, err := repository.GetUserRole(db, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	// refresh token user 2FA hanya bisa didapat lewat /login/2fa (token lama dicabut saat 2FA aktif)
+	totp, err := repository.GetTOTPState(db, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	access, err := helper.GenerateJWTWithMFA(userID, role, totp.Enabled)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, model.TokenResp{
+		Token:        access,
+		RefreshToken: refresh,
+		ExpiresIn:    int(helper.AccessTokenTTL().Seconds()),
+	})
+}
+
+// LogoutHandler revokes the access token of the request and, when given, the refresh token
+func LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID := helper.GetUserIDFromContext(ctx)
+
+	var req model.RefreshTokenReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	if req.RefreshToken != "" {
+		if err := repository.RevokeRefreshToken(db, userID, account.HashToken(req.RefreshToken)); err != nil {
+			helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to revoke refresh token")
+			return
+		}
+	}
+
+	claims := helper.GetTokenClaimsFromContext(ctx)
+	jti, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if jti != "" && err == nil && exp != nil {
+		if err := tokenDenylist.Deny(ctx, jti, time.Until(exp.Time)); err != nil {
+			helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to revoke token")
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// JWKSHandler publishes the public keys access tokens can be verified with
+func JWKSHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	helper.WriteJSON(w, http.StatusOK, helper.PublicJWKS())
+}
@@ -0,0 +1,192 @@
+// Package shipping prices the delivery of an order. Every warehouse region an order
+// is reserved from becomes its own parcel; the cost of a method is the sum over the
+// parcels and the estimate is the slowest parcel.
+package shipping
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+
+	"order-service-sample/model"
+)
+
+const (
+	MethodRegular = "regular"
+	MethodExpress = "express"
+)
+
+// Methods lists the supported methods in the order they are offered
+var Methods = []string{MethodRegular, MethodExpress}
+
+// Wildcard matches any origin or destination region in a Rate
+const Wildcard = "*"
+
+// VolumetricDivisor converts a volume in mm³ to a volumetric weight in grams,
+// the usual 6000 cm³ per kg of the couriers
+const VolumetricDivisor = 6000
+
+func IsValidMethod(method string) bool {
+	for _, m := range Methods {
+		if m == method {
+			return true
+		}
+	}
+	return false
+}
+
+// Quote is the price of one parcel
+type Quote struct {
+	Cost          int64
+	EstimatedDays int
+}
+
+// ShippingRateProvider prices a parcel of weightGrams from the origin warehouse
+// region to the destination region. A route without a rate returns "no_shipping_rate".
+type ShippingRateProvider interface {
+	Rate(origin, destination string, weightGrams int, method string) (Quote, error)
+}
+
+// Rate is one row of a rate table. Cost (in cents) applies up to MaxWeightGrams; the
+// heaviest row of a route also charges ExtraPerKg for every started kg above it.
+type Rate struct {
+	Origin         string `json:"origin"`
+	Destination    string `json:"destination"`
+	Method         string `json:"method"`
+	MaxWeightGrams int    `json:"max_weight_grams"`
+	Cost           int64  `json:"cost"`
+	ExtraPerKg     int64  `json:"extra_per_kg"`
+	EstimatedDays  int    `json:"estimated_days"`
+}
+
+// TableRateProvider looks rates up in a table. A route with an exact origin and
+// destination wins over one that uses the Wildcard.
+type TableRateProvider struct {
+	rates []Rate
+}
+
+func NewTableRateProvider(rates []Rate) *TableRateProvider {
+	sorted := append([]Rate(nil), rates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].MaxWeightGrams < sorted[j].MaxWeightGrams
+	})
+	return &TableRateProvider{rates: sorted}
+}
+
+func (p *TableRateProvider) Rate(origin, destination string, weightGrams int, method string) (Quote, error) {
+	// urutan: rute persis, lalu origin bebas, lalu destination bebas, lalu keduanya bebas
+	routes := [][2]string{
+		{origin, destination},
+		{Wildcard, destination},
+		{origin, Wildcard},
+		{Wildcard, Wildcard},
+	}
+	for _, route := range routes {
+		var heaviest *Rate
+		for i := range p.rates {
+			r := &p.rates[i]
+			if r.Origin != route[0] || r.Destination != route[1] || r.Method != method {
+				continue
+			}
+			if weightGrams <= r.MaxWeightGrams {
+				return Quote{Cost: r.Cost, EstimatedDays: r.EstimatedDays}, nil
+			}
+			heaviest = r
+		}
+		if heaviest == nil {
+			continue
+		}
+		if heaviest.ExtraPerKg == 0 {
+			return Quote{}, errors.New("no_shipping_rate")
+		}
+		extraKg := (weightGrams - heaviest.MaxWeightGrams + 999) / 1000
+		return Quote{
+			Cost:          heaviest.Cost + int64(extraKg)*heaviest.ExtraPerKg,
+			EstimatedDays: heaviest.EstimatedDays,
+		}, nil
+	}
+	return Quote{}, errors.New("no_shipping_rate")
+}
+
+// DefaultRates is used when SHIPPING_RATES_FILE is not set. Costs are in cents (Rupiah × 100).
+var DefaultRates = []Rate{
+	// dalam satu region
+	{Origin: "jakarta", Destination: "jakarta", Method: MethodRegular, MaxWeightGrams: 1000, Cost: 900000, ExtraPerKg: 500000, EstimatedDays: 2},
+	{Origin: "jakarta", Destination: "jakarta", Method: MethodExpress, MaxWeightGrams: 1000, Cost: 1800000, ExtraPerKg: 900000, EstimatedDays: 1},
+	{Origin: "surabaya", Destination: "surabaya", Method: MethodRegular, MaxWeightGrams: 1000, Cost: 900000, ExtraPerKg: 500000, EstimatedDays: 2},
+	{Origin: "surabaya", Destination: "surabaya", Method: MethodExpress, MaxWeightGrams: 1000, Cost: 1800000, ExtraPerKg: 900000, EstimatedDays: 1},
+	{Origin: "central", Destination: "central", Method: MethodRegular, MaxWeightGrams: 1000, Cost: 900000, ExtraPerKg: 500000, EstimatedDays: 2},
+	{Origin: "central", Destination: "central", Method: MethodExpress, MaxWeightGrams: 1000, Cost: 1800000, ExtraPerKg: 900000, EstimatedDays: 1},
+
+	// antar region
+	{Origin: Wildcard, Destination: Wildcard, Method: MethodRegular, MaxWeightGrams: 1000, Cost: 1500000, EstimatedDays: 4},
+	{Origin: Wildcard, Destination: Wildcard, Method: MethodRegular, MaxWeightGrams: 5000, Cost: 4500000, ExtraPerKg: 800000, EstimatedDays: 4},
+	{Origin: Wildcard, Destination: Wildcard, Method: MethodExpress, MaxWeightGrams: 1000, Cost: 3000000, EstimatedDays: 2},
+	{Origin: Wildcard, Destination: Wildcard, Method: MethodExpress, MaxWeightGrams: 5000, Cost: 9000000, ExtraPerKg: 1500000, EstimatedDays: 2},
+}
+
+// LoadRatesFile reads a JSON array of Rate
+func LoadRatesFile(path string) ([]Rate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rates []Rate
+	if err := json.Unmarshal(data, &rates); err != nil {
+		return nil, err
+	}
+	for _, r := range rates {
+		if r.Origin == "" || r.Destination == "" || !IsValidMethod(r.Method) || r.MaxWeightGrams <= 0 || r.Cost < 0 {
+			return nil, errors.New("invalid shipping rate row")
+		}
+	}
+	return rates, nil
+}
+
+// ChargeableWeight is the larger of the actual and the volumetric weight of a line
+func ChargeableWeight(line model.ShipmentLine) int {
+	volumetric := line.LengthMM * line.WidthMM * line.HeightMM / VolumetricDivisor
+	weight := line.WeightGrams
+	if volumetric > weight {
+		weight = volumetric
+	}
+	return weight * line.Qty
+}
+
+// Options prices every method for the lines shipped to destination. Methods that
+// cannot be priced for every parcel are left out.
+func Options(provider ShippingRateProvider, destination string, lines []model.ShipmentLine) ([]model.ShippingOption, error) {
+	weights := map[string]int{}
+	origins := []string{}
+	for _, line := range lines {
+		if _, ok := weights[line.OriginRegion]; !ok {
+			origins = append(origins, line.OriginRegion)
+		}
+		weights[line.OriginRegion] += ChargeableWeight(line)
+	}
+
+	options := []model.ShippingOption{}
+	for _, method := range Methods {
+		option := model.ShippingOption{Method: method}
+		available := true
+		for _, origin := range origins {
+			q, err := provider.Rate(origin, destination, weights[origin], method)
+			if err != nil && err.Error() == "no_shipping_rate" {
+				available = false
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			option.Cost += q.Cost
+			if q.EstimatedDays > option.EstimatedDays {
+				option.EstimatedDays = q.EstimatedDays
+			}
+		}
+		if available {
+			options = append(options, option)
+		}
+	}
+	return options, nil
+}
@@ -0,0 +1,106 @@
+package shipping
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"order-service-sample/model"
+)
+
+var testRates = []Rate{
+	{Origin: "jakarta", Destination: "jakarta", Method: MethodRegular, MaxWeightGrams: 1000, Cost: 100, ExtraPerKg: 50, EstimatedDays: 2},
+	{Origin: Wildcard, Destination: Wildcard, Method: MethodRegular, MaxWeightGrams: 5000, Cost: 400, EstimatedDays: 4},
+	{Origin: Wildcard, Destination: Wildcard, Method: MethodRegular, MaxWeightGrams: 1000, Cost: 200, EstimatedDays: 4},
+	{Origin: Wildcard, Destination: "jakarta", Method: MethodExpress, MaxWeightGrams: 1000, Cost: 500, EstimatedDays: 1},
+}
+
+func TestTableRateProvider_Rate(t *testing.T) {
+	p := NewTableRateProvider(testRates)
+
+	cases := []struct {
+		name        string
+		origin      string
+		destination string
+		weight      int
+		method      string
+		want        Quote
+		wantErr     bool
+	}{
+		{"exact route", "jakarta", "jakarta", 800, MethodRegular, Quote{Cost: 100, EstimatedDays: 2}, false},
+		{"exact route extra kg", "jakarta", "jakarta", 2100, MethodRegular, Quote{Cost: 200, EstimatedDays: 2}, false},
+		{"wildcard smallest bracket", "surabaya", "jakarta", 900, MethodRegular, Quote{Cost: 200, EstimatedDays: 4}, false},
+		{"wildcard heavier bracket", "surabaya", "jakarta", 3000, MethodRegular, Quote{Cost: 400, EstimatedDays: 4}, false},
+		{"over heaviest without extra", "surabaya", "jakarta", 6000, MethodRegular, Quote{}, true},
+		{"wildcard origin", "surabaya", "jakarta", 500, MethodExpress, Quote{Cost: 500, EstimatedDays: 1}, false},
+		{"no route for method", "jakarta", "surabaya", 500, MethodExpress, Quote{}, true},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := p.Rate(tc.origin, tc.destination, tc.weight, tc.method)
+			if tc.wantErr {
+				if err == nil || err.Error() != "no_shipping_rate" {
+					t.Fatalf("expected no_shipping_rate, got %+v, %v", got, err)
+				}
+				return
+			}
+			if err != nil || got != tc.want {
+				t.Fatalf("expected %+v, got %+v, %v", tc.want, got, err)
+			}
+		})
+	}
+}
+
+func TestChargeableWeight_UsesVolumetric(t *testing.T) {
+	// 300×200×100 mm = 6.000.000 mm³ → 1000 g volumetric
+	line := model.ShipmentLine{Qty: 2, WeightGrams: 400, LengthMM: 300, WidthMM: 200, HeightMM: 100}
+	if w := ChargeableWeight(line); w != 2000 {
+		t.Fatalf("expected 2000, got %d", w)
+	}
+
+	line.WeightGrams = 1500
+	if w := ChargeableWeight(line); w != 3000 {
+		t.Fatalf("expected 3000, got %d", w)
+	}
+}
+
+func TestOptions_SumsParcelsPerOrigin(t *testing.T) {
+	p := NewTableRateProvider(testRates)
+
+	lines := []model.ShipmentLine{
+		{OriginRegion: "jakarta", ProductID: 1, Qty: 1, WeightGrams: 600},
+		{OriginRegion: "jakarta", ProductID: 2, Qty: 1, WeightGrams: 300},
+		{OriginRegion: "surabaya", ProductID: 3, Qty: 2, WeightGrams: 1000},
+	}
+
+	options, err := Options(p, "jakarta", lines)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// express hanya ada untuk rute ke jakarta sampai 1 kg, parcel surabaya 2 kg → tidak tersedia
+	want := []model.ShippingOption{
+		{Method: MethodRegular, Cost: 100 + 400, EstimatedDays: 4},
+	}
+	if !reflect.DeepEqual(options, want) {
+		t.Fatalf("unexpected options: %+v", options)
+	}
+}
+
+func TestLoadRatesFile(t *testing.T) {
+	dir := t.TempDir()
+
+	valid := filepath.Join(dir, "rates.json")
+	os.WriteFile(valid, []byte(`[{"origin":"*","destination":"*","method":"regular","max_weight_grams":1000,"cost":100,"estimated_days":3}]`), 0o600)
+	rates, err := LoadRatesFile(valid)
+	if err != nil || len(rates) != 1 || rates[0].EstimatedDays != 3 {
+		t.Fatalf("unexpected result %+v, %v", rates, err)
+	}
+
+	invalid := filepath.Join(dir, "invalid.json")
+	os.WriteFile(invalid, []byte(`[{"origin":"*","destination":"*","method":"cargo","max_weight_grams":1000,"cost":100}]`), 0o600)
+	if _, err := LoadRatesFile(invalid); err == nil {
+		t.Fatalf("expected error for unknown method")
+	}
+}
@@ -0,0 +1,20 @@
+// Package storage abstracts where uploaded binary objects (product images) are kept.
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+var ErrInvalidKey = errors.New("invalid_blob_key")
+
+// BlobStore stores binary objects under a slash separated key
+type BlobStore interface {
+	// Put writes the content of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Delete removes the object stored under key. Deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL clients use to fetch the object
+	URL(key string) string
+}
@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStore_PutAndDelete(t *testing.T) {
+	root := t.TempDir()
+	store, err := NewLocalStore(root, "/media/")
+	if err != nil {
+		t.Fatalf("NewLocalStore err: %v", err)
+	}
+
+	ctx := context.Background()
+	if err := store.Put(ctx, "products/1/a.png", strings.NewReader("png-bytes")); err != nil {
+		t.Fatalf("Put err: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(root, "products", "1", "a.png"))
+	if err != nil {
+		t.Fatalf("expected file on disk: %v", err)
+	}
+	if string(data) != "png-bytes" {
+		t.Fatalf("unexpected content: %s", data)
+	}
+
+	if url := store.URL("products/1/a.png"); url != "/media/products/1/a.png" {
+		t.Fatalf("unexpected url: %s", url)
+	}
+
+	if err := store.Delete(ctx, "products/1/a.png"); err != nil {
+		t.Fatalf("Delete err: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "products", "1", "a.png")); !os.IsNotExist(err) {
+		t.Fatalf("expected file to be removed, stat err: %v", err)
+	}
+
+	// deleting again is not an error
+	if err := store.Delete(ctx, "products/1/a.png"); err != nil {
+		t.Fatalf("second Delete err: %v", err)
+	}
+}
+
+func TestLocalStore_RejectsTraversal(t *testing.T) {
+	store, _ := NewLocalStore(t.TempDir(), "/media")
+
+	for _, key := range []string{"", "../etc/passwd", "products/../../x", "/abs", "a//b"} {
+		if err := store.Put(context.Background(), key, strings.NewReader("x")); err != ErrInvalidKey {
+			t.Fatalf("key %q: expected ErrInvalidKey, got %v", key, err)
+		}
+	}
+}
+
+func TestLocalStore_ServePath(t *testing.T) {
+	cases := map[string]string{
+		"/media":                         "/media/",
+		"/static/img/":                   "/static/img/",
+		"https://shop.example.com/media": "/media/",
+	}
+	for baseURL, want := range cases {
+		store := &LocalStore{Root: t.TempDir(), BaseURL: baseURL}
+		if got := store.ServePath(); got != want {
+			t.Errorf("%s: expected %s, got %s", baseURL, want, got)
+		}
+	}
+}
+
+func TestLocalStore_FileServerHidesDirectories(t *testing.T) {
+	store, err := NewLocalStore(t.TempDir(), "/assets")
+	if err != nil {
+		t.Fatalf("NewLocalStore err: %v", err)
+	}
+	if err := store.Put(context.Background(), "products/1/a.png", strings.NewReader("png-bytes")); err != nil {
+		t.Fatalf("Put err: %v", err)
+	}
+
+	cases := map[string]int{
+		"/assets/products/1/a.png": http.StatusOK,
+		"/assets/products/1/":      http.StatusNotFound,
+		"/assets/":                 http.StatusNotFound,
+	}
+	for path, want := range cases {
+		rec := httptest.NewRecorder()
+		store.FileServer().ServeHTTP(rec, httptest.NewRequest("GET", path, nil))
+		if rec.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"order-service-sample/account"
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/repository"
+)
+
+const loginChallengeTTL = 5 * time.Minute
+
+// verifySecondFactor accepts a current TOTP code (each time step once) or an unused
+// recovery code
+func verifySecondFactor(userID int, secret, code string) (bool, error) {
+	if account.IsRecoveryCode(code) {
+		return repository.UseRecoveryCode(db, userID, account.HashToken(account.NormalizeRecoveryCode(code)))
+	}
+
+	step, ok := account.VerifyTOTP(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+	return repository.UseTOTPStep(db, userID, step)
+}
+
+// EnrollTOTPHandler creates a new secret; 2FA is only switched on by ConfirmTOTPHandler
+func EnrollTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helper.GetUserIDFromContext(r.Context())
+
+	profile, err := repository.GetUserProfile(db, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+
+	secret, err := account.NewTOTPSecret()
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to generate secret")
+		return
+	}
+	if err := repository.SetPendingTOTPSecret(db, userID, secret); err != nil {
+		if err.Error() == "totp_already_enabled" {
+			helper.WriteErrorJSON(w, http.StatusConflict, "two-factor authentication is already enabled")
+			return
+		}
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to store secret")
+		return
+	}
+
+	issuer := helper.GetEnv("TOTP_ISSUER", "order-service-sample")
+	helper.WriteJSON(w, http.StatusOK, model.TOTPEnrollResp{
+		Secret:          secret,
+		ProvisioningURI: account.ProvisioningURI(issuer, profile.Email, secret),
+	})
+}
+
+// ConfirmTOTPHandler enables 2FA with a first code from the app and returns the
+// recovery codes, which are never shown again
+func ConfirmTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helper.GetUserIDFromContext(r.Context())
+
+	var req model.TOTPCodeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	state, err := repository.GetTOTPState(db, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load two-factor state")
+		return
+	}
+	if state.Enabled {
+		helper.WriteErrorJSON(w, http.StatusConflict, "two-factor authentication is already enabled")
+		return
+	}
+	if state.Secret == "" {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "start with POST /2fa/enroll")
+		return
+	}
+
+	step, ok := account.VerifyTOTP(state.Secret, req.Code, time.Now())
+	if !ok {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid code")
+		return
+	}
+
+	codes, err := account.NewRecoveryCodes(account.RecoveryCodeCount)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to generate recovery codes")
+		return
+	}
+	hashes := make([]string, len(codes))
+	for i, c := range codes {
+		hashes[i] = account.HashToken(c)
+	}
+
+	if err := repository.EnableTOTP(r.Context(), db, userID, step, hashes); err != nil {
+		if err.Error() == "totp_already_enabled" {
+			helper.WriteErrorJSON(w, http.StatusConflict, "two-factor authentication is already enabled")
+			return
+		}
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to enable two-factor authentication")
+		return
+	}
+	log.Printf("audit: user %d enabled two-factor authentication", userID)
+
+	// sesi yang dibuka tanpa 2FA harus login ulang lewat /login/2fa
+	if err := repository.RevokeUserRefreshTokens(db, userID); err != nil {
+		log.Println("2fa: failed to revoke refresh tokens:", err)
+	}
+
+	helper.WriteJSON(w, http.StatusOK, model.RecoveryCodesResp{RecoveryCodes: codes})
+}
+
+// DisableTOTPHandler switches 2FA off; it needs a current code so a hijacked
+// session alone can not do it
+func DisableTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	userID := helper.GetUserIDFromContext(r.Context())
+
+	var req model.TOTPCodeReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	state, err := repository.GetTOTPState(db, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load two-factor state")
+		return
+	}
+	if !state.Enabled {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "two-factor authentication is not enabled")
+		return
+	}
+
+	ok, err := verifySecondFactor(userID, state.Secret, req.Code)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to check code")
+		return
+	}
+	if !ok {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid code")
+		return
+	}
+
+	if err := repository.DisableTOTP(r.Context(), db, userID); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to disable two-factor authentication")
+		return
+	}
+	log.Printf("audit: user %d disabled two-factor authentication", userID)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoginTOTPHandler is the second login step: it trades the challenge token from
+// /login plus a code for the real tokens
+func LoginTOTPHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req model.LoginTOTPReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid request body")
+		return
+	}
+
+	userID, err := loginChallenges.ChallengeUser(ctx, req.ChallengeToken)
+	if err == account.ErrTokenNotFound {
+		helper.WriteErrorJSON(w, http.StatusUnauthorized, "invalid or expired challenge, log in again")
+		return
+	}
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to check challenge")
+		return
+	}
+
+	// tebakan code dihitung per user, jadi challenge baru tidak memberi jatah tebakan baru
+	limiterKey := fmt.Sprintf("2fa:%d", userID)
+	ip := clientIP(r)
+	if locked, err := loginLimiter.Locked(ctx, limiterKey, ip); err == nil && locked > 0 {
+		writeLoginLocked(w, locked)
+		return
+	}
+
+	state, err := repository.GetTOTPState(db, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load two-factor state")
+		return
+	}
+
+	ok, err := verifySecondFactor(userID, state.Secret, req.Code)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to check code")
+		return
+	}
+	if !ok {
+		if lockout, err := loginLimiter.Fail(ctx, limiterKey, ip); err == nil && lockout > 0 {
+			writeLoginLocked(w, lockout)
+			return
+		}
+		helper.WriteErrorJSON(w, http.StatusUnauthorized, "invalid code")
+		return
+	}
+
+	if err := loginChallenges.DeleteChallenge(ctx, req.ChallengeToken); err != nil {
+		log.Println("login: failed to delete challenge:", err)
+	}
+	if err := loginLimiter.Succeed(ctx, limiterKey); err != nil {
+		log.Println("login: failed to reset failed attempts:", err)
+	}
+
+	role, err := repository.GetUserRole(db, userID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load user")
+		return
+	}
+	tokens, err := issueTokens(userID, role, true)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to generate token")
+		return
+	}
+	helper.WriteJSON(w, http.StatusOK, tokens)
+}
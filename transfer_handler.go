@@ -0,0 +1,244 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/repository"
+
+	"github.com/gorilla/mux"
+)
+
+// decodeTransferRequest parses and validates a transfer body. It writes the error
+// response itself and returns ok=false when the request must not go on.
+func decodeTransferRequest(w http.ResponseWriter, r *http.Request) (req model.TransferReq, lines []model.TransferLine, ok bool) {
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return req, nil, false
+	}
+
+	// single-line request tetap didukung
+	lines = req.Items
+	if len(lines) == 0 && req.ProductID != 0 {
+		lines = []model.TransferLine{{ProductID: req.ProductID, Quantity: req.Quantity}}
+	}
+
+	if req.FromWarehouse == 0 || req.ToWarehouse == 0 || len(lines) == 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "missing or invalid fields")
+		return req, nil, false
+	}
+	if req.FromWarehouse == req.ToWarehouse {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "from and to warehouse must be different")
+		return req, nil, false
+	}
+
+	var errs []model.FieldError
+	seen := map[int]bool{}
+	productIDs := []int{}
+	for i, l := range lines {
+		if l.ProductID <= 0 {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "product_id must be > 0"})
+		} else if seen[l.ProductID] {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "duplicate product_id"})
+		} else {
+			seen[l.ProductID] = true
+			productIDs = append(productIDs, l.ProductID)
+		}
+		if l.Quantity <= 0 {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].quantity", i), Message: "quantity must be > 0"})
+		}
+	}
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return req, nil, false
+	}
+
+	// validate warehouses: source may be inactive so its stock can be drained,
+	// destination must be active
+	exists, err := repository.WarehouseExists(db, req.FromWarehouse)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to validate source warehouse")
+		return req, nil, false
+	}
+	if !exists {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "source warehouse not found")
+		return req, nil, false
+	}
+
+	active, err := repository.CheckWarehouseActive(db, req.ToWarehouse)
+	if err != nil && err.Error() != "warehouse_not_found" {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to validate destination warehouse")
+		return req, nil, false
+	}
+	if !active {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "destination warehouse not active or not found")
+		return req, nil, false
+	}
+
+	prices, err := repository.GetProductPrices(db, productIDs)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to validate products")
+		return req, nil, false
+	}
+	for i, l := range lines {
+		if _, found := prices[l.ProductID]; !found {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "product not found"})
+		}
+	}
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return req, nil, false
+	}
+
+	return req, lines, true
+}
+
+// writeTransferError maps repository errors of the transfer functions to a response
+func writeTransferError(w http.ResponseWriter, err error, fallback string) {
+	msg := err.Error()
+	switch {
+	case msg == "transfer_not_found":
+		helper.WriteErrorJSON(w, http.StatusNotFound, "transfer not found")
+	case msg == "transfer_not_in_transit":
+		helper.WriteErrorJSON(w, http.StatusConflict, "transfer is not in transit")
+	case strings.HasPrefix(msg, "not enough available stock"),
+		strings.HasPrefix(msg, "source_stock_not_found"),
+		strings.HasPrefix(msg, "invalid_received_quantity"),
+		msg == "product_not_in_transfer":
+		helper.WriteErrorJSON(w, http.StatusBadRequest, msg)
+	default:
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, fallback)
+	}
+}
+
+func transferIDFromRequest(w http.ResponseWriter, r *http.Request) (int, bool) {
+	transferID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || transferID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid transfer id")
+		return 0, false
+	}
+	return transferID, true
+}
+
+// TransferHandler moves stock instantly from one warehouse to another
+func TransferHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, lines, ok := decodeTransferRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// availability is checked under row locks inside the transaction
+	transfer, err := repository.CreateTransfer(ctx, db, req.FromWarehouse, req.ToWarehouse, lines, req.Note, helper.ActorFromContext(ctx))
+	if err != nil {
+		writeTransferError(w, err, "failed to transfer stock")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusCreated, transfer)
+}
+
+// DispatchTransferHandler takes stock out of the source warehouse and keeps it in transit
+func DispatchTransferHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	req, lines, ok := decodeTransferRequest(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := repository.DispatchTransfer(ctx, db, req.FromWarehouse, req.ToWarehouse, lines, req.Note, helper.ActorFromContext(ctx))
+	if err != nil {
+		writeTransferError(w, err, "failed to dispatch transfer")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusCreated, transfer)
+}
+
+// ReceiveTransferHandler books a dispatched transfer into the destination warehouse.
+// An empty body means everything arrived.
+func ReceiveTransferHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	transferID, ok := transferIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.ReceiveTransferReq
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+			return
+		}
+	}
+
+	var errs []model.FieldError
+	seen := map[int]bool{}
+	for i, l := range req.Items {
+		if l.ProductID <= 0 {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "product_id must be > 0"})
+		} else if seen[l.ProductID] {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "duplicate product_id"})
+		}
+		seen[l.ProductID] = true
+		if l.ReceivedQuantity < 0 {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].received_quantity", i), Message: "received_quantity must be >= 0"})
+		}
+		if l.DamagedQuantity < 0 {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].damaged_quantity", i), Message: "damaged_quantity must be >= 0"})
+		}
+	}
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	transfer, err := repository.ReceiveTransfer(ctx, db, transferID, req.Items, helper.ActorFromContext(ctx))
+	if err != nil {
+		writeTransferError(w, err, "failed to receive transfer")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, transfer)
+}
+
+// CancelTransferHandler returns a dispatched transfer to its source warehouse
+func CancelTransferHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	transferID, ok := transferIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := repository.CancelTransfer(ctx, db, transferID, helper.ActorFromContext(ctx))
+	if err != nil {
+		writeTransferError(w, err, "failed to cancel transfer")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, transfer)
+}
+
+func GetTransferHandler(w http.ResponseWriter, r *http.Request) {
+	transferID, ok := transferIDFromRequest(w, r)
+	if !ok {
+		return
+	}
+
+	transfer, err := repository.GetTransfer(db, transferID)
+	if err != nil {
+		writeTransferError(w, err, "failed to get transfer")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, transfer)
+}
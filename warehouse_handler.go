@@ -0,0 +1,360 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"order-service-sample/helper"
+	"order-service-sample/model"
+	"order-service-sample/repository"
+
+	"github.com/gorilla/mux"
+)
+
+func ListWarehousesHandler(w http.ResponseWriter, r *http.Request) {
+	var active *bool
+	if v := r.URL.Query().Get("active"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			helper.WriteErrorJSON(w, http.StatusBadRequest, "active must be true or false")
+			return
+		}
+		active = &b
+	}
+
+	warehouses, err := repository.ListWarehouses(db, active)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load warehouses")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, warehouses)
+}
+
+func WarehouseStockHandler(w http.ResponseWriter, r *http.Request) {
+	warehouseID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || warehouseID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid warehouse id")
+		return
+	}
+
+	exists, err := repository.WarehouseExists(db, warehouseID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !exists {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "warehouse not found")
+		return
+	}
+
+	stock, err := repository.GetWarehouseStock(db, warehouseID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to load warehouse stock")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, stock)
+}
+
+func CreateWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.CreateWarehouseReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if errs := validateWarehouseFields(&req.Name, &req.Region, &req.Capacity, true); len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	warehouse, err := repository.CreateWarehouse(db, req)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to create warehouse")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusCreated, warehouse)
+}
+
+func UpdateWarehouseHandler(w http.ResponseWriter, r *http.Request) {
+	warehouseID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || warehouseID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid warehouse id")
+		return
+	}
+
+	var req model.UpdateWarehouseReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	if req.Name != nil {
+		trimmed := strings.TrimSpace(*req.Name)
+		req.Name = &trimmed
+	}
+	if errs := validateWarehouseFields(req.Name, req.Region, req.Capacity, false); len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	warehouse, err := repository.UpdateWarehouse(db, warehouseID, req)
+	if err != nil {
+		if err.Error() == "warehouse_not_found" {
+			helper.WriteErrorJSON(w, http.StatusNotFound, "warehouse not found")
+			return
+		}
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to update warehouse")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusOK, warehouse)
+}
+
+// validateWarehouseFields checks the editable warehouse fields; nil fields are skipped
+// unless nameRequired is set.
+func validateWarehouseFields(name, region *string, capacity *int, nameRequired bool) []model.FieldError {
+	var errs []model.FieldError
+	if name == nil && nameRequired {
+		errs = append(errs, model.FieldError{Field: "name", Message: "name is required"})
+	}
+	if name != nil {
+		if *name == "" {
+			errs = append(errs, model.FieldError{Field: "name", Message: "name is required"})
+		} else if len(*name) > 100 {
+			errs = append(errs, model.FieldError{Field: "name", Message: "name must be at most 100 characters"})
+		}
+	}
+	if region != nil {
+		// samakan dengan region alamat yang disimpan lowercase
+		*region = strings.ToLower(strings.TrimSpace(*region))
+	}
+	if region != nil && len(*region) > 50 {
+		errs = append(errs, model.FieldError{Field: "region", Message: "region must be at most 50 characters"})
+	}
+	if capacity != nil && *capacity < 0 {
+		errs = append(errs, model.FieldError{Field: "capacity", Message: "capacity must be >= 0"})
+	}
+	return errs
+}
+
+func GoodsReceiptHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	warehouseID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || warehouseID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid warehouse id")
+		return
+	}
+
+	var req model.GoodsReceiptReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	// 1. Validasi item
+	var errs []model.FieldError
+	if len(req.Items) == 0 {
+		errs = append(errs, model.FieldError{Field: "items", Message: "items cannot be empty"})
+	}
+	seen := map[int]bool{}
+	productIDs := []int{}
+	for i, it := range req.Items {
+		if it.ProductID <= 0 {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "product_id must be > 0"})
+		} else if seen[it.ProductID] {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "duplicate product_id"})
+		} else {
+			seen[it.ProductID] = true
+			productIDs = append(productIDs, it.ProductID)
+		}
+		if it.Quantity <= 0 {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].quantity", i), Message: "quantity must be > 0"})
+		}
+	}
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	// 2. Validasi warehouse aktif & product ada
+	ok, err := repository.CheckWarehouseActive(db, warehouseID)
+	if err != nil {
+		if err.Error() == "warehouse_not_found" {
+			helper.WriteErrorJSON(w, http.StatusNotFound, "warehouse not found")
+			return
+		}
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !ok {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "warehouse is not active")
+		return
+	}
+
+	prices, err := repository.GetProductPrices(db, productIDs)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	for i, it := range req.Items {
+		if _, found := prices[it.ProductID]; !found {
+			errs = append(errs, model.FieldError{Field: fmt.Sprintf("items[%d].product_id", i), Message: "product not found"})
+		}
+	}
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	// 3. Booking dalam satu transaksi
+	receipt, err := repository.CreateGoodsReceipt(ctx, db, warehouseID, req, helper.ActorFromContext(ctx))
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to book goods receipt")
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusCreated, receipt)
+}
+
+func StockAdjustmentHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	warehouseID, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil || warehouseID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid warehouse id")
+		return
+	}
+
+	var req model.StockAdjustmentReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+
+	// 1. Validasi request
+	var errs []model.FieldError
+	if req.ProductID <= 0 {
+		errs = append(errs, model.FieldError{Field: "product_id", Message: "product_id must be > 0"})
+	}
+	switch {
+	case req.CountedQuantity != nil && req.QuantityDelta != 0:
+		errs = append(errs, model.FieldError{Field: "quantity_delta", Message: "send either counted_quantity or quantity_delta, not both"})
+	case req.CountedQuantity == nil && req.QuantityDelta == 0:
+		errs = append(errs, model.FieldError{Field: "quantity_delta", Message: "counted_quantity or a non-zero quantity_delta is required"})
+	case req.CountedQuantity != nil && *req.CountedQuantity < 0:
+		errs = append(errs, model.FieldError{Field: "counted_quantity", Message: "counted_quantity must be >= 0"})
+	}
+	if req.ReasonCode == "" {
+		errs = append(errs, model.FieldError{Field: "reason_code", Message: "reason_code is required"})
+	} else if !repository.AdjustmentReasonCodes[req.ReasonCode] {
+		errs = append(errs, model.FieldError{Field: "reason_code", Message: "unknown reason_code"})
+	}
+	if len(errs) > 0 {
+		helper.WriteValidationErrorJSON(w, errs)
+		return
+	}
+
+	// 2. Validasi warehouse & product ada
+	exists, err := repository.WarehouseExists(db, warehouseID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !exists {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "warehouse not found")
+		return
+	}
+
+	exists, err = repository.ProductExists(db, req.ProductID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !exists {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "product not found")
+		return
+	}
+
+	// 3. Adjustment dalam satu transaksi
+	adj, err := repository.CreateStockAdjustment(ctx, db, warehouseID, req, helper.ActorFromContext(ctx))
+	if err != nil {
+		switch err.Error() {
+		case "quantity_below_reserved":
+			helper.WriteErrorJSON(w, http.StatusConflict, "quantity cannot drop below reserved stock")
+		case "no_change":
+			helper.WriteErrorJSON(w, http.StatusBadRequest, "adjustment does not change the quantity")
+		default:
+			helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to adjust stock")
+		}
+		return
+	}
+
+	helper.WriteJSON(w, http.StatusCreated, adj)
+}
+
+func SetReorderPointHandler(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	warehouseID, err := strconv.Atoi(vars["id"])
+	if err != nil || warehouseID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid warehouse id")
+		return
+	}
+	productID, err := strconv.Atoi(vars["product_id"])
+	if err != nil || productID <= 0 {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid product id")
+		return
+	}
+
+	var req model.SetReorderPointReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		helper.WriteErrorJSON(w, http.StatusBadRequest, "invalid json")
+		return
+	}
+	if req.ReorderPoint != nil && *req.ReorderPoint < 0 {
+		helper.WriteValidationErrorJSON(w, []model.FieldError{{Field: "reorder_point", Message: "reorder_point must be >= 0"}})
+		return
+	}
+
+	exists, err := repository.WarehouseExists(db, warehouseID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !exists {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "warehouse not found")
+		return
+	}
+
+	exists, err = repository.ProductExists(db, productID)
+	if err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "internal error")
+		return
+	}
+	if !exists {
+		helper.WriteErrorJSON(w, http.StatusNotFound, "product not found")
+		return
+	}
+
+	if err := repository.SetReorderPoint(db, warehouseID, productID, req.ReorderPoint); err != nil {
+		helper.WriteErrorJSON(w, http.StatusInternalServerError, "failed to set reorder point")
+		return
+	}
+	// evaluasi langsung, stok yang sudah di bawah reorder point baru tidak menunggu pergerakan berikutnya
+	if req.ReorderPoint != nil {
+		checkLowStock(r.Context(), lowStockNotifier(), []int{productID})
+	}
+
+	helper.WriteJSON(w, http.StatusOK, map[string]any{
+		"warehouse_id":  warehouseID,
+		"product_id":    productID,
+		"reorder_point": req.ReorderPoint,
+	})
+}